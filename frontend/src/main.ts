@@ -5,7 +5,7 @@ import * as LinkService from "../bindings/github.com/kazuph/obails/services/link
 import * as WindowService from "../bindings/github.com/kazuph/obails/services/windowservice.js";
 import * as GraphService from "../bindings/github.com/kazuph/obails/services/graphservice.js";
 import * as StateService from "../bindings/github.com/kazuph/obails/services/stateservice.js";
-import { FileInfo, Note, Timeline, Backlink, Link, Config, Graph } from "../bindings/github.com/kazuph/obails/models/models.js";
+import { FileInfo, Note, Timeline, Backlink, Link, Config, Graph, SaveResult, Workspace, NoteViewState, SidebarLayout, GraphViewState } from "../bindings/github.com/kazuph/obails/models/models.js";
 import mermaid from "mermaid";
 import hljs from "highlight.js";
 import "highlight.js/styles/github-dark.css";
@@ -47,6 +47,7 @@ function isModKey(e: KeyboardEvent): boolean {
 
 // State
 let currentNote: Note | null = null;
+let noteConflict: SaveResult | null = null;  // Unresolved save conflict of currentNote
 let currentFilePath: string | null = null;  // Tracks any open file (md, image, pdf, html)
 let showTimeline = false;
 let showGraph = false;
@@ -54,6 +55,8 @@ let contextMenuTargetPath: string = "";
 let contextMenuTargetIsDir: boolean = false;
 let draggedFilePath: string | null = null;
 let graphInstance: ReturnType<typeof ForceGraph> | null = null;
+let workspace: Workspace | null = null;  // Layout restored at startup, saved on changes
+let savedNoteView = "";  // Last note view sent to the vault state, to skip repeats
 
 // Keyboard navigation state
 let fileTreeFocused = false;
@@ -119,8 +122,11 @@ async function init() {
         if (config?.Vault?.Path) {
             await loadFileTree();
 
-            // Open last file if exists (from vault state)
-            const lastFile = await StateService.GetLastOpenedFile();
+            // Restore the layout, then open the active tab or the last file (from vault state)
+            workspace = await StateService.GetWorkspace();
+            await restoreWorkspace(workspace);
+            const activeTab = workspace.panes[workspace.activePane]?.tabs[workspace.panes[workspace.activePane].activeTab];
+            const lastFile = activeTab ?? await StateService.GetLastOpenedFile();
             if (lastFile) {
                 try {
                     await openFile(lastFile.path, lastFile.fileType);
@@ -129,6 +135,9 @@ async function init() {
                     await StateService.ClearLastOpenedFile();
                 }
             }
+            if (workspace.graph.open) {
+                await showGraphView();
+            }
 
             // Prefetch graph data in background (don't block init)
             prefetchGraphData().catch(console.error);
@@ -232,6 +241,16 @@ function setupEventListeners() {
     });
 
     editor.addEventListener("input", debounce(saveCurrentNote, 500));
+    const saveNoteViewLater = debounce(saveNoteView, 1000);
+    editor.addEventListener("scroll", saveNoteViewLater);
+    editor.addEventListener("keyup", saveNoteViewLater);
+    editor.addEventListener("mouseup", saveNoteViewLater);
+    window.addEventListener("beforeunload", () => {
+        saveNoteView();
+    });
+    document.getElementById("conflict-keep-mine")!.addEventListener("click", keepMineInConflict);
+    document.getElementById("conflict-use-disk")!.addEventListener("click", useDiskInConflict);
+    document.getElementById("conflict-edit-merge")!.addEventListener("click", editMergeInConflict);
     editor.addEventListener("input", updatePreview);
 
     // HTML Editor events
@@ -1068,6 +1087,7 @@ async function deleteTargetPathWithArgs(targetPath: string, isDir: boolean) {
         // If deleted file was currently open, clear editor
         if (currentNote && currentNote.path === targetPath) {
             currentNote = null;
+            hideNoteConflict();
             editor.value = "";
             // Reset cursor position and scroll to the top
             editor.selectionStart = 0;
@@ -1135,6 +1155,11 @@ function getFileIcon(file: FileInfo): string {
         case "image": return "🖼️";
         case "pdf": return "📕";
         case "html": return "🌐";
+        case "audio": return "🎵";
+        case "video": return "🎬";
+        case "text": return "📃";
+        case "data": return "📊";
+        case "canvas": return "🧩";
         default: return "📄";
     }
 }
@@ -1150,13 +1175,16 @@ function getFileTypeFromPath(path: string): string {
 
 // Open file based on file type
 async function openFile(path: string, fileType: string): Promise<void> {
+    await saveNoteView();
     hideAllViewers();
     currentFilePath = path;  // Track current file for refresh
+    scheduleWorkspaceSave();
 
     // Save last opened file to vault state (for all supported types)
     if (fileType === "markdown" || fileType === "image" || fileType === "pdf" || fileType === "html") {
         await StateService.SetLastOpenedFile(path, fileType);
     }
+    await StateService.RecordFileOpened(path, fileType);
 
     // Clear outline for non-markdown files (outline is only relevant for markdown)
     if (fileType !== "markdown") {
@@ -1195,11 +1223,7 @@ function hideAllViewers() {
 // Open image file
 async function openImage(path: string): Promise<void> {
     try {
-        const base64Data = await FileService.ReadBinaryFile(path);
-        const ext = path.split('.').pop()?.toLowerCase() || 'png';
-        const mimeType = getMimeTypeFromExt(ext);
-
-        imagePreview.src = `data:${mimeType};base64,${base64Data}`;
+        imagePreview.src = vaultAssetURL(path);
         imageTitle.textContent = path.split('/').pop() || 'Image';
         imageViewer.style.display = "block";
 
@@ -1215,15 +1239,9 @@ async function openImage(path: string): Promise<void> {
 async function openPDF(path: string): Promise<void> {
     try {
         currentPdfPath = path;
-        const base64Data = await FileService.ReadBinaryFile(path);
-        const binaryData = atob(base64Data);
-        const byteArray = new Uint8Array(binaryData.length);
-        for (let i = 0; i < binaryData.length; i++) {
-            byteArray[i] = binaryData.charCodeAt(i);
-        }
 
-        // Load PDF document
-        const loadingTask = pdfjsLib.getDocument({ data: byteArray });
+        // Load PDF document (streamed with range requests)
+        const loadingTask = pdfjsLib.getDocument({ url: vaultAssetURL(path) });
         pdfDoc = await loadingTask.promise;
         pdfTotalPages = pdfDoc.numPages;
         pdfCurrentPage = 1;
@@ -1583,28 +1601,18 @@ async function openExternal(path: string): Promise<void> {
     }
 }
 
-// Get MIME type from extension
-function getMimeTypeFromExt(ext: string): string {
-    const mimeTypes: { [key: string]: string } = {
-        png: "image/png",
-        jpg: "image/jpeg",
-        jpeg: "image/jpeg",
-        gif: "image/gif",
-        webp: "image/webp",
-        svg: "image/svg+xml",
-        bmp: "image/bmp",
-        ico: "image/x-icon",
-    };
-    return mimeTypes[ext] || "application/octet-stream";
+// URL of a vault file streamed by the backend asset handler
+function vaultAssetURL(path: string): string {
+    return "/vault/" + path.split("/").map(encodeURIComponent).join("/");
 }
 
 // Update file tree selection highlight
-function updateFileTreeSelection(path: string) {
+async function updateFileTreeSelection(path: string) {
     // Remove previous selection
     document.querySelectorAll(".file-item").forEach(el => el.classList.remove("active"));
 
     // Expand parent folders to reveal the file
-    expandParentFolders(path);
+    await expandParentFolders(path);
 
     // Highlight the file
     const fileItem = document.querySelector(`.file-item[data-path="${path}"]`);
@@ -1617,8 +1625,8 @@ function updateFileTreeSelection(path: string) {
     }
 }
 
-// Expand all parent folders for a given file path
-function expandParentFolders(path: string) {
+// Expand all parent folders for a given file path, loading their entries as needed
+async function expandParentFolders(path: string) {
     const parts = path.split("/");
     let currentPath = "";
 
@@ -1640,8 +1648,9 @@ function expandParentFolders(path: string) {
             // Show children
             const wrapper = folderItem.parentElement;
             if (wrapper) {
-                const childrenEl = wrapper.querySelector(".folder-children") as HTMLElement;
+                const childrenEl = wrapper.querySelector(":scope > .folder-children") as HTMLElement;
                 if (childrenEl) {
+                    await loadFolderChildren(currentPath, childrenEl);
                     childrenEl.style.display = "block";
                 }
             }
@@ -1649,7 +1658,112 @@ function expandParentFolders(path: string) {
     }
 }
 
+// Workspace
+// The layout is kept in the vault state: the open file, expanded folders, the
+// sidebar width and whether the graph is open
+async function restoreWorkspace(saved: Workspace) {
+    const sidebar = document.getElementById("sidebar")!;
+    if (saved.sidebars.leftWidth) {
+        sidebar.style.width = `${saved.sidebars.leftWidth}px`;
+    }
+    for (const folder of saved.expandedFolders) {
+        // The trailing slash makes the folder itself expand along with its parents
+        await expandParentFolders(`${folder}/`);
+    }
+}
+
+function collectWorkspace(): Workspace {
+    const sidebar = document.getElementById("sidebar")!;
+    const tabs = currentFilePath
+        ? [{ path: currentFilePath, fileType: currentNote?.path === currentFilePath ? "markdown" : getFileTypeFromPath(currentFilePath) }]
+        : [];
+    const expandedFolders = Array.from(fileTree.querySelectorAll(".file-item.folder.expanded"))
+        .map(el => el.getAttribute("data-path") || "")
+        .filter(path => path !== "");
+
+    return new Workspace({
+        panes: [{ tabs, activeTab: 0, size: 0 }],
+        activePane: 0,
+        expandedFolders,
+        sidebars: new SidebarLayout({ ...workspace?.sidebars, leftWidth: sidebar.style.width ? sidebar.offsetWidth : 0 }),
+        graph: new GraphViewState({ ...workspace?.graph, open: showGraph }),
+    });
+}
+
+const scheduleWorkspaceSave = debounce(async () => {
+    try {
+        workspace = collectWorkspace();
+        await StateService.SaveWorkspace(workspace);
+    } catch (err) {
+        console.error("Failed to save workspace:", err);
+    }
+}, 500);
+
+// Note views remember the scroll position and the 0-based cursor line and column of notes
+async function saveNoteView() {
+    if (!currentNote || editorContainer.style.display === "none") return;
+
+    const lines = editor.value.slice(0, editor.selectionStart).split("\n");
+    const view = {
+        scrollTop: editor.scrollTop,
+        cursorLine: lines.length - 1,
+        cursorColumn: lines[lines.length - 1].length,
+    };
+    const key = JSON.stringify([currentNote.path, view]);
+    if (key === savedNoteView) return;
+    savedNoteView = key;
+    try {
+        await StateService.SetNoteViewState(currentNote.path, new NoteViewState(view));
+    } catch (err) {
+        console.error("Failed to save note view:", err);
+    }
+}
+
+async function restoreNoteView(path: string) {
+    let view: NoteViewState | null;
+    try {
+        view = await StateService.GetNoteViewState(path);
+    } catch (err) {
+        console.error("Failed to load note view:", err);
+        return;
+    }
+    if (!view) return;
+
+    const lines = editor.value.split("\n");
+    const line = Math.min(view.cursorLine, lines.length - 1);
+    let offset = 0;
+    for (let i = 0; i < line; i++) {
+        offset += lines[i].length + 1;
+    }
+    offset += Math.min(view.cursorColumn, lines[line].length);
+    editor.selectionStart = offset;
+    editor.selectionEnd = offset;
+    editor.scrollTop = view.scrollTop;
+    savedNoteView = JSON.stringify([path, { scrollTop: view.scrollTop, cursorLine: view.cursorLine, cursorColumn: view.cursorColumn }]);
+}
+
 // File Tree
+// The tree lists the top level; folders load their entries when first expanded
+async function loadFolderChildren(folderPath: string, childrenEl: HTMLElement) {
+    if (childrenEl.dataset.loaded === "true") return;
+    childrenEl.dataset.loaded = "true";
+    try {
+        let cursor = "";
+        do {
+            const page = await FileService.ListTreeChildren(folderPath, cursor, 0);
+            if (!page) break;
+            for (const child of page.entries) {
+                childrenEl.appendChild(createFileElement(child));
+            }
+            cursor = page.nextCursor;
+        } while (cursor);
+    } catch (err) {
+        console.error("Failed to load folder:", err);
+        childrenEl.replaceChildren();
+        childrenEl.dataset.loaded = "false";
+    }
+}
+
 async function loadFileTree() {
     try {
         const files = await FileService.ListDirectoryTree();
@@ -1675,6 +1789,9 @@ function createFileElement(file: FileInfo): HTMLElement {
 
     const el = document.createElement("div");
     el.className = `file-item ${file.isDir ? "folder" : "file"}`;
+    if (file.gitStatus) {
+        el.classList.add(`git-${file.gitStatus}`);
+    }
     el.setAttribute("data-path", file.path);
     el.setAttribute("data-name", file.name);
 
@@ -1699,29 +1816,33 @@ function createFileElement(file: FileInfo): HTMLElement {
     wrapper.appendChild(el);
 
     if (file.isDir) {
-        let childrenEl: HTMLElement | null = null;
-
+        const childrenEl = document.createElement("div");
+        childrenEl.className = "folder-children";
+        childrenEl.style.display = "none";
         if (file.children && file.children.length > 0) {
-            childrenEl = document.createElement("div");
-            childrenEl.className = "folder-children";
-            childrenEl.style.display = "none";
             for (const child of file.children) {
                 childrenEl.appendChild(createFileElement(child));
             }
-            wrapper.appendChild(childrenEl);
+            childrenEl.dataset.loaded = "true";
+        } else if (file.childCount === 0) {
+            childrenEl.dataset.loaded = "true";
         }
+        wrapper.appendChild(childrenEl);
 
-        el.addEventListener("click", (e) => {
+        el.addEventListener("click", async (e) => {
             e.stopPropagation();
             el.classList.toggle("expanded");
+            const expanded = el.classList.contains("expanded");
             const iconSpan = el.querySelector(".folder-icon");
             if (iconSpan) {
                 // 📂 = open folder, 📁 = closed folder
-                iconSpan.textContent = el.classList.contains("expanded") ? "📂" : "📁";
+                iconSpan.textContent = expanded ? "📂" : "📁";
             }
-            if (childrenEl) {
-                childrenEl.style.display = childrenEl.style.display === "none" ? "block" : "none";
+            if (expanded) {
+                await loadFolderChildren(file.path, childrenEl);
             }
+            childrenEl.style.display = expanded ? "block" : "none";
+            scheduleWorkspaceSave();
         });
 
         // Right-click context menu for folders
@@ -1769,7 +1890,9 @@ function createFileElement(file: FileInfo): HTMLElement {
 // Note Operations
 async function openNote(path: string) {
     try {
+        await saveNoteView();
         currentNote = await NoteService.GetNote(path);
+        hideNoteConflict();
         if (currentNote) {
             editor.value = currentNote.content;
             // Reset cursor position and scroll to the top
@@ -1777,6 +1900,7 @@ async function openNote(path: string) {
             editor.selectionEnd = 0;
             editor.scrollTop = 0;
             updatePreview();
+            await restoreNoteView(path);
             await loadBacklinks(path);
             await loadOutgoingLinks(path);
 
@@ -1970,22 +2094,100 @@ function cancelTitleEdit(originalTitle: string) {
     editorTitle.textContent = originalTitle;
 }
 
+// Save the editor content on top of the version it was loaded from. Changes made on
+// disk in the meantime are merged in; overlapping ones show the conflict banner.
 async function saveCurrentNote() {
-    if (!currentNote) return;
+    if (!currentNote || noteConflict) return;
 
+    const note = currentNote;
+    const content = editor.value;
     try {
-        await NoteService.SaveNote(currentNote.path, editor.value);
-        currentNote.content = editor.value;
+        const result = await NoteService.SaveNoteMerged(note.path, content, {
+            hash: note.hash,
+            modifiedAt: note.modifiedAt,
+        });
+        if (!result || currentNote !== note) return;
+
+        if (result.status === "conflict") {
+            showNoteConflict(result);
+            return;
+        }
+        // Typed while saving: keep the old base, the next save merges again
+        if (editor.value !== content) return;
+
+        setNoteVersion(note, result);
+        if (result.status === "merged") {
+            const { selectionStart, selectionEnd, scrollTop } = editor;
+            editor.value = result.content;
+            editor.setSelectionRange(selectionStart, selectionEnd);
+            editor.scrollTop = scrollTop;
+            updatePreview();
+        }
     } catch (err) {
         console.error("Failed to save note:", err);
     }
 }
 
+function setNoteVersion(note: Note, result: SaveResult) {
+    note.content = result.content;
+    note.hash = result.version.hash;
+    note.modifiedAt = result.version.modifiedAt;
+}
+
+function showNoteConflict(result: SaveResult) {
+    noteConflict = result;
+    const count = result.conflicts === 1 ? "1 conflicting change" : `${result.conflicts} conflicting changes`;
+    document.getElementById("conflict-message")!.textContent =
+        `This note changed on disk while you edited it (${count}).`;
+    document.getElementById("conflict-banner")!.style.display = "flex";
+}
+
+function hideNoteConflict() {
+    noteConflict = null;
+    document.getElementById("conflict-banner")!.style.display = "none";
+}
+
+// Overwrite the disk version with the editor content
+async function keepMineInConflict() {
+    if (!currentNote || !noteConflict) return;
+    const note = currentNote;
+    const version = noteConflict.version;
+    hideNoteConflict();
+    note.hash = version.hash;
+    note.modifiedAt = version.modifiedAt;
+    await saveCurrentNote();
+}
+
+// Drop the editor changes and show the disk version
+function useDiskInConflict() {
+    if (!currentNote || !noteConflict) return;
+    const result = noteConflict;
+    hideNoteConflict();
+    currentNote.content = result.theirs ?? "";
+    currentNote.hash = result.version.hash;
+    currentNote.modifiedAt = result.version.modifiedAt;
+    editor.value = currentNote.content;
+    updatePreview();
+}
+
+// Put the merge with conflict markers into the editor; it is saved once edited
+function editMergeInConflict() {
+    if (!currentNote || !noteConflict) return;
+    const result = noteConflict;
+    hideNoteConflict();
+    currentNote.hash = result.version.hash;
+    currentNote.modifiedAt = result.version.modifiedAt;
+    editor.value = result.content;
+    updatePreview();
+    editor.focus();
+}
+
 async function openTodayNote() {
     try {
         const note = await NoteService.GetTodayDailyNote();
         if (note) {
             currentNote = note;
+            hideNoteConflict();
             editor.value = note.content;
             // Reset cursor position and scroll to the top
             editor.selectionStart = 0;
@@ -2279,6 +2481,9 @@ function setupResizeHandles() {
     });
 
     document.addEventListener("mouseup", () => {
+        if (isResizingSidebar) {
+            scheduleWorkspaceSave();
+        }
         isResizingSidebar = false;
         isResizingEditor = false;
         isResizingRightSidebar = false;
@@ -2297,8 +2502,7 @@ async function refresh() {
 
     // Restore file tree selection and expand parent folders
     if (currentFilePath) {
-        expandParentFolders(currentFilePath);
-        updateFileTreeSelection(currentFilePath);
+        await updateFileTreeSelection(currentFilePath);
     }
 
     // If graph view is showing, refresh the graph data
@@ -2319,6 +2523,7 @@ async function toggleGraphView() {
 
 async function showGraphView() {
     showGraph = true;
+    scheduleWorkspaceSave();
 
     // Hide all viewers
     hideAllViewers();
@@ -2332,6 +2537,7 @@ async function showGraphView() {
 
 async function hideGraphView() {
     showGraph = false;
+    scheduleWorkspaceSave();
 
     // Save node positions before closing
     saveGraphNodePositions();
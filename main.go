@@ -19,21 +19,27 @@ func main() {
 		log.Printf("Warning: Failed to load config: %v", err)
 	}
 
-	stateService := services.NewStateService(configService)
+	fileService := services.NewFileService(configService)
+	stateService := services.NewStateService(configService, fileService)
 	if err := stateService.Load(); err != nil {
 		log.Printf("Warning: Failed to load state: %v", err)
 	}
 
-	fileService := services.NewFileService(configService)
 	noteService := services.NewNoteService(fileService, configService)
 	linkService := services.NewLinkService(fileService, configService)
+	taskService := services.NewTaskService(noteService, fileService, configService)
+	calendarService := services.NewCalendarService(noteService, taskService, fileService, configService)
+	thumbnailService := services.NewThumbnailService(fileService)
+	gitService := services.NewGitService(noteService, fileService, configService)
 	graphService := services.NewGraphService(linkService, fileService, configService)
 	windowService := services.NewWindowService()
 
-	// Build link index on startup
+	vaultService := services.NewVaultService(configService, fileService, stateService, noteService, linkService, taskService, gitService)
+
+	// Clean up old trash and history, then build link and task indices on startup
 	go func() {
-		if err := linkService.RebuildIndex(); err != nil {
-			log.Printf("Warning: Failed to build link index: %v", err)
+		if err := vaultService.PrepareVault(); err != nil {
+			log.Printf("Warning: Failed to prepare vault: %v", err)
 		}
 	}()
 
@@ -47,11 +53,17 @@ func main() {
 			application.NewService(fileService),
 			application.NewService(noteService),
 			application.NewService(linkService),
+			application.NewService(taskService),
+			application.NewService(calendarService),
+			application.NewService(thumbnailService),
+			application.NewService(gitService),
 			application.NewService(graphService),
 			application.NewService(windowService),
+			application.NewService(vaultService),
 		},
 		Assets: application.AssetOptions{
-			Handler: application.AssetFileServerFS(assets),
+			Handler:    application.AssetFileServerFS(assets),
+			Middleware: services.NewVaultAssetHandler(fileService).Middleware,
 		},
 		Mac: application.MacOptions{
 			ApplicationShouldTerminateAfterLastWindowClosed: true,
@@ -77,6 +89,7 @@ func main() {
 
 	// Set app reference for config service (for dialogs)
 	configService.SetApp(app)
+	calendarService.SetApp(app)
 
 	// Run the application
 	if err := app.Run(); err != nil {
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// AttachmentImport describes a binary file to add to the vault, either pasted
+// (Data) or dropped from disk (SourcePath)
+type AttachmentImport struct {
+	NotePath   string `json:"notePath"`   // Note the attachment is inserted into
+	FileName   string `json:"fileName"`   // Optional; pasted data gets "Pasted image <timestamp>"
+	Data       string `json:"data"`       // Base64 encoded content
+	SourcePath string `json:"sourcePath"` // Absolute path of a file to copy, used when Data is empty
+}
+
+// Attachment is a stored attachment and the link to insert for it
+type Attachment struct {
+	Path string `json:"path"` // Vault-relative
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	Link string `json:"link"` // e.g. "![[Pasted image 20261016093000.png]]"
+}
+
+// UnusedAttachment is a non-note file no note links to or embeds
+type UnusedAttachment struct {
+	Path       string    `json:"path"`
+	Name       string    `json:"name"`
+	FileType   string    `json:"fileType"` // image, pdf, audio, video or other
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// BookmarkGroup is a named, ordered list of bookmarks
+type BookmarkGroup struct {
+	Name      string     `json:"name"`
+	Bookmarks []Bookmark `json:"bookmarks"`
+}
+
+// Bookmark points to a note, a heading in a note, a folder or a search query
+type Bookmark struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`              // note, heading, folder or search
+	Path      string    `json:"path,omitempty"`    // Note or folder, with forward slashes
+	Heading   string    `json:"heading,omitempty"` // Heading text for heading bookmarks
+	Query     string    `json:"query,omitempty"`   // Query for search bookmarks
+	Title     string    `json:"title,omitempty"`   // Display name, the target if empty
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Bookmark type constants
+const (
+	BookmarkTypeNote    = "note"
+	BookmarkTypeHeading = "heading"
+	BookmarkTypeFolder  = "folder"
+	BookmarkTypeSearch  = "search"
+)
+
+// DefaultBookmarkGroup is the group of bookmarks added without a group name
+const DefaultBookmarkGroup = "Bookmarks"
@@ -0,0 +1,16 @@
+package models
+
+// CalendarExportResult describes an iCalendar export
+type CalendarExportResult struct {
+	Path   string `json:"path"`   // Vault-relative path of the written .ics file
+	Events int    `json:"events"` // Planner blocks exported as VEVENT
+	Todos  int    `json:"todos"`  // Dated tasks exported as VTODO
+}
+
+// CalendarImportResult describes an iCalendar import
+type CalendarImportResult struct {
+	Imported  int      `json:"imported"`  // Events written to planner sections
+	Skipped   int      `json:"skipped"`   // All-day, duplicate or invalid events
+	Recurring int      `json:"recurring"` // Recurring events and their exceptions, which aren't imported
+	Dates     []string `json:"dates"`     // Daily notes that were changed ("YYYY-MM-DD")
+}
@@ -1,34 +1,139 @@
 package models
 
+import "time"
+
 // Config represents the application configuration
 type Config struct {
-	Vault      VaultConfig      `toml:"vault"`
-	DailyNotes DailyNotesConfig `toml:"daily_notes"`
-	Timeline      TimelineConfig      `toml:"timeline"`
-	Templates  TemplatesConfig  `toml:"templates"`
-	Editor     EditorConfig     `toml:"editor"`
-	UI         UIConfig         `toml:"ui"`
+	Vault       VaultConfig      `toml:"vault"`
+	Vaults      []KnownVault     `toml:"vaults"` // Vaults offered by the vault switcher
+	DailyNotes  DailyNotesConfig `toml:"daily_notes"`
+	Timeline    TimelineConfig   `toml:"timeline"`
+	Planner     PlannerConfig    `toml:"planner"`
+	Calendar    CalendarConfig   `toml:"calendar"`
+	Templates   TemplatesConfig  `toml:"templates"`
+	Files       FilesConfig      `toml:"files"`
+	Attachments AttachmentConfig `toml:"attachments"`
+	Trash       TrashConfig      `toml:"trash"`
+	History     HistoryConfig    `toml:"history"`
+	Git         GitConfig        `toml:"git"`
+	Editor      EditorConfig     `toml:"editor"`
+	UI          UIConfig         `toml:"ui"`
 }
 
 type VaultConfig struct {
 	Path string `toml:"path"`
 }
 
+// KnownVault is a vault listed in the vault switcher
+type KnownVault struct {
+	Name       string    `toml:"name"`
+	Path       string    `toml:"path"`
+	LastOpened time.Time `toml:"last_opened"`
+}
+
 type DailyNotesConfig struct {
-	Folder   string `toml:"folder"`
-	Format   string `toml:"format"`
-	Template string `toml:"template"`
+	Folder   string         `toml:"folder"`
+	Format   string         `toml:"format"`
+	Template string         `toml:"template"`
+	Rollover RolloverConfig `toml:"rollover"`
+}
+
+// RolloverConfig controls carrying unfinished tasks into a newly created daily note
+type RolloverConfig struct {
+	Enabled  bool     `toml:"enabled"`
+	Mode     string   `toml:"mode"`     // copy or move
+	Sections []string `toml:"sections"` // Sections whose open tasks roll over
 }
 
+// Rollover mode constants
+const (
+	RolloverModeCopy = "copy"
+	RolloverModeMove = "move"
+)
+
 type TimelineConfig struct {
-	Section    string `toml:"section"`
-	TimeFormat string `toml:"time_format"`
+	Section     string `toml:"section"`
+	TimeFormat  string `toml:"time_format"`
+	InsertOrder string `toml:"insert_order"` // newest_first or oldest_first
+}
+
+// Timeline insert order constants
+const (
+	TimelineOrderNewestFirst = "newest_first"
+	TimelineOrderOldestFirst = "oldest_first"
+)
+
+type PlannerConfig struct {
+	Section         string `toml:"section"`
+	DefaultDuration int    `toml:"default_duration"` // Minutes, for blocks without an end time
+}
+
+type CalendarConfig struct {
+	ExportPath string `toml:"export_path"` // .ics file in the vault written by export
 }
 
 type TemplatesConfig struct {
 	Folder string `toml:"folder"`
 }
 
+// AttachmentConfig controls where imported attachments are stored and how they are linked
+type AttachmentConfig struct {
+	Location  string `toml:"location"`   // folder, subfolder or note
+	Folder    string `toml:"folder"`     // Vault folder (folder) or folder name next to the note (subfolder)
+	LinkStyle string `toml:"link_style"` // wikilink or markdown
+
+	// Per-folder overrides: notes inside a key folder store attachments in the value folder
+	Folders map[string]string `toml:"folders"`
+}
+
+// Attachment location constants
+const (
+	AttachmentLocationFolder    = "folder"    // One folder for the whole vault
+	AttachmentLocationSubfolder = "subfolder" // A folder inside each note's folder
+	AttachmentLocationNote      = "note"      // Next to the note
+)
+
+// Attachment link style constants
+const (
+	LinkStyleWikilink = "wikilink"
+	LinkStyleMarkdown = "markdown"
+)
+
+// FilesConfig controls how vault files are recognized
+type FilesConfig struct {
+	MarkdownExtensions []string          `toml:"markdown_extensions"` // Extra extensions opened and indexed as notes, e.g. ".mdx"
+	Types              map[string]string `toml:"types"`               // Extra extensions mapped to file types, e.g. ".excalidraw" = "canvas"
+	Ignore             []string          `toml:"ignore"`              // gitignore-style patterns hidden from the tree, search and indexes
+}
+
+type TrashConfig struct {
+	RetentionDays int `toml:"retention_days"` // Purge trashed items after this many days, 0 keeps them forever
+}
+
+// HistoryConfig controls local version snapshots of notes in .obails/history
+type HistoryConfig struct {
+	Enabled         bool `toml:"enabled"`
+	IntervalMinutes int  `toml:"interval_minutes"` // Minimum time between snapshots of a note
+	RetentionDays   int  `toml:"retention_days"`   // Drop snapshots after this many days, 0 keeps them forever
+}
+
+// GitConfig controls committing the vault when it is a git repository
+type GitConfig struct {
+	AutoCommit      string `toml:"auto_commit"`      // off, interval or save
+	IntervalMinutes int    `toml:"interval_minutes"` // For interval auto-commits
+	MessageTemplate string `toml:"message_template"` // Go template with .Date, .Time, .Count and .Files
+	Remote          string `toml:"remote"`
+	AuthorName      string `toml:"author_name"`
+	AuthorEmail     string `toml:"author_email"`
+}
+
+// Git auto-commit mode constants
+const (
+	GitAutoCommitOff      = "off"
+	GitAutoCommitInterval = "interval"
+	GitAutoCommitSave     = "save"
+)
+
 type EditorConfig struct {
 	FontSize    int    `toml:"font_size"`
 	FontFamily  string `toml:"font_family"`
@@ -51,14 +156,43 @@ func DefaultConfig() *Config {
 			Folder:   "02_dailynotes",
 			Format:   "2006-01-02",
 			Template: "daily_note",
+			Rollover: RolloverConfig{
+				Enabled:  false,
+				Mode:     RolloverModeCopy,
+				Sections: []string{"## Todo", "## Day Planner"},
+			},
 		},
 		Timeline: TimelineConfig{
-			Section:    "## Memos",
-			TimeFormat: "15:04",
+			Section:     "## Memos",
+			TimeFormat:  "15:04",
+			InsertOrder: TimelineOrderNewestFirst,
+		},
+		Planner: PlannerConfig{
+			Section:         "## Day Planner",
+			DefaultDuration: 30,
+		},
+		Calendar: CalendarConfig{
+			ExportPath: "calendar.ics",
 		},
 		Templates: TemplatesConfig{
 			Folder: "99_template",
 		},
+		Attachments: AttachmentConfig{
+			Location:  AttachmentLocationFolder,
+			Folder:    "attachments",
+			LinkStyle: LinkStyleWikilink,
+		},
+		History: HistoryConfig{
+			Enabled:         true,
+			IntervalMinutes: 5,
+			RetentionDays:   7,
+		},
+		Git: GitConfig{
+			AutoCommit:      GitAutoCommitOff,
+			IntervalMinutes: 10,
+			MessageTemplate: "vault backup: {{.Date}} {{.Time}}",
+			Remote:          "origin",
+		},
 		Editor: EditorConfig{
 			FontSize:    14,
 			FontFamily:  "SF Mono",
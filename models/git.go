@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// GitStatus describes the git repository containing the vault
+type GitStatus struct {
+	IsRepo bool            `json:"isRepo"`
+	Branch string          `json:"branch"`
+	Clean  bool            `json:"clean"`
+	Files  []GitFileStatus `json:"files"` // Changed files only
+}
+
+// GitFileStatus is the status of a changed file, for decorating the file tree
+type GitFileStatus struct {
+	Path   string `json:"path"` // Vault-relative
+	Status string `json:"status"`
+	Staged bool   `json:"staged"`
+}
+
+// Git file status constants
+const (
+	GitStatusModified   = "modified"
+	GitStatusAdded      = "added"
+	GitStatusDeleted    = "deleted"
+	GitStatusRenamed    = "renamed"
+	GitStatusUntracked  = "untracked"
+	GitStatusConflicted = "conflicted"
+)
+
+// GitCommit is a commit in the vault repository
+type GitCommit struct {
+	Hash    string    `json:"hash"`
+	Message string    `json:"message"`
+	Author  string    `json:"author"`
+	Email   string    `json:"email"`
+	When    time.Time `json:"when"`
+}
+
+// GitBlameLine attributes a line of a note to the commit that last changed it
+type GitBlameLine struct {
+	Line   int       `json:"line"` // 1-based
+	Text   string    `json:"text"`
+	Hash   string    `json:"hash"`
+	Author string    `json:"author"`
+	When   time.Time `json:"when"`
+}
+
+// GitSyncResult describes a pull or push
+type GitSyncResult struct {
+	Status    string   `json:"status"`
+	Conflicts []string `json:"conflicts"` // Vault-relative files blocking the sync
+}
+
+// Git sync status constants
+const (
+	GitSyncUpToDate    = "up_to_date"
+	GitSyncUpdated     = "updated"
+	GitSyncDiverged    = "diverged"    // Both sides have new commits; Conflicts lists files changed on both
+	GitSyncUncommitted = "uncommitted" // Pull refused; Conflicts lists the uncommitted files
+)
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// NoteSnapshot is a recorded version of a note in the local history
+type NoteSnapshot struct {
+	ID        int       `json:"id"` // Increasing per note
+	Path      string    `json:"path"`
+	Hash      string    `json:"hash"`
+	Size      int       `json:"size"` // Bytes
+	CreatedAt time.Time `json:"createdAt"`
+	Source    string    `json:"source"`
+}
+
+// Snapshot source constants
+const (
+	SnapshotSourceSave     = "save"     // Content saved from the app
+	SnapshotSourceExternal = "external" // Content changed outside the app, captured before overwriting
+	SnapshotSourceRestore  = "restore"  // Content replaced by restoring another version
+)
+
+// DiffLine is one line of a line-based diff between two versions
+type DiffLine struct {
+	Op      string `json:"op"`
+	Text    string `json:"text"`
+	OldLine int    `json:"oldLine,omitempty"` // 1-based, 0 for inserted lines
+	NewLine int    `json:"newLine,omitempty"` // 1-based, 0 for deleted lines
+}
+
+// Diff operation constants
+const (
+	DiffOpEqual  = "equal"
+	DiffOpInsert = "insert"
+	DiffOpDelete = "delete"
+)
@@ -9,15 +9,88 @@ type Note struct {
 	Content     string         `json:"content"`
 	Frontmatter map[string]any `json:"frontmatter"`
 	ModifiedAt  time.Time      `json:"modifiedAt"`
+	Hash        string         `json:"hash"` // SHA-256 of Content, for conflict detection
+}
+
+// NoteVersion identifies the on-disk version of a note the editor loaded.
+// A zero field is not checked.
+type NoteVersion struct {
+	ModifiedAt time.Time `json:"modifiedAt"`
+	Hash       string    `json:"hash"`
+}
+
+// Save status constants
+const (
+	SaveStatusSaved    = "saved"    // Written as given
+	SaveStatusMerged   = "merged"   // Merged cleanly with changes made on disk, then written
+	SaveStatusConflict = "conflict" // Not written; the frontend has to resolve
+)
+
+// SaveResult describes the outcome of a save that merges external changes
+type SaveResult struct {
+	Status    string      `json:"status"`
+	Content   string      `json:"content"`          // Content on disk, or the conflict-marked merge
+	Version   NoteVersion `json:"version"`          // Version on disk after the save
+	Base      string      `json:"base,omitempty"`   // Version the editor loaded (conflict only)
+	Ours      string      `json:"ours,omitempty"`   // Editor content (conflict only)
+	Theirs    string      `json:"theirs,omitempty"` // Content changed on disk (conflict only)
+	Conflicts int         `json:"conflicts"`        // Number of conflicting regions
 }
 
 // Timeline represents a quick memo entry in daily notes
 type Timeline struct {
-	Time    string `json:"time"`    // "10:38"
-	Content string `json:"content"` // The memo content
-	IsTodo  bool   `json:"isTodo"`  // true if [ ] or [x]
-	Done    bool   `json:"done"`    // true if [x]
-	Date    string `json:"date"`    // "Today", "Yesterday", or "MM/DD"
+	Time    string `json:"time"`              // "10:38"
+	Content string `json:"content"`           // The memo content
+	IsTodo  bool   `json:"isTodo"`            // true if [ ] or [x]
+	Done    bool   `json:"done"`              // true if [x]
+	Date    string `json:"date"`              // "Today", "Yesterday", or "MM/DD"
+	ISODate string `json:"isoDate,omitempty"` // "2026-10-16"
+	SortKey string `json:"sortKey,omitempty"` // "2026-10-16T10:38:00#0012", sorts chronologically
+	Path    string `json:"path,omitempty"`    // Daily note containing the entry
+	Line    int    `json:"line,omitempty"`    // 1-based line number in the note
+}
+
+// TimelineQuery filters Timeline entries across daily notes
+type TimelineQuery struct {
+	From     string `json:"from"`     // "YYYY-MM-DD", inclusive (empty = no lower bound)
+	To       string `json:"to"`       // "YYYY-MM-DD", inclusive (empty = no upper bound)
+	Text     string `json:"text"`     // Case-insensitive substring filter on content
+	TodoOnly bool   `json:"todoOnly"` // Only entries with a checkbox
+	Offset   int    `json:"offset"`
+	Limit    int    `json:"limit"` // 0 = no limit
+}
+
+// TimelinePage is one page of a Timeline query result, newest first
+type TimelinePage struct {
+	Items   []Timeline `json:"items"`
+	Total   int        `json:"total"`   // Matches before paging
+	HasMore bool       `json:"hasMore"` // More items after this page
+}
+
+// PlannerBlock represents a time block in the Day Planner section of a daily note,
+// e.g. "- [ ] 09:00 - 10:30 Deep work"
+type PlannerBlock struct {
+	Line        int    `json:"line"`        // 1-based line number in the note
+	Start       string `json:"start"`       // "09:00"
+	End         string `json:"end"`         // "10:30", computed when not written
+	ExplicitEnd bool   `json:"explicitEnd"` // true if the end time is written in the note
+	Duration    int    `json:"duration"`    // Minutes
+	Text        string `json:"text"`
+	IsTodo      bool   `json:"isTodo"`
+	Done        bool   `json:"done"`
+}
+
+// PlannerStatus describes the current and upcoming planner blocks for today
+type PlannerStatus struct {
+	Date string        `json:"date"` // "YYYY-MM-DD"
+	Now  *PlannerBlock `json:"now,omitempty"`
+	Next *PlannerBlock `json:"next,omitempty"`
+}
+
+// DailyNoteInfo identifies a daily note file by its date
+type DailyNoteInfo struct {
+	Date string `json:"date"` // "YYYY-MM-DD"
+	Path string `json:"path"`
 }
 
 // FileType constants
@@ -26,6 +99,11 @@ const (
 	FileTypeImage    = "image"
 	FileTypePDF      = "pdf"
 	FileTypeHTML     = "html"
+	FileTypeAudio    = "audio"
+	FileTypeVideo    = "video"
+	FileTypeText     = "text" // plain text and source code
+	FileTypeData     = "data" // csv, json, yaml and the like
+	FileTypeCanvas   = "canvas"
 	FileTypeOther    = "other"
 )
 
@@ -34,11 +112,23 @@ type FileInfo struct {
 	Name       string     `json:"name"`
 	Path       string     `json:"path"`
 	IsDir      bool       `json:"isDir"`
-	FileType   string     `json:"fileType,omitempty"` // markdown, image, pdf, html, other
+	FileType   string     `json:"fileType,omitempty"` // markdown, image, pdf, html, audio, video, text, data, canvas, other
 	Children   []FileInfo `json:"children,omitempty"`
+	ChildCount int        `json:"childCount"` // Entries in a folder, set by the lazy tree API
+	Size       int64      `json:"size,omitempty"`
+	GitStatus  string     `json:"gitStatus,omitempty"` // Git file status constant, empty when unchanged
+	CreatedAt  time.Time  `json:"createdAt"`
 	ModifiedAt time.Time  `json:"modifiedAt"`
 }
 
+// TreePage is one page of the entries of a folder in the file tree
+type TreePage struct {
+	Path       string     `json:"path"`
+	Entries    []FileInfo `json:"entries"`
+	Total      int        `json:"total"`      // Number of entries in the folder
+	NextCursor string     `json:"nextCursor"` // Empty on the last page
+}
+
 // Link represents a wiki-style link [[text]]
 type Link struct {
 	Text       string `json:"text"`       // The link text
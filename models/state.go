@@ -1,17 +1,75 @@
 package models
 
+import "time"
+
 // State represents the application session state stored in vault
 type State struct {
-	LastOpenedFile *LastOpenedFile `json:"lastOpenedFile,omitempty"`
+	Version        int                      `json:"version"` // Schema version, see CurrentStateVersion
+	LastOpenedFile *LastOpenedFile          `json:"lastOpenedFile,omitempty"`
+	FileTree       FileTreeState            `json:"fileTree"`
+	Bookmarks      []BookmarkGroup          `json:"bookmarks,omitempty"`
+	Workspace      Workspace                `json:"workspace"`
+	NoteViews      map[string]NoteViewState `json:"noteViews,omitempty"`   // By note path, with forward slashes
+	RecentFiles    []RecentFile             `json:"recentFiles,omitempty"` // Most recently opened first
+	Navigation     NavigationHistory        `json:"navigation"`
 }
 
+// CurrentStateVersion is the schema version of State written by this version of the
+// app. Files without a version predate the workspace and are version 0.
+const CurrentStateVersion = 1
+
 // LastOpenedFile represents the last opened file information
 type LastOpenedFile struct {
 	Path     string `json:"path"`
 	FileType string `json:"fileType"`
 }
 
+// RecentFile is a recently opened file
+type RecentFile struct {
+	Path     string    `json:"path"` // With forward slashes
+	FileType string    `json:"fileType"`
+	OpenedAt time.Time `json:"openedAt"`
+}
+
+// NavigationHistory is the back/forward stack of opened files. Index is the
+// current entry; entries after it can be navigated forward to.
+type NavigationHistory struct {
+	Entries []NavigationEntry `json:"entries"`
+	Index   int               `json:"index"`
+}
+
+// NavigationEntry is a file in the navigation history
+type NavigationEntry struct {
+	Path     string `json:"path"` // With forward slashes
+	FileType string `json:"fileType"`
+}
+
+// FileTreeState holds how the file tree is sorted. Paths use forward slashes and
+// "." is the vault root.
+type FileTreeState struct {
+	Sort        map[string]TreeSort `json:"sort,omitempty"`        // By folder, inherited by subfolders
+	Pinned      []string            `json:"pinned,omitempty"`      // Entries listed first in their folder
+	ManualOrder map[string][]string `json:"manualOrder,omitempty"` // Entry names by folder, for TreeSortManual
+}
+
+// TreeSort is the sort order of a folder in the file tree
+type TreeSort struct {
+	Mode       string `json:"mode"`
+	Descending bool   `json:"descending"`
+}
+
+// TreeSort mode constants. Folders always come before files; without a mode, folders
+// are sorted by name and files newest first.
+const (
+	TreeSortName     = "name"
+	TreeSortNatural  = "natural" // Names with numbers in numeric order, e.g. 2 before 10
+	TreeSortCreated  = "created"
+	TreeSortModified = "modified"
+	TreeSortSize     = "size"
+	TreeSortManual   = "manual"
+)
+
 // DefaultState returns the default state
 func DefaultState() *State {
-	return &State{}
+	return &State{Version: CurrentStateVersion}
 }
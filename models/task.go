@@ -0,0 +1,35 @@
+package models
+
+// Task priority constants (Obsidian Tasks emoji syntax)
+const (
+	TaskPriorityHighest = "highest" // 🔺
+	TaskPriorityHigh    = "high"    // ⏫
+	TaskPriorityMedium  = "medium"  // 🔼
+	TaskPriorityNone    = ""
+	TaskPriorityLow     = "low"    // 🔽
+	TaskPriorityLowest  = "lowest" // ⏬
+)
+
+// Task represents a checkbox item ("- [ ] text") somewhere in the vault
+type Task struct {
+	Path     string   `json:"path"`              // Note containing the task
+	Line     int      `json:"line"`              // 1-based line number
+	Text     string   `json:"text"`              // Text after the checkbox
+	Done     bool     `json:"done"`              // true if [x], or [-] for cancelled
+	Heading  string   `json:"heading,omitempty"` // Closest heading above the task
+	Due      string   `json:"due,omitempty"`     // "YYYY-MM-DD" from "📅 2026-10-20" or "due:2026-10-20"
+	Priority string   `json:"priority,omitempty"`
+	Tags     []string `json:"tags,omitempty"` // Without the leading #
+}
+
+// TaskQuery filters tasks in the vault index. Empty fields don't filter.
+type TaskQuery struct {
+	Status    string `json:"status"`    // "open", "done", or "" for all
+	Path      string `json:"path"`      // Path prefix (note or folder)
+	Tag       string `json:"tag"`       // Tag without the leading #
+	Text      string `json:"text"`      // Case-insensitive substring
+	DueAfter  string `json:"dueAfter"`  // "YYYY-MM-DD", inclusive
+	DueBefore string `json:"dueBefore"` // "YYYY-MM-DD", inclusive
+	HasDue    bool   `json:"hasDue"`    // Only tasks with a due date
+	Priority  string `json:"priority"`
+}
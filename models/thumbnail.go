@@ -0,0 +1,10 @@
+package models
+
+// Thumbnail is a scaled-down preview of an image in the vault
+type Thumbnail struct {
+	Path     string `json:"path"`     // Vault-relative path of the source image
+	Width    int    `json:"width"`    // Pixels
+	Height   int    `json:"height"`   // Pixels
+	MimeType string `json:"mimeType"` // image/png or image/jpeg
+	Data     string `json:"data"`     // Base64 encoded
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// TrashItem describes a file or folder moved to the vault trash
+type TrashItem struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	OriginalPath string    `json:"originalPath"` // Vault-relative path before deletion
+	IsDir        bool      `json:"isDir"`
+	Size         int64     `json:"size"` // Bytes, summed over folder contents
+	DeletedAt    time.Time `json:"deletedAt"`
+}
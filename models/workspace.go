@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Workspace is the layout of the app, restored when the vault is opened again
+type Workspace struct {
+	Panes           []WorkspacePane `json:"panes"`
+	ActivePane      int             `json:"activePane"`
+	ExpandedFolders []string        `json:"expandedFolders"` // Folders expanded in the file tree
+	Sidebars        SidebarLayout   `json:"sidebars"`
+	Graph           GraphViewState  `json:"graph"`
+}
+
+// WorkspacePane is a pane of open tabs, in display order
+type WorkspacePane struct {
+	Tabs      []WorkspaceTab `json:"tabs"`
+	ActiveTab int            `json:"activeTab"`
+	Size      float64        `json:"size,omitempty"` // Share of the editor area, 0 for an even split
+}
+
+// WorkspaceTab is an open file
+type WorkspaceTab struct {
+	Path     string `json:"path"`
+	FileType string `json:"fileType"`
+}
+
+// SidebarLayout holds the widths in pixels and visibility of the sidebars
+type SidebarLayout struct {
+	LeftWidth      int  `json:"leftWidth,omitempty"`
+	RightWidth     int  `json:"rightWidth,omitempty"`
+	LeftCollapsed  bool `json:"leftCollapsed"`
+	RightCollapsed bool `json:"rightCollapsed"`
+}
+
+// GraphViewState holds the settings of the graph view
+type GraphViewState struct {
+	Open            bool    `json:"open"`
+	Zoom            float64 `json:"zoom,omitempty"`
+	CenterX         float64 `json:"centerX"`
+	CenterY         float64 `json:"centerY"`
+	Filter          string  `json:"filter,omitempty"` // Search query limiting the shown notes
+	ShowOrphans     bool    `json:"showOrphans"`
+	ShowAttachments bool    `json:"showAttachments"`
+}
+
+// NoteViewState is where a note was scrolled to and where its cursor was
+type NoteViewState struct {
+	ScrollTop    float64   `json:"scrollTop"`
+	CursorLine   int       `json:"cursorLine"`   // 0-based
+	CursorColumn int       `json:"cursorColumn"` // 0-based, in characters of the line
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// VaultAssetPrefix is the URL path under which vault files are served, e.g.
+// /vault/attachments/image.png
+const VaultAssetPrefix = "/vault/"
+
+// VaultAssetHandler streams vault files to the webview, so images and PDFs don't
+// have to be base64 encoded over the bindings. Range requests and conditional
+// requests (ETag, Last-Modified) are handled by http.ServeContent.
+type VaultAssetHandler struct {
+	fileService *FileService
+}
+
+// NewVaultAssetHandler creates a handler serving files of the current vault
+func NewVaultAssetHandler(fileService *FileService) *VaultAssetHandler {
+	return &VaultAssetHandler{fileService: fileService}
+}
+
+// Middleware routes requests under VaultAssetPrefix to the handler and all other
+// requests to next
+func (h *VaultAssetHandler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, VaultAssetPrefix) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *VaultAssetHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	relativePath, ok := strings.CutPrefix(r.URL.Path, VaultAssetPrefix)
+	if !ok || relativePath == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Hidden files such as .obails/ and .git/ are not vault content
+	for _, segment := range strings.Split(relativePath, "/") {
+		if strings.HasPrefix(segment, ".") && segment != "." && segment != ".." {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	fullPath, err := h.fileService.getEntryPath(relativePath)
+	if err != nil {
+		http.Error(w, err.Error(), assetErrorStatus(err))
+		return
+	}
+
+	f, err := os.Open(fullPath)
+	if err != nil {
+		http.Error(w, "file not found", assetErrorStatus(err))
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	_, mimeType := h.fileService.types.detect(fullPath)
+
+	header := w.Header()
+	header.Set("Content-Type", mimeType)
+	header.Set("ETag", fmt.Sprintf(`"%x-%x"`, info.Size(), info.ModTime().UnixNano()))
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Content-Type-Options", "nosniff")
+	// Vault HTML must not run scripts with access to the app bindings
+	header.Set("Content-Security-Policy", "sandbox")
+
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
+
+// assetErrorStatus maps a file error to an HTTP status code
+func assetErrorStatus(err error) int {
+	var pathErr *VaultPathError
+	switch {
+	case errors.As(err, &pathErr):
+		if errors.Is(err, ErrVaultNotSet) {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusForbidden
+	case errors.Is(err, fs.ErrNotExist):
+		return http.StatusNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
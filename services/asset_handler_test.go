@@ -0,0 +1,110 @@
+package services
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVaultAssetHandler(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs := NewFileService(cs)
+	fs.CreateFile("files/my doc.pdf", "0123456789")
+	fs.CreateFile(".obails/state.json", "{}")
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler := NewVaultAssetHandler(fs).Middleware(next)
+
+	serve := func(method string, target string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("full file", func(t *testing.T) {
+		rec := serve(http.MethodGet, "/vault/files/my%20doc.pdf", nil)
+		body, _ := io.ReadAll(rec.Body)
+		if rec.Code != http.StatusOK || string(body) != "0123456789" {
+			t.Fatalf("Unexpected response %d: %q", rec.Code, body)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/pdf" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		if rec.Header().Get("ETag") == "" || rec.Header().Get("Last-Modified") == "" {
+			t.Errorf("Missing caching headers: %v", rec.Header())
+		}
+	})
+
+	t.Run("range request", func(t *testing.T) {
+		rec := serve(http.MethodGet, "/vault/files/my%20doc.pdf", map[string]string{"Range": "bytes=2-5"})
+		body, _ := io.ReadAll(rec.Body)
+		if rec.Code != http.StatusPartialContent || string(body) != "2345" {
+			t.Errorf("Unexpected response %d: %q", rec.Code, body)
+		}
+		if cr := rec.Header().Get("Content-Range"); cr != "bytes 2-5/10" {
+			t.Errorf("Content-Range = %q", cr)
+		}
+	})
+
+	t.Run("conditional requests", func(t *testing.T) {
+		first := serve(http.MethodGet, "/vault/files/my%20doc.pdf", nil)
+
+		rec := serve(http.MethodGet, "/vault/files/my%20doc.pdf", map[string]string{"If-None-Match": first.Header().Get("ETag")})
+		if rec.Code != http.StatusNotModified {
+			t.Errorf("Expected 304 for matching ETag, got %d", rec.Code)
+		}
+		rec = serve(http.MethodGet, "/vault/files/my%20doc.pdf", map[string]string{"If-Modified-Since": first.Header().Get("Last-Modified")})
+		if rec.Code != http.StatusNotModified {
+			t.Errorf("Expected 304 for unmodified file, got %d", rec.Code)
+		}
+
+		// A changed file gets a new ETag
+		os.WriteFile(filepath.Join(tmpDir, "files", "my doc.pdf"), []byte("changed content"), 0644)
+		rec = serve(http.MethodGet, "/vault/files/my%20doc.pdf", map[string]string{"If-None-Match": first.Header().Get("ETag")})
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected 200 for changed file, got %d", rec.Code)
+		}
+	})
+
+	t.Run("rejected requests", func(t *testing.T) {
+		tests := []struct {
+			method string
+			target string
+			code   int
+		}{
+			{http.MethodGet, "/vault/missing.png", http.StatusNotFound},
+			{http.MethodGet, "/vault/files", http.StatusNotFound},
+			{http.MethodGet, "/vault/", http.StatusNotFound},
+			{http.MethodGet, "/vault/.obails/state.json", http.StatusNotFound},
+			{http.MethodGet, "/vault/files/%2E%2E/%2E%2E/etc/passwd", http.StatusForbidden},
+			{http.MethodPost, "/vault/files/my%20doc.pdf", http.StatusMethodNotAllowed},
+		}
+		for _, tt := range tests {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			req.URL.Path, _ = url.PathUnescape(tt.target)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.code {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.target, rec.Code, tt.code)
+			}
+		}
+	})
+
+	t.Run("other requests pass through", func(t *testing.T) {
+		if rec := serve(http.MethodGet, "/index.html", nil); rec.Code != http.StatusTeapot {
+			t.Errorf("Expected pass-through, got %d", rec.Code)
+		}
+	})
+}
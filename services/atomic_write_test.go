@@ -0,0 +1,105 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/kazuph/obails/models"
+)
+
+func TestAtomicWriteFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "note.md")
+
+	t.Run("creates new file", func(t *testing.T) {
+		if err := atomicWriteFile(path, []byte("first"), 0644); err != nil {
+			t.Fatalf("atomicWriteFile failed: %v", err)
+		}
+		content, _ := os.ReadFile(path)
+		if string(content) != "first" {
+			t.Errorf("Content mismatch: %q", content)
+		}
+	})
+
+	t.Run("replaces content and keeps permissions", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("Unix permissions only")
+		}
+		os.Chmod(path, 0600)
+
+		if err := atomicWriteFile(path, []byte("second"), 0644); err != nil {
+			t.Fatalf("atomicWriteFile failed: %v", err)
+		}
+
+		info, _ := os.Stat(path)
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("Permissions not preserved: %v", info.Mode().Perm())
+		}
+		content, _ := os.ReadFile(path)
+		if string(content) != "second" {
+			t.Errorf("Content mismatch: %q", content)
+		}
+	})
+
+	t.Run("writes through symlinks", func(t *testing.T) {
+		link := filepath.Join(tmpDir, "link.md")
+		if err := os.Symlink(path, link); err != nil {
+			t.Skipf("Symlinks not supported: %v", err)
+		}
+
+		if err := atomicWriteFile(link, []byte("third"), 0644); err != nil {
+			t.Fatalf("atomicWriteFile failed: %v", err)
+		}
+
+		if info, _ := os.Lstat(link); info.Mode()&os.ModeSymlink == 0 {
+			t.Error("Symlink was replaced by a regular file")
+		}
+		content, _ := os.ReadFile(path)
+		if string(content) != "third" {
+			t.Errorf("Target not updated: %q", content)
+		}
+	})
+
+	t.Run("leaves no temporary files", func(t *testing.T) {
+		entries, _ := os.ReadDir(tmpDir)
+		for _, entry := range entries {
+			if entry.Name() != "note.md" && entry.Name() != "link.md" {
+				t.Errorf("Unexpected file left behind: %s", entry.Name())
+			}
+		}
+	})
+
+	t.Run("fails for missing directory", func(t *testing.T) {
+		if err := atomicWriteFile(filepath.Join(tmpDir, "missing", "note.md"), []byte("x"), 0644); err == nil {
+			t.Error("Should fail when the directory doesn't exist")
+		}
+	})
+}
+
+func TestConfigService_SaveAtomic(t *testing.T) {
+	tmpDir := t.TempDir()
+	cs := &ConfigService{
+		configPath: filepath.Join(tmpDir, "config.toml"),
+		config:     models.DefaultConfig(),
+	}
+	cs.config.Vault.Path = "/path/to/vault"
+
+	if err := cs.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded := &ConfigService{configPath: cs.configPath, config: models.DefaultConfig()}
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.GetVaultPath() != "/path/to/vault" {
+		t.Errorf("Vault path not saved: %q", loaded.GetVaultPath())
+	}
+
+	entries, _ := os.ReadDir(tmpDir)
+	if len(entries) != 1 {
+		t.Errorf("Expected only config.toml, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,428 @@
+package services
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kazuph/obails/models"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// iCalendar date-time layouts
+const (
+	icsDateLayout     = "20060102"
+	icsDateTimeLayout = "20060102T150405"
+)
+
+// Match ISO 8601 durations used by DURATION, e.g. "PT1H30M" or "P1D"
+var icsDurationRegex = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// icsPriorities maps task priorities to iCalendar PRIORITY values (1 = highest)
+var icsPriorities = map[string]int{
+	models.TaskPriorityHighest: 1,
+	models.TaskPriorityHigh:    3,
+	models.TaskPriorityMedium:  5,
+	models.TaskPriorityLow:     7,
+	models.TaskPriorityLowest:  9,
+}
+
+// CalendarService imports and exports iCalendar (.ics) files for planner blocks and tasks
+type CalendarService struct {
+	noteService   *NoteService
+	taskService   *TaskService
+	fileService   *FileService
+	configService *ConfigService
+	app           *application.App
+}
+
+// NewCalendarService creates a new CalendarService
+func NewCalendarService(noteService *NoteService, taskService *TaskService, fileService *FileService, configService *ConfigService) *CalendarService {
+	return &CalendarService{
+		noteService:   noteService,
+		taskService:   taskService,
+		fileService:   fileService,
+		configService: configService,
+	}
+}
+
+// ExportICS writes planner blocks of daily notes and dated tasks between from and to
+// ("YYYY-MM-DD", inclusive, empty = unbounded) to the configured .ics file in the vault
+func (s *CalendarService) ExportICS(from string, to string) (*models.CalendarExportResult, error) {
+	for _, dateStr := range []string{from, to} {
+		if dateStr == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", dateStr); err != nil {
+			return nil, fmt.Errorf("invalid date %q: %w", dateStr, err)
+		}
+	}
+
+	notes, err := s.noteService.ListDailyNotes()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.CalendarExportResult{Path: s.configService.GetCalendarExportPath()}
+	stamp := time.Now().UTC().Format(icsDateTimeLayout) + "Z"
+
+	var b strings.Builder
+	writeICSLine(&b, "BEGIN:VCALENDAR")
+	writeICSLine(&b, "VERSION:2.0")
+	writeICSLine(&b, "PRODID:-//Obails//Obails//EN")
+	writeICSLine(&b, "CALSCALE:GREGORIAN")
+
+	for _, info := range notes {
+		if (from != "" && info.Date < from) || (to != "" && info.Date > to) {
+			continue
+		}
+
+		blocks, err := s.noteService.GetPlanner(info.Date)
+		if err != nil {
+			continue
+		}
+
+		date, _ := time.Parse("2006-01-02", info.Date)
+		for _, block := range blocks {
+			start := date.Add(time.Duration(mustClockMinutes(block.Start)) * time.Minute)
+			end := date.Add(time.Duration(mustClockMinutes(block.End)) * time.Minute)
+
+			writeICSLine(&b, "BEGIN:VEVENT")
+			writeICSLine(&b, "UID:"+icsUID("event", info.Date, block.Start, block.Text))
+			writeICSLine(&b, "DTSTAMP:"+stamp)
+			writeICSLine(&b, "DTSTART:"+start.Format(icsDateTimeLayout))
+			writeICSLine(&b, "DTEND:"+end.Format(icsDateTimeLayout))
+			writeICSLine(&b, "SUMMARY:"+escapeICSText(block.Text))
+			writeICSLine(&b, "END:VEVENT")
+			result.Events++
+		}
+	}
+
+	// Tasks in notes edited outside the app aren't indexed yet
+	if err := s.taskService.RebuildIndex(); err != nil {
+		return nil, err
+	}
+	tasks := s.taskService.QueryTasks(models.TaskQuery{HasDue: true, DueAfter: from, DueBefore: to})
+	for _, task := range tasks {
+		due, err := time.Parse("2006-01-02", task.Due)
+		if err != nil {
+			continue
+		}
+
+		writeICSLine(&b, "BEGIN:VTODO")
+		writeICSLine(&b, "UID:"+icsUID("todo", task.Path, task.Text))
+		writeICSLine(&b, "DTSTAMP:"+stamp)
+		writeICSLine(&b, "DUE;VALUE=DATE:"+due.Format(icsDateLayout))
+		writeICSLine(&b, "SUMMARY:"+escapeICSText(cleanTaskText(task.Text)))
+		if task.Done {
+			writeICSLine(&b, "STATUS:COMPLETED")
+		} else {
+			writeICSLine(&b, "STATUS:NEEDS-ACTION")
+		}
+		if priority, ok := icsPriorities[task.Priority]; ok {
+			writeICSLine(&b, "PRIORITY:"+strconv.Itoa(priority))
+		}
+		if len(task.Tags) > 0 {
+			writeICSLine(&b, "CATEGORIES:"+escapeICSText(strings.Join(task.Tags, ",")))
+		}
+		writeICSLine(&b, "DESCRIPTION:"+escapeICSText(task.Path))
+		writeICSLine(&b, "END:VTODO")
+		result.Todos++
+	}
+
+	writeICSLine(&b, "END:VCALENDAR")
+
+	if err := s.fileService.WriteFile(result.Path, b.String()); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ImportICS reads VEVENTs from a local .ics file and adds timed events as blocks to the
+// planner section of the daily note for their start date. Events already in the planner
+// (same start time and text) are skipped, as are all-day events. Recurring events are
+// counted separately and not imported, since a single block would only hold their
+// first occurrence.
+func (s *CalendarService) ImportICS(sourcePath string) (*models.CalendarImportResult, error) {
+	data, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.CalendarImportResult{Dates: []string{}}
+	changed := make(map[string]bool)
+
+	for _, event := range parseICSEvents(string(data)) {
+		if isRecurringICSEvent(event) {
+			result.Recurring++
+			continue
+		}
+
+		summary := strings.Join(strings.Fields(event["SUMMARY"].value), " ")
+		start, allDay, err := parseICSTime(event["DTSTART"])
+		if err != nil || allDay || summary == "" {
+			result.Skipped++
+			continue
+		}
+
+		end := start
+		if prop, ok := event["DTEND"]; ok {
+			if t, _, err := parseICSTime(prop); err == nil {
+				end = t
+			}
+		} else if prop, ok := event["DURATION"]; ok {
+			if d, err := parseICSDuration(prop.value); err == nil {
+				end = start.Add(d)
+			}
+		}
+
+		dateStr := start.Format("2006-01-02")
+		startClock := start.Format("15:04")
+		endClock := ""
+		if end.After(start) {
+			endClock = end.Format("15:04")
+			if end.Format("2006-01-02") != dateStr {
+				endClock = "24:00"
+			}
+		}
+
+		if s.hasPlannerBlock(dateStr, startClock, summary) {
+			result.Skipped++
+			continue
+		}
+
+		if _, err := s.noteService.AddPlannerBlock(dateStr, startClock, endClock, summary); err != nil {
+			result.Skipped++
+			continue
+		}
+
+		result.Imported++
+		changed[dateStr] = true
+	}
+
+	for dateStr := range changed {
+		result.Dates = append(result.Dates, dateStr)
+	}
+	sort.Strings(result.Dates)
+
+	return result, nil
+}
+
+// SetApp sets the application reference for dialog support
+func (s *CalendarService) SetApp(app *application.App) {
+	s.app = app
+}
+
+// SelectAndImportICS opens a file dialog for an .ics file and imports it
+func (s *CalendarService) SelectAndImportICS() (*models.CalendarImportResult, error) {
+	if s.app == nil {
+		return nil, nil
+	}
+
+	path, err := s.app.Dialog.OpenFile().
+		SetTitle("Import Calendar").
+		AddFilter("iCalendar", "*.ics").
+		CanChooseFiles(true).
+		CanChooseDirectories(false).
+		PromptForSingleSelection()
+	if err != nil || path == "" {
+		return nil, err
+	}
+
+	return s.ImportICS(path)
+}
+
+func (s *CalendarService) hasPlannerBlock(dateStr string, start string, text string) bool {
+	blocks, err := s.noteService.GetPlanner(dateStr)
+	if err != nil {
+		return false
+	}
+	for _, block := range blocks {
+		if block.Start == start && block.Text == text {
+			return true
+		}
+	}
+	return false
+}
+
+// isRecurringICSEvent reports whether an event repeats, or overrides one occurrence
+// of an event that repeats
+func isRecurringICSEvent(event map[string]icsProperty) bool {
+	for _, name := range []string{"RRULE", "RDATE", "RECURRENCE-ID"} {
+		if _, ok := event[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// icsProperty is a single content line: NAME;PARAM=VALUE:value
+type icsProperty struct {
+	params map[string]string
+	value  string
+}
+
+// parseICSEvents returns the properties of each VEVENT, keyed by property name.
+// Properties of nested components (e.g. VALARM) are ignored.
+func parseICSEvents(data string) []map[string]icsProperty {
+	// Unfold continuation lines
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\n ", "")
+	data = strings.ReplaceAll(data, "\n\t", "")
+
+	var events []map[string]icsProperty
+	var current map[string]icsProperty
+	depth := 0
+
+	for _, line := range strings.Split(data, "\n") {
+		name, prop, ok := parseICSLine(line)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(prop.value, "VEVENT") && current == nil:
+			current = make(map[string]icsProperty)
+			depth = 0
+		case current == nil:
+			continue
+		case name == "BEGIN":
+			depth++
+		case name == "END" && depth > 0:
+			depth--
+		case name == "END" && strings.EqualFold(prop.value, "VEVENT"):
+			events = append(events, current)
+			current = nil
+		case depth == 0:
+			if _, exists := current[name]; !exists {
+				current[name] = prop
+			}
+		}
+	}
+
+	return events
+}
+
+// parseICSLine splits a content line into its upper-cased name, parameters and
+// unescaped value
+func parseICSLine(line string) (string, icsProperty, bool) {
+	// The value starts at the first colon outside a quoted parameter value
+	colon := -1
+	quoted := false
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon <= 0 {
+		return "", icsProperty{}, false
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	prop := icsProperty{
+		params: make(map[string]string),
+		value:  unescapeICSText(line[colon+1:]),
+	}
+	for _, param := range parts[1:] {
+		if key, value, ok := strings.Cut(param, "="); ok {
+			prop.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+
+	return strings.ToUpper(parts[0]), prop, true
+}
+
+// parseICSTime parses a DATE or DATE-TIME property into local time
+func parseICSTime(prop icsProperty) (time.Time, bool, error) {
+	value := strings.TrimSpace(prop.value)
+	if prop.params["VALUE"] == "DATE" || len(value) == len(icsDateLayout) {
+		t, err := time.ParseInLocation(icsDateLayout, value, time.Local)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(icsDateTimeLayout+"Z", value)
+		return t.Local(), false, err
+	}
+
+	loc := time.Local
+	if tzid := prop.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation(icsDateTimeLayout, value, loc)
+	return t.In(time.Local), false, err
+}
+
+// parseICSDuration parses an ISO 8601 duration such as "PT1H30M"
+func parseICSDuration(value string) (time.Duration, error) {
+	matches := icsDurationRegex.FindStringSubmatch(strings.TrimSpace(value))
+	if matches == nil {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, unit := range units {
+		if n, err := strconv.Atoi(matches[i+2]); err == nil {
+			d += time.Duration(n) * unit
+		}
+	}
+	if matches[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
+
+// writeICSLine writes a content line terminated by CRLF, folded at 75 octets
+func writeICSLine(b *strings.Builder, line string) {
+	// Continuation lines start with a space, which counts towards the limit
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		limit = 74
+	}
+	b.WriteString(line + "\r\n")
+}
+
+func escapeICSText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(text)
+}
+
+func unescapeICSText(text string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(text)
+}
+
+// icsUID derives a stable UID from the given parts so re-exports update existing entries
+func icsUID(parts ...string) string {
+	return fmt.Sprintf("%x@obails", sha1.Sum([]byte(strings.Join(parts, "\x00"))))
+}
+
+// cleanTaskText removes due dates and priority markers from a task's text
+func cleanTaskText(text string) string {
+	text = taskDueRegex.ReplaceAllString(text, "")
+	for _, p := range taskPriorities {
+		text = strings.ReplaceAll(text, p.emoji, "")
+	}
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func mustClockMinutes(clock string) int {
+	m, _ := parseClock(clock)
+	return m
+}
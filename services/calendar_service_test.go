@@ -0,0 +1,174 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestCalendarService(t *testing.T) (*CalendarService, *NoteService, *FileService, string) {
+	t.Helper()
+	ns, fs, tmpDir := newTestNoteService(t)
+	ts := NewTaskService(ns, fs, ns.configService)
+	return NewCalendarService(ns, ts, fs, ns.configService), ns, fs, tmpDir
+}
+
+func TestCalendarService_ExportICS(t *testing.T) {
+	cal, _, fs, tmpDir := newTestCalendarService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs.CreateFile("dailynotes/2026-10-18.md", "## Day Planner\n- [ ] 09:00 - 10:30 Deep work; focus\n- [ ] 13:00 Lunch\n")
+	fs.CreateFile("dailynotes/2026-10-25.md", "## Day Planner\n- [ ] 09:00 Out of range\n")
+	fs.CreateFile("projects/app.md", "- [ ] Ship it 📅 2026-10-20 ⏫ #work\n- [ ] No date\n")
+
+	result, err := cal.ExportICS("2026-10-18", "2026-10-21")
+	if err != nil {
+		t.Fatalf("ExportICS failed: %v", err)
+	}
+	if result.Path != "calendar.ics" || result.Events != 2 || result.Todos != 1 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	content, err := fs.ReadFile("calendar.ics")
+	if err != nil {
+		t.Fatalf("Export file not written: %v", err)
+	}
+
+	for _, expected := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"DTSTART:20261018T090000\r\nDTEND:20261018T103000\r\nSUMMARY:Deep work\\; focus\r\n",
+		"DTSTART:20261018T130000\r\nDTEND:20261018T133000\r\n",
+		"DUE;VALUE=DATE:20261020\r\nSUMMARY:Ship it #work\r\nSTATUS:NEEDS-ACTION\r\nPRIORITY:3\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("Export missing %q:\n%s", expected, content)
+		}
+	}
+	if strings.Contains(content, "Out of range") || strings.Contains(content, "No date") {
+		t.Errorf("Export contains entries outside the range:\n%s", content)
+	}
+}
+
+func TestCalendarService_ImportICS(t *testing.T) {
+	cal, ns, fs, tmpDir := newTestCalendarService(t)
+	defer os.RemoveAll(tmpDir)
+
+	utcStart := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"DTSTART:20261018T140000",
+		"DTEND:20261018T150000",
+		"SUMMARY:Design review\\, round 2",
+		"BEGIN:VALARM",
+		"SUMMARY:Alarm",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART:" + utcStart.Format(icsDateTimeLayout) + "Z",
+		"DURATION:PT45M",
+		"SUMMARY:Call with a very long title that needs folding because it is longer",
+		"  than seventy five octets",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20261018",
+		"SUMMARY:Holiday",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART:20261018T110000",
+		"DTEND:20261018T113000",
+		"RRULE:FREQ=WEEKLY;BYDAY=SU",
+		"SUMMARY:Weekly sync",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"RECURRENCE-ID:20261025T110000",
+		"DTSTART:20261025T120000",
+		"SUMMARY:Weekly sync",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	source := filepath.Join(tmpDir, "import.ics")
+	os.WriteFile(source, []byte(ics), 0644)
+	fs.CreateFile("dailynotes/2026-10-18.md", "## Day Planner\n- [ ] 09:00 Plan the day\n")
+
+	result, err := cal.ImportICS(source)
+	if err != nil {
+		t.Fatalf("ImportICS failed: %v", err)
+	}
+	if result.Imported != 2 || result.Skipped != 1 || result.Recurring != 2 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	blocks, _ := ns.GetPlanner("2026-10-18")
+	if len(blocks) != 2 || blocks[1].Text != "Design review, round 2" || blocks[1].End != "15:00" {
+		t.Errorf("Unexpected blocks: %+v", blocks)
+	}
+
+	local := utcStart.Local()
+	blocks, _ = ns.GetPlanner(local.Format("2006-01-02"))
+	found := false
+	for _, block := range blocks {
+		if strings.HasPrefix(block.Text, "Call with a very long title") && block.Start == local.Format("15:04") && block.Duration == 45 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("UTC event not imported in local time: %+v", blocks)
+	}
+
+	t.Run("re-import skips duplicates", func(t *testing.T) {
+		result, _ := cal.ImportICS(source)
+		if result.Imported != 0 || result.Skipped != 3 {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+}
+
+func TestCalendarService_RoundTrip(t *testing.T) {
+	cal, ns, fs, tmpDir := newTestCalendarService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs.CreateFile("dailynotes/2026-10-18.md", "## Day Planner\n- [ ] 09:00 - 10:00 Standup, daily\n")
+	if _, err := cal.ExportICS("", ""); err != nil {
+		t.Fatalf("ExportICS failed: %v", err)
+	}
+
+	os.Rename(filepath.Join(tmpDir, "calendar.ics"), filepath.Join(tmpDir, "exported.ics"))
+	os.Remove(filepath.Join(tmpDir, "dailynotes", "2026-10-18.md"))
+
+	if _, err := cal.ImportICS(filepath.Join(tmpDir, "exported.ics")); err != nil {
+		t.Fatalf("ImportICS failed: %v", err)
+	}
+
+	blocks, _ := ns.GetPlanner("2026-10-18")
+	found := false
+	for _, block := range blocks {
+		if block.Text == "Standup, daily" && block.Start == "09:00" && block.End == "10:00" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Block not restored: %+v", blocks)
+	}
+}
+
+func TestWriteICSLine_Folding(t *testing.T) {
+	var b strings.Builder
+	writeICSLine(&b, "SUMMARY:"+strings.Repeat("日本語", 20))
+
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Line longer than 75 octets: %d", len(line))
+		}
+	}
+
+	events := parseICSEvents("BEGIN:VEVENT\r\n" + b.String() + "END:VEVENT\r\n")
+	if len(events) != 1 || events[0]["SUMMARY"].value != strings.Repeat("日本語", 20) {
+		t.Errorf("Folded line not unfolded correctly: %+v", events)
+	}
+}
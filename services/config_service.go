@@ -1,20 +1,31 @@
 package services
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/kazuph/obails/models"
 	"github.com/wailsapp/wails/v3/pkg/application"
 )
 
-// ConfigService handles application configuration
+// ConfigService handles application configuration. Published configurations
+// aren't changed in place; changes replace them under mu.
 type ConfigService struct {
 	configPath string
-	config     *models.Config
+	config     *models.Config // Effective configuration, with overrides of the open vault
+	base       *models.Config // Configuration from configPath when the vault overrides it, else nil
+	mu         sync.RWMutex   // Guards config and base
 	app        *application.App
+
+	// Switches vaults with the per-vault services rebuilt, set by VaultService
+	switchVault func(path string) error
 }
 
 // NewConfigService creates a new ConfigService
@@ -31,6 +42,9 @@ func NewConfigService() *ConfigService {
 
 // Load reads configuration from file
 func (s *ConfigService) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	// Ensure config directory exists
 	configDir := filepath.Dir(s.configPath)
 	if err := os.MkdirAll(configDir, 0755); err != nil {
@@ -40,73 +54,225 @@ func (s *ConfigService) Load() error {
 	// Check if config file exists
 	if _, err := os.Stat(s.configPath); os.IsNotExist(err) {
 		// Create default config file
-		return s.Save()
+		return s.write(s.global())
 	}
 
 	// Read config file
-	if _, err := toml.DecodeFile(s.configPath, s.config); err != nil {
+	global := models.DefaultConfig()
+	if _, err := toml.DecodeFile(s.configPath, global); err != nil {
 		return err
 	}
+	global.Vaults = rememberVault(global.Vaults, global.Vault.Path, time.Time{})
 
+	// Overrides of the vault are applied again after reading
+	merged, err := vaultOverrides(global)
+	if err != nil {
+		return err
+	}
+	s.use(global, merged)
 	return nil
 }
 
 // Save writes configuration to file
 func (s *ConfigService) Save() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.write(s.global())
+}
+
+// write saves the global configuration to file. The caller must hold mu.
+func (s *ConfigService) write(config *models.Config) error {
 	configDir := filepath.Dir(s.configPath)
 	if err := os.MkdirAll(configDir, 0755); err != nil {
 		return err
 	}
 
-	f, err := os.Create(s.configPath)
-	if err != nil {
+	var buf bytes.Buffer
+	encoder := toml.NewEncoder(&buf)
+	if err := encoder.Encode(config); err != nil {
 		return err
 	}
-	defer f.Close()
 
-	encoder := toml.NewEncoder(f)
-	return encoder.Encode(s.config)
+	return atomicWriteFile(s.configPath, buf.Bytes(), 0644)
 }
 
 // GetConfig returns the current configuration
 func (s *ConfigService) GetConfig() *models.Config {
+	return s.current()
+}
+
+// current returns the effective configuration, which must not be changed
+func (s *ConfigService) current() *models.Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.config
 }
 
+// global returns the configuration without vault overrides, which is what gets
+// saved. The caller must hold mu.
+func (s *ConfigService) global() *models.Config {
+	if s.base != nil {
+		return s.base
+	}
+	return s.config
+}
+
+// use publishes a new global configuration and, if not nil, the configuration
+// with the open vault's overrides. The caller must hold mu.
+func (s *ConfigService) use(global, merged *models.Config) {
+	if merged == nil {
+		s.config, s.base = global, nil
+		return
+	}
+	// Which vault is open and the known vaults are global
+	merged.Vault = global.Vault
+	merged.Vaults = global.Vaults
+	s.config, s.base = merged, global
+}
+
 // GetVaultPath returns the vault path
 func (s *ConfigService) GetVaultPath() string {
-	return s.config.Vault.Path
+	return s.current().Vault.Path
 }
 
-// SetVaultPath sets the vault path and saves
+// SetVaultPath opens another vault and saves
 func (s *ConfigService) SetVaultPath(path string) error {
-	s.config.Vault.Path = path
-	return s.Save()
+	if s.switchVault != nil {
+		return s.switchVault(path)
+	}
+	return s.openVault(path)
 }
 
 // GetDailyNotesFolder returns the daily notes folder relative path
 func (s *ConfigService) GetDailyNotesFolder() string {
-	return s.config.DailyNotes.Folder
+	return s.current().DailyNotes.Folder
 }
 
 // GetDailyNotesFormat returns the daily notes date format
 func (s *ConfigService) GetDailyNotesFormat() string {
-	return s.config.DailyNotes.Format
+	return s.current().DailyNotes.Format
+}
+
+// GetDailyNotesRollover returns the rollover settings with defaults filled in
+func (s *ConfigService) GetDailyNotesRollover() models.RolloverConfig {
+	rollover := s.current().DailyNotes.Rollover
+	if rollover.Mode != models.RolloverModeMove {
+		rollover.Mode = models.RolloverModeCopy
+	}
+	if len(rollover.Sections) == 0 {
+		rollover.Sections = models.DefaultConfig().DailyNotes.Rollover.Sections
+	}
+	return rollover
 }
 
 // GetTimelineSection returns the Timeline section header
 func (s *ConfigService) GetTimelineSection() string {
-	return s.config.Timeline.Section
+	return s.current().Timeline.Section
 }
 
 // GetTimelineTimeFormat returns the Timeline time format
 func (s *ConfigService) GetTimelineTimeFormat() string {
-	return s.config.Timeline.TimeFormat
+	return s.current().Timeline.TimeFormat
+}
+
+// GetTimelineInsertOrder returns where new Timeline entries go (newest_first or oldest_first)
+func (s *ConfigService) GetTimelineInsertOrder() string {
+	if s.current().Timeline.InsertOrder == models.TimelineOrderOldestFirst {
+		return models.TimelineOrderOldestFirst
+	}
+	return models.TimelineOrderNewestFirst
+}
+
+// GetPlannerSection returns the Day Planner section header
+func (s *ConfigService) GetPlannerSection() string {
+	section := s.current().Planner.Section
+	if section == "" {
+		return "## Day Planner"
+	}
+	return section
+}
+
+// GetPlannerDefaultDuration returns the duration in minutes of planner blocks without an end time
+func (s *ConfigService) GetPlannerDefaultDuration() int {
+	duration := s.current().Planner.DefaultDuration
+	if duration <= 0 {
+		return 30
+	}
+	return duration
+}
+
+// GetCalendarExportPath returns the vault-relative path of the exported .ics file
+func (s *ConfigService) GetCalendarExportPath() string {
+	exportPath := s.current().Calendar.ExportPath
+	if exportPath == "" {
+		return "calendar.ics"
+	}
+	return exportPath
 }
 
 // GetTemplatesFolder returns the templates folder relative path
 func (s *ConfigService) GetTemplatesFolder() string {
-	return s.config.Templates.Folder
+	return s.current().Templates.Folder
+}
+
+// GetAttachments returns the attachment settings with defaults filled in
+func (s *ConfigService) GetAttachments() models.AttachmentConfig {
+	attachments := s.current().Attachments
+	switch attachments.Location {
+	case models.AttachmentLocationSubfolder, models.AttachmentLocationNote:
+	default:
+		attachments.Location = models.AttachmentLocationFolder
+	}
+	if attachments.Folder == "" && attachments.Location == models.AttachmentLocationSubfolder {
+		attachments.Folder = "attachments"
+	}
+	if attachments.LinkStyle != models.LinkStyleMarkdown {
+		attachments.LinkStyle = models.LinkStyleWikilink
+	}
+	return attachments
+}
+
+// filesConfig returns the [files] settings, e.g. for the file type registry
+func (s *ConfigService) filesConfig() models.FilesConfig {
+	return s.current().Files
+}
+
+// GetIgnorePatterns returns the configured gitignore-style patterns of hidden vault paths
+func (s *ConfigService) GetIgnorePatterns() []string {
+	return s.current().Files.Ignore
+}
+
+// GetTrashRetentionDays returns how many days trashed items are kept (0 keeps them forever)
+func (s *ConfigService) GetTrashRetentionDays() int {
+	return max(s.current().Trash.RetentionDays, 0)
+}
+
+// GetHistory returns the note history settings
+func (s *ConfigService) GetHistory() models.HistoryConfig {
+	history := s.current().History
+	history.IntervalMinutes = max(history.IntervalMinutes, 0)
+	history.RetentionDays = max(history.RetentionDays, 0)
+	return history
+}
+
+// GetGit returns the git settings with defaults filled in
+func (s *ConfigService) GetGit() models.GitConfig {
+	git := s.current().Git
+	switch git.AutoCommit {
+	case models.GitAutoCommitInterval, models.GitAutoCommitSave:
+	default:
+		git.AutoCommit = models.GitAutoCommitOff
+	}
+	if git.IntervalMinutes <= 0 {
+		git.IntervalMinutes = 10
+	}
+	if git.MessageTemplate == "" {
+		git.MessageTemplate = "vault backup: {{.Date}} {{.Time}}"
+	}
+	if git.Remote == "" {
+		git.Remote = "origin"
+	}
+	return git
 }
 
 // GetConfigPath returns the configuration file path
@@ -164,3 +330,99 @@ func (s *ConfigService) SelectVaultFolder() (string, error) {
 
 	return path, nil
 }
+
+// vaultConfigFile holds settings of a vault that override the global configuration
+const vaultConfigFile = ".obails/config.toml"
+
+// openVault makes path the open vault, records it as opened now, applies its
+// overrides and saves. The new configuration is built aside first, so a broken
+// vault config or a failed save leaves the open vault as it was.
+func (s *ConfigService) openVault(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	global := *s.global()
+	global.Vault.Path = path
+	global.Vaults = rememberVault(slices.Clone(global.Vaults), path, time.Now())
+
+	merged, err := vaultOverrides(&global)
+	if err != nil {
+		return err
+	}
+	if err := s.write(&global); err != nil {
+		return err
+	}
+	s.use(&global, merged)
+	return nil
+}
+
+// knownVaults returns a copy of the known vaults
+func (s *ConfigService) knownVaults() []models.KnownVault {
+	return slices.Clone(s.current().Vaults)
+}
+
+// updateVaults changes the known vaults of the global and the effective
+// configuration and saves
+func (s *ConfigService) updateVaults(update func(vaults []models.KnownVault) []models.KnownVault) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	global := *s.global()
+	global.Vaults = update(slices.Clone(global.Vaults))
+	if err := s.write(&global); err != nil {
+		return err
+	}
+
+	var merged *models.Config
+	if s.base != nil {
+		config := *s.config
+		merged = &config
+	}
+	s.use(&global, merged)
+	return nil
+}
+
+// rememberVault adds a vault to the known vaults or updates its last opened time.
+// A zero time only adds it.
+func rememberVault(vaults []models.KnownVault, path string, opened time.Time) []models.KnownVault {
+	if path == "" {
+		return vaults
+	}
+	path = filepath.Clean(path)
+
+	for i := range vaults {
+		if vaults[i].Path == path {
+			if !opened.IsZero() {
+				vaults[i].LastOpened = opened
+			}
+			return vaults
+		}
+	}
+	return append(vaults, models.KnownVault{Name: filepath.Base(path), Path: path, LastOpened: opened})
+}
+
+// vaultOverrides returns the global configuration with the settings of its
+// vault's .obails/config.toml on top, or nil if the vault overrides nothing
+func vaultOverrides(global *models.Config) (*models.Config, error) {
+	overridePath, err := resolveInVault(global.Vault.Path, vaultConfigFile)
+	if err != nil {
+		return nil, nil // No vault, nothing to override
+	}
+	if _, err := os.Stat(overridePath); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	// Decode a copy of the global configuration, so maps and slices aren't shared
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(global); err != nil {
+		return nil, err
+	}
+	merged := &models.Config{}
+	if _, err := toml.Decode(buf.String(), merged); err != nil {
+		return nil, err
+	}
+	if _, err := toml.DecodeFile(overridePath, merged); err != nil {
+		return nil, fmt.Errorf("vault config %s: %w", overridePath, err)
+	}
+	return merged, nil
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kazuph/obails/models"
+)
+
+// Extensions for sniffed content types of pasted data
+var sniffedExtensions = map[string]string{
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"image/bmp":       ".bmp",
+	"application/pdf": ".pdf",
+}
+
+// Characters that break wikilinks in attachment names
+var attachmentNameReplacer = strings.NewReplacer("[", "-", "]", "-", "#", "-", "^", "-", "|", "-", ":", "-", "\\", "-")
+
+// WriteBinaryFile writes base64 encoded content to a file
+func (s *FileService) WriteBinaryFile(relativePath string, data string) error {
+	content, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return fmt.Errorf("invalid base64 data: %w", err)
+	}
+
+	fullPath, err := s.getEntryPath(relativePath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return err
+	}
+	return atomicWriteFile(fullPath, content, 0644)
+}
+
+// ImportAttachment stores pasted data or a copy of a dropped file in the attachments
+// folder for a note, under a name that doesn't collide with existing files, and
+// returns the link to insert into the note
+func (s *FileService) ImportAttachment(req models.AttachmentImport) (*models.Attachment, error) {
+	content, name, err := attachmentContent(req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	folder, err := s.attachmentFolder(req.NotePath)
+	if err != nil {
+		return nil, err
+	}
+	relPath, err := s.availablePath(filepath.Join(folder, name))
+	if err != nil {
+		return nil, err
+	}
+
+	fullPath, err := s.getEntryPath(relPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return nil, err
+	}
+	if err := atomicWriteFile(fullPath, content, 0644); err != nil {
+		return nil, err
+	}
+
+	return &models.Attachment{
+		Path: relPath,
+		Name: filepath.Base(relPath),
+		Size: int64(len(content)),
+		Link: s.attachmentLink(req.NotePath, relPath),
+	}, nil
+}
+
+// attachmentContent returns the bytes and file name of an attachment to import
+func attachmentContent(req models.AttachmentImport, now time.Time) ([]byte, string, error) {
+	var content []byte
+	name := req.FileName
+
+	switch {
+	case req.Data != "":
+		data, err := base64.StdEncoding.DecodeString(req.Data)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid base64 data: %w", err)
+		}
+		content = data
+	case req.SourcePath != "":
+		if !filepath.IsAbs(req.SourcePath) {
+			return nil, "", fmt.Errorf("source path %q is not absolute", req.SourcePath)
+		}
+		data, err := os.ReadFile(req.SourcePath)
+		if err != nil {
+			return nil, "", err
+		}
+		content = data
+		if name == "" {
+			name = filepath.Base(req.SourcePath)
+		}
+	default:
+		return nil, "", fmt.Errorf("attachment has no data or source path")
+	}
+
+	if name == "" {
+		name = "Pasted image " + now.Format("20060102150405")
+	}
+	name = attachmentNameReplacer.Replace(filepath.Base(filepath.FromSlash(name)))
+	if filepath.Ext(name) == "" {
+		name += sniffedExtension(content)
+	}
+	return content, name, nil
+}
+
+// attachmentFolder returns the vault-relative folder for attachments of a note
+func (s *FileService) attachmentFolder(notePath string) (string, error) {
+	noteDir := "."
+	if notePath != "" {
+		rel, err := cleanRelativePath(notePath)
+		if err != nil {
+			return "", err
+		}
+		noteDir = filepath.Dir(rel)
+	}
+
+	cfg := s.configService.GetAttachments()
+
+	// The most specific folder override wins
+	folder, matched := "", ""
+	for parent, target := range cfg.Folders {
+		parent = filepath.Clean(filepath.FromSlash(parent))
+		if isSubPath(noteDir, parent) && len(parent) > len(matched) {
+			folder, matched = target, parent
+		}
+	}
+
+	if matched == "" {
+		switch cfg.Location {
+		case models.AttachmentLocationSubfolder:
+			folder = filepath.Join(noteDir, cfg.Folder)
+		case models.AttachmentLocationNote:
+			folder = noteDir
+		default:
+			folder = cfg.Folder
+		}
+	}
+
+	return cleanRelativePath(folder)
+}
+
+// attachmentLink returns the link text for an attachment inserted into a note.
+// Wikilinks use the bare file name unless another file in the vault has the same
+// name; markdown links are relative to the note.
+func (s *FileService) attachmentLink(notePath string, relPath string) string {
+	embed := ""
+	switch s.types.fileType(relPath) {
+	case models.FileTypeImage, models.FileTypePDF, models.FileTypeAudio, models.FileTypeVideo:
+		embed = "!"
+	}
+
+	name := filepath.Base(relPath)
+	if s.configService.GetAttachments().LinkStyle == models.LinkStyleMarkdown {
+		target := relPath
+		if notePath != "" {
+			if rel, err := filepath.Rel(filepath.Dir(filepath.Clean(notePath)), relPath); err == nil {
+				target = rel
+			}
+		}
+		segments := strings.Split(filepath.ToSlash(target), "/")
+		for i, segment := range segments {
+			segments[i] = url.PathEscape(segment)
+		}
+		return fmt.Sprintf("%s[%s](%s)", embed, strings.TrimSuffix(name, filepath.Ext(name)), strings.Join(segments, "/"))
+	}
+
+	target := name
+	if s.countFilesNamed(name) > 1 {
+		target = filepath.ToSlash(relPath)
+	}
+	return embed + "[[" + target + "]]"
+}
+
+// countFilesNamed counts the files with the given name outside hidden folders
+func (s *FileService) countFilesNamed(name string) int {
+	vaultPath, err := s.getFullPath("")
+	if err != nil {
+		return 0
+	}
+
+	count := 0
+	filepath.WalkDir(vaultPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && path != vaultPath && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && d.Name() == name {
+			count++
+		}
+		return nil
+	})
+	return count
+}
+
+// sniffedExtension guesses a file extension from content
+func sniffedExtension(content []byte) string {
+	contentType, _, _ := strings.Cut(http.DetectContentType(content), ";")
+	if ext, ok := sniffedExtensions[contentType]; ok {
+		return ext
+	}
+	return ".bin"
+}
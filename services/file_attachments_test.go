@@ -0,0 +1,126 @@
+package services
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kazuph/obails/models"
+)
+
+// Smallest PNG signature http.DetectContentType recognizes
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestFileService_WriteBinaryFile(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs := NewFileService(cs)
+
+	if err := fs.WriteBinaryFile("images/a.png", base64.StdEncoding.EncodeToString(testPNG)); err != nil {
+		t.Fatalf("WriteBinaryFile failed: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(tmpDir, "images", "a.png"))
+	if string(data) != string(testPNG) {
+		t.Error("Content mismatch")
+	}
+
+	if err := fs.WriteBinaryFile("b.png", "not base64!"); err == nil {
+		t.Error("Should fail for invalid base64")
+	}
+	if err := fs.WriteBinaryFile("../outside.png", ""); err == nil {
+		t.Error("Should reject paths outside the vault")
+	}
+}
+
+func TestFileService_ImportAttachment(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs := NewFileService(cs)
+	data := base64.StdEncoding.EncodeToString(testPNG)
+
+	t.Run("pasted image in the attachments folder", func(t *testing.T) {
+		cs.config.Attachments = models.AttachmentConfig{Folder: "attachments"}
+
+		first, err := fs.ImportAttachment(models.AttachmentImport{NotePath: "notes/a.md", Data: data})
+		if err != nil {
+			t.Fatalf("ImportAttachment failed: %v", err)
+		}
+		if !strings.HasPrefix(first.Name, "Pasted image ") || !strings.HasSuffix(first.Name, ".png") {
+			t.Errorf("Unexpected name %q", first.Name)
+		}
+		if first.Path != filepath.Join("attachments", first.Name) || first.Link != "![["+first.Name+"]]" {
+			t.Errorf("Unexpected attachment: %+v", first)
+		}
+
+		second, _ := fs.ImportAttachment(models.AttachmentImport{NotePath: "notes/a.md", Data: data, FileName: first.Name})
+		if second.Name != strings.TrimSuffix(first.Name, ".png")+" 1.png" {
+			t.Errorf("Expected a collision-free name, got %q", second.Name)
+		}
+	})
+
+	t.Run("subfolder next to the note with markdown links", func(t *testing.T) {
+		cs.config.Attachments = models.AttachmentConfig{Location: models.AttachmentLocationSubfolder, LinkStyle: models.LinkStyleMarkdown}
+
+		attachment, err := fs.ImportAttachment(models.AttachmentImport{NotePath: "notes/b.md", Data: data, FileName: "my shot.png"})
+		if err != nil {
+			t.Fatalf("ImportAttachment failed: %v", err)
+		}
+		if attachment.Path != filepath.Join("notes", "attachments", "my shot.png") || attachment.Link != "![my shot](attachments/my%20shot.png)" {
+			t.Errorf("Unexpected attachment: %+v", attachment)
+		}
+	})
+
+	t.Run("next to the note from a source file", func(t *testing.T) {
+		cs.config.Attachments = models.AttachmentConfig{Location: models.AttachmentLocationNote}
+
+		source := filepath.Join(t.TempDir(), "report.pdf")
+		os.WriteFile(source, []byte("%PDF-1.4"), 0644)
+
+		attachment, err := fs.ImportAttachment(models.AttachmentImport{NotePath: "projects/c.md", SourcePath: source})
+		if err != nil {
+			t.Fatalf("ImportAttachment failed: %v", err)
+		}
+		if attachment.Path != filepath.Join("projects", "report.pdf") || attachment.Link != "![[report.pdf]]" || attachment.Size != 8 {
+			t.Errorf("Unexpected attachment: %+v", attachment)
+		}
+
+		// A second file with the same name elsewhere makes the wikilink use the path
+		cs.config.Attachments = models.AttachmentConfig{Folders: map[string]string{"projects": "projects/files"}}
+		attachment, _ = fs.ImportAttachment(models.AttachmentImport{NotePath: "projects/c.md", SourcePath: source})
+		if attachment.Path != filepath.Join("projects", "files", "report.pdf") || attachment.Link != "![[projects/files/report.pdf]]" {
+			t.Errorf("Unexpected attachment: %+v", attachment)
+		}
+	})
+
+	t.Run("invalid imports", func(t *testing.T) {
+		if _, err := fs.ImportAttachment(models.AttachmentImport{NotePath: "a.md"}); err == nil {
+			t.Error("Should fail without data")
+		}
+		if _, err := fs.ImportAttachment(models.AttachmentImport{SourcePath: "relative.png"}); err == nil {
+			t.Error("Should fail for a relative source path")
+		}
+		cs.config.Attachments = models.AttachmentConfig{Folder: "../outside"}
+		if _, err := fs.ImportAttachment(models.AttachmentImport{Data: data}); err == nil {
+			t.Error("Should reject an attachments folder outside the vault")
+		}
+	})
+}
+
+func TestAttachmentContent_Name(t *testing.T) {
+	now := time.Date(2026, 10, 16, 9, 30, 0, 0, time.Local)
+
+	_, name, _ := attachmentContent(models.AttachmentImport{Data: base64.StdEncoding.EncodeToString(testPNG)}, now)
+	if name != "Pasted image 20261016093000.png" {
+		t.Errorf("Unexpected pasted name %q", name)
+	}
+
+	_, name, _ = attachmentContent(models.AttachmentImport{Data: "AAAA", FileName: "../a[1]|b"}, now)
+	if name != "a-1--b.bin" {
+		t.Errorf("Unexpected sanitized name %q", name)
+	}
+}
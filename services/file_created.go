@@ -0,0 +1,14 @@
+//go:build !darwin && !windows
+
+package services
+
+import (
+	"os"
+	"time"
+)
+
+// fileCreatedAt returns when a file was created. Creation times aren't available
+// here, so the modification time stands in.
+func fileCreatedAt(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
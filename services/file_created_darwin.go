@@ -0,0 +1,15 @@
+package services
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileCreatedAt returns when a file was created
+func fileCreatedAt(info os.FileInfo) time.Time {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(stat.Birthtimespec.Unix())
+	}
+	return info.ModTime()
+}
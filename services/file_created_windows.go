@@ -0,0 +1,15 @@
+package services
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileCreatedAt returns when a file was created
+func fileCreatedAt(info os.FileInfo) time.Time {
+	if data, ok := info.Sys().(*syscall.Win32FileAttributeData); ok {
+		return time.Unix(0, data.CreationTime.Nanoseconds())
+	}
+	return info.ModTime()
+}
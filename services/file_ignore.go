@@ -0,0 +1,97 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// ignoreFileName is a file in the vault root with gitignore-style patterns of paths
+// to hide from the file tree, search and the link and task indexes
+const ignoreFileName = ".obailsignore"
+
+// ignoreRules matches vault-relative paths against the configured ignore patterns.
+// A nil *ignoreRules ignores nothing.
+type ignoreRules struct {
+	matcher gitignore.Matcher
+}
+
+// ignoreCache keeps the parsed ignore rules until the configured patterns or the
+// .obailsignore file change
+type ignoreCache struct {
+	mu    sync.Mutex
+	key   ignoreCacheKey
+	rules *ignoreRules
+	valid bool
+}
+
+// ignoreCacheKey identifies the sources the cached rules were parsed from
+type ignoreCacheKey struct {
+	path     string // .obailsignore of the open vault
+	modTime  time.Time
+	size     int64
+	patterns string // Configured patterns, one per line
+}
+
+// ignoreRules returns the patterns from the config and the vault's .obailsignore.
+// Patterns in the file take precedence, so it can re-include configured paths.
+func (s *FileService) ignoreRules() *ignoreRules {
+	configured := s.configService.GetIgnorePatterns()
+	key := ignoreCacheKey{patterns: strings.Join(configured, "\n")}
+	if fullPath, err := s.getFullPath(ignoreFileName); err == nil {
+		key.path = fullPath
+		if info, err := os.Stat(fullPath); err == nil {
+			key.modTime, key.size = info.ModTime(), info.Size()
+		}
+	}
+
+	s.ignore.mu.Lock()
+	defer s.ignore.mu.Unlock()
+	if s.ignore.valid && s.ignore.key == key {
+		return s.ignore.rules
+	}
+
+	patterns := parseIgnorePatterns(configured)
+	if key.path != "" {
+		if data, err := os.ReadFile(key.path); err == nil {
+			patterns = append(patterns, parseIgnorePatterns(strings.Split(string(data), "\n"))...)
+		}
+	}
+
+	var rules *ignoreRules
+	if len(patterns) > 0 {
+		rules = &ignoreRules{matcher: gitignore.NewMatcher(patterns)}
+	}
+	s.ignore.key, s.ignore.rules, s.ignore.valid = key, rules, true
+	return rules
+}
+
+// ignored reports whether a vault-relative path matches the ignore patterns. Paths
+// inside an ignored folder are ignored too.
+func (r *ignoreRules) ignored(relativePath string, isDir bool) bool {
+	if r == nil {
+		return false
+	}
+	rel := filepath.ToSlash(filepath.Clean(relativePath))
+	if rel == "." || rel == "" {
+		return false
+	}
+	return r.matcher.Match(strings.Split(rel, "/"), isDir)
+}
+
+// parseIgnorePatterns parses gitignore lines, skipping blank lines and comments
+func parseIgnorePatterns(lines []string) []gitignore.Pattern {
+	var patterns []gitignore.Pattern
+	for _, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, gitignore.ParsePattern(line, nil))
+	}
+	return patterns
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kazuph/obails/models"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs := NewFileService(cs)
+	if fs.ignoreRules() != nil {
+		t.Error("Expected no rules without patterns")
+	}
+
+	cs.config.Files.Ignore = []string{"node_modules/", "*.log", "/build"}
+	os.WriteFile(filepath.Join(tmpDir, ignoreFileName), []byte("# archives\narchive/\n\n!keep.log\n"), 0644)
+	rules := fs.ignoreRules()
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"node_modules", true, true},
+		{"app/node_modules/pkg/readme.md", false, true},
+		{"node_modules", false, false},
+		{"debug.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"docs/build", true, false},
+		{"archive/2020/old.md", false, true},
+		{"notes/archive.md", false, false},
+		{".", true, false},
+	}
+	for _, tt := range tests {
+		if got := rules.ignored(tt.path, tt.isDir); got != tt.ignored {
+			t.Errorf("ignored(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.ignored)
+		}
+	}
+
+	t.Run("cached until the sources change", func(t *testing.T) {
+		if fs.ignoreRules() != rules {
+			t.Error("Expected the cached rules while nothing changed")
+		}
+
+		ignorePath := filepath.Join(tmpDir, ignoreFileName)
+		os.WriteFile(ignorePath, []byte("drafts/\n"), 0644)
+		later := time.Now().Add(time.Minute)
+		os.Chtimes(ignorePath, later, later)
+		if rules := fs.ignoreRules(); !rules.ignored("drafts", true) || rules.ignored("archive", true) {
+			t.Error("Expected the rules of the changed .obailsignore")
+		}
+
+		cs.config.Files.Ignore = nil
+		if rules := fs.ignoreRules(); rules.ignored("debug.log", false) {
+			t.Error("Expected the rules of the changed config")
+		}
+	})
+}
+
+func TestIgnoredPaths(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		"Note.md":                      "[[Hidden]] [[Vendored]]",
+		"archive/Hidden.md":            "[[Note]]",
+		"node_modules/pkg/Vendored.md": "- [ ] vendored task",
+		"tasks.md":                     "- [ ] real task",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(tmpDir, path)
+		os.MkdirAll(filepath.Dir(fullPath), 0755)
+		os.WriteFile(fullPath, []byte(content), 0644)
+	}
+	cs.config.Files.Ignore = []string{"node_modules"}
+	os.WriteFile(filepath.Join(tmpDir, ignoreFileName), []byte("archive/\n"), 0644)
+
+	fs := NewFileService(cs)
+
+	t.Run("tree", func(t *testing.T) {
+		tree, err := fs.ListDirectoryTree()
+		if err != nil {
+			t.Fatalf("ListDirectoryTree failed: %v", err)
+		}
+		for _, entry := range tree {
+			if entry.Name == "archive" || entry.Name == "node_modules" {
+				t.Errorf("Ignored folder %s listed", entry.Name)
+			}
+		}
+		if len(tree) != 2 {
+			t.Errorf("Expected 2 entries, got %d", len(tree))
+		}
+	})
+
+	t.Run("search", func(t *testing.T) {
+		results, err := fs.SearchFiles("e")
+		if err != nil {
+			t.Fatalf("SearchFiles failed: %v", err)
+		}
+		if len(results) != 1 || results[0].Path != "Note.md" {
+			t.Errorf("Expected only Note.md: %+v", results)
+		}
+	})
+
+	t.Run("links", func(t *testing.T) {
+		ls := NewLinkService(fs, cs)
+		if err := ls.RebuildIndex(); err != nil {
+			t.Fatalf("RebuildIndex failed: %v", err)
+		}
+		if backlinks := ls.GetBacklinks("Note.md"); len(backlinks) != 0 {
+			t.Errorf("Ignored notes should not be indexed: %+v", backlinks)
+		}
+		if _, ok := ls.ResolveLink("Hidden"); ok {
+			t.Error("Ignored notes should not resolve")
+		}
+		if _, ok := ls.ResolveLink("archive/Hidden"); ok {
+			t.Error("Ignored notes should not resolve by path")
+		}
+		if path, ok := ls.ResolveLink("tasks"); !ok || path != "tasks.md" {
+			t.Errorf("Expected tasks.md, got %q", path)
+		}
+	})
+
+	t.Run("tasks", func(t *testing.T) {
+		ts := NewTaskService(NewNoteService(fs, cs), fs, cs)
+		if err := ts.RebuildIndex(); err != nil {
+			t.Fatalf("RebuildIndex failed: %v", err)
+		}
+		tasks := ts.QueryTasks(models.TaskQuery{})
+		if len(tasks) != 1 || tasks[0].Path != "tasks.md" {
+			t.Errorf("Unexpected tasks: %+v", tasks)
+		}
+	})
+}
@@ -2,53 +2,45 @@ package services
 
 import (
 	"encoding/base64"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"sort"
 	"strings"
 
 	"github.com/kazuph/obails/models"
 )
 
-// Image file extensions
-var imageExtensions = map[string]bool{
-	".png": true, ".jpg": true, ".jpeg": true, ".gif": true,
-	".webp": true, ".svg": true, ".bmp": true, ".ico": true,
-}
-
-// GetFileType determines the file type based on extension
-func GetFileType(filename string) string {
-	ext := strings.ToLower(filepath.Ext(filename))
-	switch {
-	case ext == ".md":
-		return models.FileTypeMarkdown
-	case ext == ".pdf":
-		return models.FileTypePDF
-	case ext == ".html" || ext == ".htm":
-		return models.FileTypeHTML
-	case imageExtensions[ext]:
-		return models.FileTypeImage
-	default:
-		return models.FileTypeOther
-	}
-}
-
 // FileService handles file system operations
 type FileService struct {
 	configService *ConfigService
+	types         *fileTypeRegistry
+	folderOrder   func(folder string) treeOrder
+	annotateTree  func(entries []models.FileInfo)
+	ignore        ignoreCache
+
+	// Called with the cleaned source and destination of every moved file or folder
+	moveHooks []func(from string, to string)
+	// Called with the cleaned path of every deleted or trashed file or folder
+	deleteHooks []func(relativePath string)
+	// Called with the cleaned original and new path of every restored file or folder
+	restoreHooks []func(original string, restored string)
 }
 
 // NewFileService creates a new FileService
 func NewFileService(configService *ConfigService) *FileService {
 	return &FileService{
 		configService: configService,
+		types:         newFileTypeRegistry(configService),
 	}
 }
 
 // ReadFile reads the content of a file
 func (s *FileService) ReadFile(relativePath string) (string, error) {
-	fullPath := s.getFullPath(relativePath)
+	fullPath, err := s.getFullPath(relativePath)
+	if err != nil {
+		return "", err
+	}
 	content, err := os.ReadFile(fullPath)
 	if err != nil {
 		return "", err
@@ -58,7 +50,10 @@ func (s *FileService) ReadFile(relativePath string) (string, error) {
 
 // WriteFile writes content to a file
 func (s *FileService) WriteFile(relativePath string, content string) error {
-	fullPath := s.getFullPath(relativePath)
+	fullPath, err := s.getEntryPath(relativePath)
+	if err != nil {
+		return err
+	}
 
 	// Ensure directory exists
 	dir := filepath.Dir(fullPath)
@@ -66,12 +61,15 @@ func (s *FileService) WriteFile(relativePath string, content string) error {
 		return err
 	}
 
-	return os.WriteFile(fullPath, []byte(content), 0644)
+	return atomicWriteFile(fullPath, []byte(content), 0644)
 }
 
 // CreateFile creates a new file with content (fails if file exists)
 func (s *FileService) CreateFile(relativePath string, content string) error {
-	fullPath := s.getFullPath(relativePath)
+	fullPath, err := s.getEntryPath(relativePath)
+	if err != nil {
+		return err
+	}
 
 	// Check if file already exists
 	if _, err := os.Stat(fullPath); err == nil {
@@ -84,31 +82,66 @@ func (s *FileService) CreateFile(relativePath string, content string) error {
 		return err
 	}
 
-	return os.WriteFile(fullPath, []byte(content), 0644)
+	return atomicWriteFile(fullPath, []byte(content), 0644)
 }
 
-// DeletePath deletes a file or directory (moves to trash on macOS)
+// DeletePath moves a file or directory to the vault trash. Paths already inside
+// the trash are deleted permanently.
 func (s *FileService) DeletePath(relativePath string) error {
-	fullPath := s.getFullPath(relativePath)
+	_, err := s.deletePath(relativePath)
+	return err
+}
+
+// deletePath deletes like DeletePath and returns the trash item, or nil if the
+// path was deleted permanently
+func (s *FileService) deletePath(relativePath string) (*models.TrashItem, error) {
+	fullPath, err := s.getEntryPath(relativePath)
+	if err != nil {
+		return nil, err
+	}
 
 	// Check if path exists
-	info, err := os.Stat(fullPath)
+	info, err := os.Lstat(fullPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// For safety, use trash command on macOS instead of permanent delete
-	// This requires 'trash' command to be installed (brew install trash)
-	if info.IsDir() {
-		return os.RemoveAll(fullPath)
+	var item *models.TrashItem
+	rel, _ := cleanRelativePath(relativePath)
+	if isSubPath(rel, trashFolder) {
+		if info.IsDir() {
+			err = os.RemoveAll(fullPath)
+		} else {
+			err = os.Remove(fullPath)
+		}
+	} else {
+		item, err = s.moveToTrash(relativePath, fullPath)
+	}
+	if err != nil {
+		return nil, err
 	}
-	return os.Remove(fullPath)
+
+	for _, hook := range s.deleteHooks {
+		hook(rel)
+	}
+	return item, nil
+}
+
+// onDelete registers a function called after each deletion of a file or folder
+func (s *FileService) onDelete(hook func(relativePath string)) {
+	s.deleteHooks = append(s.deleteHooks, hook)
 }
 
 // MoveFile moves a file from one location to another
 func (s *FileService) MoveFile(sourcePath string, destPath string) error {
-	sourceFullPath := s.getFullPath(sourcePath)
-	destFullPath := s.getFullPath(destPath)
+	sourceFullPath, err := s.getEntryPath(sourcePath)
+	if err != nil {
+		return err
+	}
+	destFullPath, err := s.getEntryPath(destPath)
+	if err != nil {
+		return err
+	}
 
 	// Check if source exists
 	if _, err := os.Stat(sourceFullPath); err != nil {
@@ -126,22 +159,57 @@ func (s *FileService) MoveFile(sourcePath string, destPath string) error {
 		return err
 	}
 
-	return os.Rename(sourceFullPath, destFullPath)
+	if err := os.Rename(sourceFullPath, destFullPath); err != nil {
+		return err
+	}
+	s.notifyMoved(sourcePath, destPath)
+	return nil
+}
+
+// onRestore registers a function called after each restore of a file or folder from
+// the trash, with its original path and the path it was restored to
+func (s *FileService) onRestore(hook func(original string, restored string)) {
+	s.restoreHooks = append(s.restoreHooks, hook)
+}
+
+// onMove registers a function called after each move of a file or folder
+func (s *FileService) onMove(hook func(from string, to string)) {
+	s.moveHooks = append(s.moveHooks, hook)
+}
+
+func (s *FileService) notifyMoved(sourcePath string, destPath string) {
+	from, _ := cleanRelativePath(sourcePath)
+	to, _ := cleanRelativePath(destPath)
+	for _, hook := range s.moveHooks {
+		hook(from, to)
+	}
 }
 
 // ListDirectory lists files and directories
 func (s *FileService) ListDirectory(relativePath string) ([]models.FileInfo, error) {
-	fullPath := s.getFullPath(relativePath)
-	return s.listDirectoryRecursive(fullPath, relativePath, 1)
+	fullPath, err := s.getFullPath(relativePath)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.listDirectoryRecursive(fullPath, relativePath, 1, s.ignoreRules())
+	if err != nil {
+		return nil, err
+	}
+	s.annotateTreeEntries(entries)
+	return entries, nil
 }
 
-// ListDirectoryTree lists the entire directory tree
+// ListDirectoryTree lists the top level of the vault for the lazily loaded file tree.
+// Folders come with the number of their entries; ListTreeChildren lists them.
 func (s *FileService) ListDirectoryTree() ([]models.FileInfo, error) {
-	vaultPath := s.configService.GetVaultPath()
-	return s.listDirectoryRecursive(vaultPath, "", 3) // 3 levels deep
+	page, err := s.listTreePage("", "", 0)
+	if err != nil {
+		return nil, err
+	}
+	return page.Entries, nil
 }
 
-func (s *FileService) listDirectoryRecursive(fullPath string, relativePath string, maxDepth int) ([]models.FileInfo, error) {
+func (s *FileService) listDirectoryRecursive(fullPath string, relativePath string, maxDepth int, ignore *ignoreRules) ([]models.FileInfo, error) {
 	if maxDepth <= 0 {
 		return nil, nil
 	}
@@ -158,21 +226,27 @@ func (s *FileService) listDirectoryRecursive(fullPath string, relativePath strin
 			continue
 		}
 
+		entryRelPath := filepath.Join(relativePath, entry.Name())
+		if ignore.ignored(entryRelPath, entry.IsDir()) {
+			continue
+		}
+
 		info, err := entry.Info()
 		if err != nil {
 			continue
 		}
 
-		entryRelPath := filepath.Join(relativePath, entry.Name())
 		fileInfo := models.FileInfo{
 			Name:       entry.Name(),
 			Path:       entryRelPath,
 			IsDir:      entry.IsDir(),
-			FileType:   GetFileType(entry.Name()),
+			CreatedAt:  fileCreatedAt(info),
 			ModifiedAt: info.ModTime(),
 		}
-		if entry.IsDir() {
-			fileInfo.FileType = "" // Directories don't have a file type
+		// Directories don't have a file type or size
+		if !entry.IsDir() {
+			fileInfo.FileType, _ = s.types.detect(filepath.Join(fullPath, entry.Name()))
+			fileInfo.Size = info.Size()
 		}
 
 		if entry.IsDir() && maxDepth > 1 {
@@ -180,6 +254,7 @@ func (s *FileService) listDirectoryRecursive(fullPath string, relativePath strin
 				filepath.Join(fullPath, entry.Name()),
 				entryRelPath,
 				maxDepth-1,
+				ignore,
 			)
 			if err == nil {
 				fileInfo.Children = children
@@ -189,82 +264,130 @@ func (s *FileService) listDirectoryRecursive(fullPath string, relativePath strin
 		result = append(result, fileInfo)
 	}
 
-	// Sort: folders first (ascending by name), then files (descending by ModifiedAt)
-	sort.Slice(result, func(i, j int) bool {
-		// Folders before files
-		if result[i].IsDir != result[j].IsDir {
-			return result[i].IsDir
-		}
-		// Folders: ascending by name
-		if result[i].IsDir {
-			return result[i].Name < result[j].Name
-		}
-		// Files: descending by ModifiedAt (newest first)
-		return result[i].ModifiedAt.After(result[j].ModifiedAt)
-	})
-
+	s.sortTreeEntries(relativePath, result)
 	return result, nil
 }
 
+// fileInfoLess orders folders first (ascending by name), then files (descending by
+// ModifiedAt, then by name)
+func fileInfoLess(a models.FileInfo, b models.FileInfo) bool {
+	// Folders before files
+	if a.IsDir != b.IsDir {
+		return a.IsDir
+	}
+	// Folders: ascending by name
+	if a.IsDir || a.ModifiedAt.Equal(b.ModifiedAt) {
+		return a.Name < b.Name
+	}
+	// Files: descending by ModifiedAt (newest first)
+	return a.ModifiedAt.After(b.ModifiedAt)
+}
+
 // CreateDirectory creates a new directory
 func (s *FileService) CreateDirectory(relativePath string) error {
-	fullPath := s.getFullPath(relativePath)
+	fullPath, err := s.getFullPath(relativePath)
+	if err != nil {
+		return err
+	}
 	return os.MkdirAll(fullPath, 0755)
 }
 
-// DeleteFile deletes a file or empty directory
+// DeleteFile moves a file or empty directory to the vault trash
 func (s *FileService) DeleteFile(relativePath string) error {
-	fullPath := s.getFullPath(relativePath)
-	return os.Remove(fullPath)
+	fullPath, err := s.getEntryPath(relativePath)
+	if err != nil {
+		return err
+	}
+	if entries, err := os.ReadDir(fullPath); err == nil && len(entries) > 0 {
+		return fmt.Errorf("directory %s is not empty", relativePath)
+	}
+	return s.DeletePath(relativePath)
 }
 
 // FileExists checks if a file exists
 func (s *FileService) FileExists(relativePath string) bool {
-	fullPath := s.getFullPath(relativePath)
-	_, err := os.Stat(fullPath)
+	fullPath, err := s.getFullPath(relativePath)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(fullPath)
 	return err == nil
 }
 
+// findNote returns the path of an existing note named by a path that may leave
+// out the note extension, trying each note extension in turn
+func (s *FileService) findNote(name string) (string, bool) {
+	candidates := []string{name}
+	if !s.types.isNote(name) {
+		candidates = candidates[:0]
+		for _, ext := range s.types.noteExts() {
+			candidates = append(candidates, name+ext)
+		}
+	}
+
+	for _, candidate := range candidates {
+		fullPath, err := s.getFullPath(candidate)
+		if err != nil {
+			return "", false
+		}
+		if info, err := os.Stat(fullPath); err == nil && !info.IsDir() {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
 // GetFileInfo returns information about a file
 func (s *FileService) GetFileInfo(relativePath string) (*models.FileInfo, error) {
-	fullPath := s.getFullPath(relativePath)
+	fullPath, err := s.getFullPath(relativePath)
+	if err != nil {
+		return nil, err
+	}
 	info, err := os.Stat(fullPath)
 	if err != nil {
 		return nil, err
 	}
 
-	return &models.FileInfo{
+	fileInfo := &models.FileInfo{
 		Name:       filepath.Base(relativePath),
 		Path:       relativePath,
 		IsDir:      info.IsDir(),
 		ModifiedAt: info.ModTime(),
-	}, nil
+	}
+	if !info.IsDir() {
+		fileInfo.FileType, _ = s.types.detect(fullPath)
+	}
+	return fileInfo, nil
 }
 
 // SearchFiles searches for files matching a pattern
 func (s *FileService) SearchFiles(pattern string) ([]models.FileInfo, error) {
-	vaultPath := s.configService.GetVaultPath()
+	vaultPath, err := s.getFullPath("")
+	if err != nil {
+		return nil, err
+	}
 	var results []models.FileInfo
 
 	pattern = strings.ToLower(pattern)
+	ignore := s.ignoreRules()
 
-	err := filepath.Walk(vaultPath, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(vaultPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil // Skip errors
 		}
 
-		// Skip hidden files and directories
-		if strings.HasPrefix(info.Name(), ".") {
+		// Skip hidden and ignored files and directories
+		relPath, _ := filepath.Rel(vaultPath, path)
+		if strings.HasPrefix(info.Name(), ".") || ignore.ignored(relPath, info.IsDir()) {
 			if info.IsDir() {
 				return filepath.SkipDir
 			}
 			return nil
 		}
 
-		// Only match markdown files
-		if !info.IsDir() && strings.HasSuffix(info.Name(), ".md") {
+		// Only match notes
+		if !info.IsDir() && s.types.isNote(info.Name()) {
 			if strings.Contains(strings.ToLower(info.Name()), pattern) {
-				relPath, _ := filepath.Rel(vaultPath, path)
 				results = append(results, models.FileInfo{
 					Name:       info.Name(),
 					Path:       relPath,
@@ -280,18 +403,37 @@ func (s *FileService) SearchFiles(pattern string) ([]models.FileInfo, error) {
 	return results, err
 }
 
-func (s *FileService) getFullPath(relativePath string) string {
-	vaultPath := s.configService.GetVaultPath()
-	if relativePath == "" {
-		return vaultPath
+// getFullPath resolves a vault-relative path to an absolute path, rejecting paths
+// that escape the vault. An empty path refers to the vault root.
+func (s *FileService) getFullPath(relativePath string) (string, error) {
+	return resolveInVault(s.configService.GetVaultPath(), relativePath)
+}
+
+// getEntryPath is getFullPath for operations that must not target the vault root itself
+func (s *FileService) getEntryPath(relativePath string) (string, error) {
+	if rel, err := cleanRelativePath(relativePath); err == nil && rel == "." {
+		return "", &VaultPathError{Path: relativePath, Err: ErrVaultRoot}
 	}
-	return filepath.Join(vaultPath, relativePath)
+	return s.getFullPath(relativePath)
+}
+
+// isSubPath reports whether relativePath is parent or lies inside the parent folder
+func isSubPath(relativePath string, parent string) bool {
+	relativePath = filepath.Clean(relativePath)
+	parent = filepath.Clean(parent)
+	if parent == "." || relativePath == parent {
+		return true
+	}
+	return strings.HasPrefix(relativePath, parent+string(filepath.Separator))
 }
 
 // ReadBinaryFile reads a binary file and returns it as base64 encoded string
 // Used for images and PDFs that need to be displayed in the frontend
 func (s *FileService) ReadBinaryFile(relativePath string) (string, error) {
-	fullPath := s.getFullPath(relativePath)
+	fullPath, err := s.getFullPath(relativePath)
+	if err != nil {
+		return "", err
+	}
 	content, err := os.ReadFile(fullPath)
 	if err != nil {
 		return "", err
@@ -299,32 +441,13 @@ func (s *FileService) ReadBinaryFile(relativePath string) (string, error) {
 	return base64.StdEncoding.EncodeToString(content), nil
 }
 
-// GetMimeType returns the MIME type for a file based on extension
-func GetMimeType(filename string) string {
-	ext := strings.ToLower(filepath.Ext(filename))
-	mimeTypes := map[string]string{
-		".png":  "image/png",
-		".jpg":  "image/jpeg",
-		".jpeg": "image/jpeg",
-		".gif":  "image/gif",
-		".webp": "image/webp",
-		".svg":  "image/svg+xml",
-		".bmp":  "image/bmp",
-		".ico":  "image/x-icon",
-		".pdf":  "application/pdf",
-		".html": "text/html",
-		".htm":  "text/html",
-	}
-	if mime, ok := mimeTypes[ext]; ok {
-		return mime
-	}
-	return "application/octet-stream"
-}
-
 // OpenExternal opens a file with the system's default application
 // Uses macOS 'open' command
 func (s *FileService) OpenExternal(relativePath string) error {
-	fullPath := s.getFullPath(relativePath)
+	fullPath, err := s.getFullPath(relativePath)
+	if err != nil {
+		return err
+	}
 
 	// Verify the file exists
 	if _, err := os.Stat(fullPath); err != nil {
@@ -94,6 +94,12 @@ func TestFileService_DeletePath(t *testing.T) {
 		if fs.FileExists("to-delete.md") {
 			t.Error("File should be deleted")
 		}
+
+		// Verify it's in the trash
+		items, _ := fs.ListTrash()
+		if len(items) != 1 || items[0].OriginalPath != "to-delete.md" {
+			t.Errorf("File should be moved to trash, got %+v", items)
+		}
 	})
 
 	t.Run("delete directory with contents", func(t *testing.T) {
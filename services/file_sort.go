@@ -0,0 +1,143 @@
+package services
+
+import (
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/kazuph/obails/models"
+)
+
+// treeOrder is how the entries of one folder are ordered in the file tree
+type treeOrder struct {
+	sort   models.TreeSort
+	pinned []string // Entry paths, first pinned first
+	manual []string // Entry names for models.TreeSortManual
+}
+
+// orderTreeBy registers the function providing the order of each folder in the tree
+func (s *FileService) orderTreeBy(order func(folder string) treeOrder) {
+	s.folderOrder = order
+}
+
+// annotateTreeBy registers the function decorating listed tree entries, e.g. with
+// their git status
+func (s *FileService) annotateTreeBy(annotate func(entries []models.FileInfo)) {
+	s.annotateTree = annotate
+}
+
+// annotateTreeEntries decorates entries listed for the file tree
+func (s *FileService) annotateTreeEntries(entries []models.FileInfo) {
+	if s.annotateTree != nil {
+		s.annotateTree(entries)
+	}
+}
+
+// sortTreeEntries sorts the entries of a folder in tree order
+func (s *FileService) sortTreeEntries(relativePath string, entries []models.FileInfo) {
+	less, _ := s.treeEntryLess(relativePath)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return less(entries[i], entries[j])
+	})
+}
+
+// treeOrderLess compares entries with pinned entries first, then folders, then files
+func treeOrderLess(order treeOrder) func(a, b models.FileInfo) bool {
+	pinRank := func(entry models.FileInfo) int {
+		if i := slices.Index(order.pinned, filepath.ToSlash(entry.Path)); i >= 0 {
+			return i
+		}
+		return len(order.pinned)
+	}
+
+	var less func(a, b models.FileInfo) bool
+	switch order.sort.Mode {
+	case "":
+		less = func(a, b models.FileInfo) bool {
+			if a.IsDir {
+				return a.Name < b.Name
+			}
+			return a.ModifiedAt.After(b.ModifiedAt)
+		}
+	case models.TreeSortNatural:
+		less = func(a, b models.FileInfo) bool { return naturalLess(a.Name, b.Name) }
+	case models.TreeSortCreated:
+		less = func(a, b models.FileInfo) bool { return a.CreatedAt.Before(b.CreatedAt) }
+	case models.TreeSortModified:
+		less = func(a, b models.FileInfo) bool { return a.ModifiedAt.Before(b.ModifiedAt) }
+	case models.TreeSortSize:
+		less = func(a, b models.FileInfo) bool { return a.Size < b.Size }
+	case models.TreeSortManual:
+		rank := func(entry models.FileInfo) int {
+			if i := slices.Index(order.manual, entry.Name); i >= 0 {
+				return i
+			}
+			return len(order.manual)
+		}
+		less = func(a, b models.FileInfo) bool { return rank(a) < rank(b) }
+	default:
+		less = func(a, b models.FileInfo) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) }
+	}
+	if order.sort.Descending && order.sort.Mode != "" && order.sort.Mode != models.TreeSortManual {
+		ascending := less
+		less = func(a, b models.FileInfo) bool { return ascending(b, a) }
+	}
+
+	return func(a, b models.FileInfo) bool {
+		if rankA, rankB := pinRank(a), pinRank(b); rankA != rankB {
+			return rankA < rankB
+		}
+		if a.IsDir != b.IsDir {
+			return a.IsDir
+		}
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		// Equal keys, e.g. unlisted entries in manual order, fall back to names
+		return naturalLess(a.Name, b.Name)
+	}
+}
+
+// naturalLess compares names case-insensitively, with runs of digits compared by
+// numeric value, so "Note 2" sorts before "Note 10"
+func naturalLess(a string, b string) bool {
+	ra, rb := []rune(strings.ToLower(a)), []rune(strings.ToLower(b))
+	i, j := 0, 0
+	for i < len(ra) && j < len(rb) {
+		if isDigit(ra[i]) && isDigit(rb[j]) {
+			startA, startB := i, j
+			for i < len(ra) && isDigit(ra[i]) {
+				i++
+			}
+			for j < len(rb) && isDigit(rb[j]) {
+				j++
+			}
+			numA := strings.TrimLeft(string(ra[startA:i]), "0")
+			numB := strings.TrimLeft(string(rb[startB:j]), "0")
+			if len(numA) != len(numB) {
+				return len(numA) < len(numB)
+			}
+			if numA != numB {
+				return numA < numB
+			}
+			continue
+		}
+		if ra[i] != rb[j] {
+			return ra[i] < rb[j]
+		}
+		i++
+		j++
+	}
+	if len(ra)-i != len(rb)-j {
+		return len(ra)-i < len(rb)-j
+	}
+	return a < b
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
@@ -0,0 +1,23 @@
+package services
+
+import "testing"
+
+func TestNaturalLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		less bool
+	}{
+		{"Note 2", "Note 10", true},
+		{"Note 10", "Note 2", false},
+		{"note 2", "Note 3", true},
+		{"Chapter 007", "Chapter 8", true},
+		{"a", "ab", true},
+		{"v1.10", "v1.9", false},
+		{"same", "same", false},
+	}
+	for _, tt := range tests {
+		if got := naturalLess(tt.a, tt.b); got != tt.less {
+			t.Errorf("naturalLess(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.less)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kazuph/obails/models"
+)
+
+// trashFolder is the vault-local trash. Each deleted entry is stored as
+// .trash/<id>/<name> with its metadata in .trash/<id>.json.
+const trashFolder = ".trash"
+
+// moveToTrash moves a file or folder into the vault trash and records where it came from
+func (s *FileService) moveToTrash(relativePath string, fullPath string) (*models.TrashItem, error) {
+	info, err := os.Lstat(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := newTrashID()
+	if err != nil {
+		return nil, err
+	}
+	itemDir, err := s.getFullPath(filepath.Join(trashFolder, id))
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(itemDir, 0755); err != nil {
+		return nil, err
+	}
+
+	rel, _ := cleanRelativePath(relativePath)
+	item := &models.TrashItem{
+		ID:           id,
+		Name:         info.Name(),
+		OriginalPath: filepath.ToSlash(rel),
+		IsDir:        info.IsDir(),
+		Size:         pathSize(fullPath, info),
+		DeletedAt:    time.Now(),
+	}
+	data, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := atomicWriteFile(itemDir+".json", data, 0644); err != nil {
+		os.Remove(itemDir)
+		return nil, err
+	}
+
+	if err := os.Rename(fullPath, filepath.Join(itemDir, info.Name())); err != nil {
+		os.Remove(itemDir + ".json")
+		os.Remove(itemDir)
+		return nil, err
+	}
+	return item, nil
+}
+
+// ListTrash returns the items in the vault trash, most recently deleted first
+func (s *FileService) ListTrash() ([]models.TrashItem, error) {
+	trashPath, err := s.getFullPath(trashFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(trashPath)
+	if os.IsNotExist(err) {
+		return []models.TrashItem{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items := []models.TrashItem{}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		item, err := s.readTrashItem(id)
+		if err != nil {
+			continue // Metadata without its entry, or unreadable
+		}
+		items = append(items, *item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+	return items, nil
+}
+
+// RestoreFromTrash moves a trashed item back to its original location and returns the
+// restored vault-relative path. If the original path is taken, a numbered name is used.
+func (s *FileService) RestoreFromTrash(id string) (string, error) {
+	item, err := s.readTrashItem(id)
+	if err != nil {
+		return "", err
+	}
+
+	destPath, err := s.availablePath(filepath.FromSlash(item.OriginalPath))
+	if err != nil {
+		return "", err
+	}
+	destFullPath, err := s.getEntryPath(destPath)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(destFullPath), 0755); err != nil {
+		return "", err
+	}
+
+	itemDir, err := s.getFullPath(filepath.Join(trashFolder, id))
+	if err != nil {
+		return "", err
+	}
+	if err := os.Rename(filepath.Join(itemDir, item.Name), destFullPath); err != nil {
+		return "", err
+	}
+
+	os.Remove(itemDir + ".json")
+	os.RemoveAll(itemDir)
+
+	original, _ := cleanRelativePath(filepath.FromSlash(item.OriginalPath))
+	restored, _ := cleanRelativePath(destPath)
+	for _, hook := range s.restoreHooks {
+		hook(original, restored)
+	}
+	return filepath.ToSlash(destPath), nil
+}
+
+// DeleteFromTrash permanently deletes a single trashed item
+func (s *FileService) DeleteFromTrash(id string) error {
+	if _, err := s.readTrashItem(id); err != nil {
+		return err
+	}
+	return s.removeTrashItem(id)
+}
+
+// EmptyTrash permanently deletes everything in the vault trash
+func (s *FileService) EmptyTrash() error {
+	trashPath, err := s.getFullPath(trashFolder)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(trashPath)
+}
+
+// PurgeTrash permanently deletes trashed items older than the configured retention
+// and returns how many were removed
+func (s *FileService) PurgeTrash() (int, error) {
+	days := s.configService.GetTrashRetentionDays()
+	if days == 0 {
+		return 0, nil
+	}
+
+	items, err := s.ListTrash()
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -days)
+	purged := 0
+	for _, item := range items {
+		if item.DeletedAt.After(cutoff) {
+			continue
+		}
+		if err := s.removeTrashItem(item.ID); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// readTrashItem loads the metadata of a trashed item whose entry still exists
+func (s *FileService) readTrashItem(id string) (*models.TrashItem, error) {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return nil, fmt.Errorf("invalid trash item id %q", id)
+	}
+
+	itemDir, err := s.getFullPath(filepath.Join(trashFolder, id))
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(itemDir + ".json")
+	if err != nil {
+		return nil, err
+	}
+
+	var item models.TrashItem
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, err
+	}
+	if _, err := os.Lstat(filepath.Join(itemDir, item.Name)); err != nil {
+		return nil, err
+	}
+	item.ID = id
+	return &item, nil
+}
+
+func (s *FileService) removeTrashItem(id string) error {
+	itemDir, err := s.getFullPath(filepath.Join(trashFolder, id))
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(itemDir); err != nil {
+		return err
+	}
+	return os.Remove(itemDir + ".json")
+}
+
+// availablePath returns relativePath, or "name 1.ext", "name 2.ext"... if it is taken
+func (s *FileService) availablePath(relativePath string) (string, error) {
+	ext := filepath.Ext(relativePath)
+	stem := strings.TrimSuffix(relativePath, ext)
+	candidate := relativePath
+	for n := 1; ; n++ {
+		fullPath, err := s.getEntryPath(candidate)
+		if err != nil {
+			return "", err
+		}
+		if _, err := os.Lstat(fullPath); os.IsNotExist(err) {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s %d%s", stem, n, ext)
+	}
+}
+
+// newTrashID returns a unique id that sorts by deletion time
+func newTrashID() (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return time.Now().UTC().Format("20060102T150405") + "-" + hex.EncodeToString(suffix), nil
+}
+
+// pathSize returns the size of a file, or the total size of the files in a folder
+func pathSize(fullPath string, info os.FileInfo) int64 {
+	if !info.IsDir() {
+		return info.Size()
+	}
+	var size int64
+	filepath.WalkDir(fullPath, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if fi, err := d.Info(); err == nil {
+				size += fi.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kazuph/obails/models"
+)
+
+func TestFileService_Trash(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs := NewFileService(cs)
+
+	fs.CreateFile("notes/keep.md", "keep me")
+	fs.CreateFile("folder/a.md", "a")
+	fs.CreateFile("folder/b.md", "bb")
+
+	if err := fs.DeletePath("notes/keep.md"); err != nil {
+		t.Fatalf("DeletePath failed: %v", err)
+	}
+	if err := fs.DeletePath("folder"); err != nil {
+		t.Fatalf("DeletePath failed: %v", err)
+	}
+
+	items, err := fs.ListTrash()
+	if err != nil {
+		t.Fatalf("ListTrash failed: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 trash items, got %+v", items)
+	}
+
+	byPath := map[string]models.TrashItem{}
+	for _, item := range items {
+		byPath[item.OriginalPath] = item
+	}
+	if item := byPath["folder"]; !item.IsDir || item.Size != 3 {
+		t.Errorf("Unexpected folder item: %+v", item)
+	}
+
+	t.Run("restore to original location", func(t *testing.T) {
+		path, err := fs.RestoreFromTrash(byPath["notes/keep.md"].ID)
+		if err != nil {
+			t.Fatalf("RestoreFromTrash failed: %v", err)
+		}
+		content, _ := fs.ReadFile("notes/keep.md")
+		if path != "notes/keep.md" || content != "keep me" {
+			t.Errorf("Restored to %q with %q", path, content)
+		}
+	})
+
+	t.Run("restore when the original path is taken", func(t *testing.T) {
+		fs.CreateFile("folder/new.md", "new")
+		path, err := fs.RestoreFromTrash(byPath["folder"].ID)
+		if err != nil {
+			t.Fatalf("RestoreFromTrash failed: %v", err)
+		}
+		if path != "folder 1" || !fs.FileExists("folder 1/b.md") || !fs.FileExists("folder/new.md") {
+			t.Errorf("Unexpected restore to %q", path)
+		}
+	})
+
+	t.Run("trash is emptied after restores", func(t *testing.T) {
+		items, _ := fs.ListTrash()
+		if len(items) != 0 {
+			t.Errorf("Expected empty trash, got %+v", items)
+		}
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		if _, err := fs.RestoreFromTrash("../notes"); err == nil {
+			t.Error("Should reject invalid id")
+		}
+	})
+
+	t.Run("empty trash", func(t *testing.T) {
+		fs.DeletePath("notes")
+		if err := fs.EmptyTrash(); err != nil {
+			t.Fatalf("EmptyTrash failed: %v", err)
+		}
+		if items, _ := fs.ListTrash(); len(items) != 0 {
+			t.Errorf("Expected empty trash, got %+v", items)
+		}
+	})
+}
+
+func TestFileService_PurgeTrash(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs := NewFileService(cs)
+
+	fs.CreateFile("old.md", "old")
+	fs.CreateFile("recent.md", "recent")
+	fs.DeletePath("old.md")
+	fs.DeletePath("recent.md")
+
+	// Backdate the first deletion
+	items, _ := fs.ListTrash()
+	for _, item := range items {
+		if item.OriginalPath == "old.md" {
+			item.DeletedAt = time.Now().AddDate(0, 0, -40)
+			data, _ := json.Marshal(item)
+			os.WriteFile(filepath.Join(tmpDir, trashFolder, item.ID+".json"), data, 0644)
+		}
+	}
+
+	t.Run("retention disabled", func(t *testing.T) {
+		purged, _ := fs.PurgeTrash()
+		if purged != 0 {
+			t.Errorf("Expected nothing purged, got %d", purged)
+		}
+	})
+
+	t.Run("retention 30 days", func(t *testing.T) {
+		cs.config.Trash.RetentionDays = 30
+		purged, err := fs.PurgeTrash()
+		if err != nil {
+			t.Fatalf("PurgeTrash failed: %v", err)
+		}
+		items, _ := fs.ListTrash()
+		if purged != 1 || len(items) != 1 || items[0].OriginalPath != "recent.md" {
+			t.Errorf("Unexpected trash after purge (%d): %+v", purged, items)
+		}
+	})
+}
+
+func TestFileService_TrashHooks(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs := NewFileService(cs)
+	var restored [][2]string
+	fs.onRestore(func(original string, to string) {
+		restored = append(restored, [2]string{original, to})
+	})
+
+	fs.CreateFile("notes/a.md", "a")
+	if err := fs.DeleteFile("notes"); err == nil {
+		t.Error("DeleteFile should fail for a folder that isn't empty")
+	}
+	if err := fs.DeleteFile("notes/a.md"); err != nil {
+		t.Fatalf("DeleteFile failed: %v", err)
+	}
+	items, _ := fs.ListTrash()
+	if len(items) != 1 || items[0].OriginalPath != "notes/a.md" {
+		t.Fatalf("Expected DeleteFile to use the trash, got %+v", items)
+	}
+
+	fs.CreateFile("notes/a.md", "new")
+	if _, err := fs.RestoreFromTrash(items[0].ID); err != nil {
+		t.Fatalf("RestoreFromTrash failed: %v", err)
+	}
+	want := [2]string{filepath.Join("notes", "a.md"), filepath.Join("notes", "a 1.md")}
+	if len(restored) != 1 || restored[0] != want {
+		t.Errorf("Expected restore hook with %v, got %v", want, restored)
+	}
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kazuph/obails/models"
+)
+
+// Page sizes of ListTreeChildren
+const (
+	defaultTreePageSize = 200
+	maxTreePageSize     = 1000
+)
+
+// ListTreeChildren returns a page of the entries of a folder for a lazily loaded file
+// tree, in tree order. Folders come with the number of their entries instead of
+// their children. Pass the NextCursor of a page to get the next one.
+func (s *FileService) ListTreeChildren(relativePath string, cursor string, limit int) (*models.TreePage, error) {
+	if limit <= 0 {
+		limit = defaultTreePageSize
+	}
+	return s.listTreePage(relativePath, cursor, min(limit, maxTreePageSize))
+}
+
+// listTreePage lists the entries of a folder after the cursor entry, at most limit
+// of them or all for 0. Only the entries of the page are stat'ed, unless the order
+// of the folder depends on times or sizes.
+func (s *FileService) listTreePage(relativePath string, cursor string, limit int) (*models.TreePage, error) {
+	fullPath, err := s.getFullPath(relativePath)
+	if err != nil {
+		return nil, err
+	}
+	dirEntries, err := os.ReadDir(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	ignore := s.ignoreRules()
+	entries := []models.FileInfo{}
+	for _, entry := range dirEntries {
+		entryRelPath := filepath.Join(relativePath, entry.Name())
+		if strings.HasPrefix(entry.Name(), ".") || ignore.ignored(entryRelPath, entry.IsDir()) {
+			continue
+		}
+		entries = append(entries, models.FileInfo{Name: entry.Name(), Path: entryRelPath, IsDir: entry.IsDir()})
+	}
+
+	less, byStat := s.treeEntryLess(relativePath)
+	if byStat {
+		for i := range entries {
+			statTreeEntry(fullPath, &entries[i])
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return less(entries[i], entries[j])
+	})
+
+	start := 0
+	if cursor != "" {
+		// The cursor has the entry's sort keys, so the page starts right after
+		// where it was even if it's gone or changed since
+		after, err := treeCursorEntry(relativePath, cursor)
+		if err != nil {
+			return nil, err
+		}
+		start = sort.Search(len(entries), func(i int) bool { return less(after, entries[i]) })
+	}
+	end := len(entries)
+	if limit > 0 {
+		end = min(start+limit, end)
+	}
+
+	page := &models.TreePage{
+		Path:    relativePath,
+		Entries: entries[start:end],
+		Total:   len(entries),
+	}
+	for i := range page.Entries {
+		entry := &page.Entries[i]
+		if !byStat {
+			statTreeEntry(fullPath, entry)
+		}
+		if entry.IsDir {
+			entry.ChildCount = countTreeEntries(filepath.Join(fullPath, entry.Name), entry.Path, ignore)
+		} else {
+			entry.FileType, _ = s.types.detect(filepath.Join(fullPath, entry.Name))
+		}
+	}
+	s.annotateTreeEntries(page.Entries)
+	if end < len(entries) {
+		page.NextCursor = treeCursor(entries[end-1])
+	}
+	return page, nil
+}
+
+// statTreeEntry fills in the times and, for files, the size of a tree entry
+func statTreeEntry(folderPath string, entry *models.FileInfo) {
+	info, err := os.Lstat(filepath.Join(folderPath, entry.Name))
+	if err != nil {
+		return // Listed without times, e.g. removed since reading the folder
+	}
+	entry.CreatedAt = fileCreatedAt(info)
+	entry.ModifiedAt = info.ModTime()
+	if !entry.IsDir {
+		entry.Size = info.Size()
+	}
+}
+
+// treeCursor returns the cursor of the page after an entry:
+// "<created>:<modified>:<size>:<name>", with the times in Unix nanoseconds (0 if
+// unknown) and a trailing slash on the name of folders
+func treeCursor(entry models.FileInfo) string {
+	name := entry.Name
+	if entry.IsDir {
+		name += "/"
+	}
+	return fmt.Sprintf("%d:%d:%d:%s", cursorTime(entry.CreatedAt), cursorTime(entry.ModifiedAt), entry.Size, name)
+}
+
+// treeCursorEntry parses a cursor back into the entry it names, with the sort
+// keys it had
+func treeCursorEntry(relativePath string, cursor string) (models.FileInfo, error) {
+	invalid := fmt.Errorf("invalid cursor %q", cursor)
+	fields := strings.SplitN(cursor, ":", 4)
+	if len(fields) != 4 {
+		return models.FileInfo{}, invalid
+	}
+	var keys [3]int64
+	for i := range keys {
+		n, err := strconv.ParseInt(fields[i], 10, 64)
+		if err != nil {
+			return models.FileInfo{}, invalid
+		}
+		keys[i] = n
+	}
+	name, isDir := strings.CutSuffix(fields[3], "/")
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return models.FileInfo{}, invalid
+	}
+	return models.FileInfo{
+		Name:       name,
+		Path:       filepath.Join(relativePath, name),
+		IsDir:      isDir,
+		CreatedAt:  cursorTimeValue(keys[0]),
+		ModifiedAt: cursorTimeValue(keys[1]),
+		Size:       keys[2],
+	}, nil
+}
+
+// cursorTime and cursorTimeValue convert times of cursors, with 0 for the zero time
+func cursorTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func cursorTimeValue(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+// treeEntryLess returns how the entries of a folder compare in tree order, and
+// whether the order depends on their times or sizes rather than their names
+func (s *FileService) treeEntryLess(relativePath string) (func(a, b models.FileInfo) bool, bool) {
+	if s.folderOrder == nil {
+		return fileInfoLess, true
+	}
+	order := s.folderOrder(relativePath)
+	switch order.sort.Mode {
+	case "", models.TreeSortCreated, models.TreeSortModified, models.TreeSortSize:
+		return treeOrderLess(order), true
+	}
+	return treeOrderLess(order), false
+}
+
+// ListAllFiles lists the files of the whole vault at any depth, newest first, e.g.
+// for quick-open. Hidden and ignored paths are skipped as in the tree.
+func (s *FileService) ListAllFiles() ([]models.FileInfo, error) {
+	vaultPath, err := s.getFullPath("")
+	if err != nil {
+		return nil, err
+	}
+
+	ignore := s.ignoreRules()
+	files := []models.FileInfo{}
+	err = filepath.WalkDir(vaultPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip errors
+		}
+		if path == vaultPath {
+			return nil
+		}
+
+		relPath, _ := filepath.Rel(vaultPath, path)
+		if strings.HasPrefix(d.Name(), ".") || ignore.ignored(relPath, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		fileType, _ := s.types.detect(path)
+		files = append(files, models.FileInfo{
+			Name:       d.Name(),
+			Path:       relPath,
+			FileType:   fileType,
+			ModifiedAt: info.ModTime(),
+		})
+		return nil
+	})
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].ModifiedAt.After(files[j].ModifiedAt)
+	})
+	return files, err
+}
+
+// countTreeEntries counts the entries of a folder shown in the tree
+func countTreeEntries(fullPath string, relativePath string, ignore *ignoreRules) int {
+	entries, err := os.ReadDir(fullPath)
+	if err != nil {
+		return 0
+	}
+
+	count := 0
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") || ignore.ignored(filepath.Join(relativePath, entry.Name()), entry.IsDir()) {
+			continue
+		}
+		count++
+	}
+	return count
+}
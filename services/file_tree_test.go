@@ -0,0 +1,174 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kazuph/obails/models"
+)
+
+func TestFileService_ListTreeChildren(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+
+	// Five notes, newest last, and two folders
+	for i := range 5 {
+		path := filepath.Join(tmpDir, fmt.Sprintf("note%d.md", i))
+		os.WriteFile(path, []byte("x"), 0644)
+		mtime := time.Now().Add(time.Duration(i-5) * time.Hour)
+		os.Chtimes(path, mtime, mtime)
+	}
+	os.MkdirAll(filepath.Join(tmpDir, "a", "deep", "er"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "a", "one.md"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "a", ".hidden"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "a", "skip.log"), []byte("x"), 0644)
+	os.MkdirAll(filepath.Join(tmpDir, "b"), 0755)
+	cs.config.Files.Ignore = []string{"*.log"}
+
+	fs := NewFileService(cs)
+
+	t.Run("pages", func(t *testing.T) {
+		first, err := fs.ListTreeChildren("", "", 3)
+		if err != nil {
+			t.Fatalf("ListTreeChildren failed: %v", err)
+		}
+		if first.Total != 7 || len(first.Entries) != 3 || first.NextCursor == "" {
+			t.Fatalf("Unexpected first page: %+v", first)
+		}
+		if first.Entries[0].Name != "a" || first.Entries[0].ChildCount != 2 {
+			t.Errorf("Expected folder a with 2 entries, got %+v", first.Entries[0])
+		}
+		if first.Entries[1].Name != "b" || first.Entries[1].ChildCount != 0 {
+			t.Errorf("Expected empty folder b, got %+v", first.Entries[1])
+		}
+		if first.Entries[0].Children != nil {
+			t.Error("Children should not be loaded")
+		}
+		if first.Entries[2].Name != "note4.md" {
+			t.Errorf("Expected newest note after folders, got %s", first.Entries[2].Name)
+		}
+
+		var names []string
+		for cursor := first.NextCursor; cursor != ""; {
+			page, err := fs.ListTreeChildren("", cursor, 3)
+			if err != nil {
+				t.Fatalf("ListTreeChildren failed: %v", err)
+			}
+			for _, entry := range page.Entries {
+				names = append(names, entry.Name)
+			}
+			cursor = page.NextCursor
+		}
+		if fmt.Sprint(names) != "[note3.md note2.md note1.md note0.md]" {
+			t.Errorf("Unexpected remaining entries: %v", names)
+		}
+	})
+
+	t.Run("subfolder", func(t *testing.T) {
+		page, err := fs.ListTreeChildren("a", "", 0)
+		if err != nil {
+			t.Fatalf("ListTreeChildren failed: %v", err)
+		}
+		if page.Total != 2 || page.NextCursor != "" || page.Entries[0].Path != filepath.Join("a", "deep") || page.Entries[0].ChildCount != 1 {
+			t.Errorf("Unexpected page: %+v", page)
+		}
+	})
+
+	t.Run("cursor of the last entry", func(t *testing.T) {
+		all, _ := fs.ListTreeChildren("", "", 0)
+		page, err := fs.ListTreeChildren("", treeCursor(all.Entries[len(all.Entries)-1]), 3)
+		if err != nil || len(page.Entries) != 0 || page.NextCursor != "" || page.Total != 7 {
+			t.Errorf("Expected empty last page: %+v, %v", page, err)
+		}
+	})
+
+	t.Run("cursor entry removed", func(t *testing.T) {
+		first, _ := fs.ListTreeChildren("", "", 4)
+		if first.Entries[3].Name != "note3.md" {
+			t.Fatalf("Unexpected first page: %+v", first.Entries)
+		}
+		notePath := filepath.Join(tmpDir, "note3.md")
+		info, _ := os.Stat(notePath)
+		os.Remove(notePath)
+		defer func() {
+			os.WriteFile(notePath, []byte("x"), 0644)
+			os.Chtimes(notePath, info.ModTime(), info.ModTime())
+		}()
+
+		next, err := fs.ListTreeChildren("", first.NextCursor, 2)
+		if err != nil || len(next.Entries) != 2 || next.Entries[0].Name != "note2.md" || next.Entries[1].Name != "note1.md" {
+			t.Errorf("Expected the page after where note3 was: %+v, %v", next, err)
+		}
+	})
+
+	t.Run("name order", func(t *testing.T) {
+		fs.orderTreeBy(func(string) treeOrder {
+			return treeOrder{sort: models.TreeSort{Mode: models.TreeSortName, Descending: true}}
+		})
+		defer fs.orderTreeBy(nil)
+
+		page, err := fs.ListTreeChildren("", treeCursor(models.FileInfo{Name: "note3.md"}), 2)
+		if err != nil || len(page.Entries) != 2 || page.Entries[0].Name != "note2.md" {
+			t.Fatalf("Unexpected page: %+v, %v", page, err)
+		}
+		if next, err := treeCursorEntry("", page.NextCursor); err != nil || next.Name != "note1.md" {
+			t.Errorf("Expected a cursor after note1.md, got %q", page.NextCursor)
+		}
+		if page.Entries[0].ModifiedAt.IsZero() || page.Entries[0].FileType != models.FileTypeMarkdown {
+			t.Errorf("Expected page entries with file info, got %+v", page.Entries[0])
+		}
+	})
+
+	t.Run("top level tree", func(t *testing.T) {
+		tree, err := fs.ListDirectoryTree()
+		if err != nil || len(tree) != 7 || tree[0].Name != "a" || tree[0].ChildCount != 2 || tree[0].Children != nil {
+			t.Errorf("Expected the top level with child counts: %+v, %v", tree, err)
+		}
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		for _, cursor := range []string{"a", "0:0:0:a/b", "x:0:0:a"} {
+			if _, err := fs.ListTreeChildren("", cursor, 3); err == nil {
+				t.Errorf("Should reject invalid cursor %q", cursor)
+			}
+		}
+		if _, err := fs.ListTreeChildren("../outside", "", 3); err == nil {
+			t.Error("Should reject paths outside the vault")
+		}
+	})
+}
+
+func TestFileService_ListAllFiles(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+
+	deep := filepath.Join(tmpDir, "1", "2", "3", "4", "5")
+	os.MkdirAll(deep, 0755)
+	os.WriteFile(filepath.Join(deep, "deep.md"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "top.png"), testPNG, 0644)
+	os.MkdirAll(filepath.Join(tmpDir, ".obails"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, ".obails", "state.json"), []byte("{}"), 0644)
+	os.MkdirAll(filepath.Join(tmpDir, "node_modules"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "node_modules", "pkg.md"), []byte("x"), 0644)
+	cs.config.Files.Ignore = []string{"node_modules/"}
+
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(tmpDir, "top.png"), old, old)
+
+	files, err := NewFileService(cs).ListAllFiles()
+	if err != nil {
+		t.Fatalf("ListAllFiles failed: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 files, got %+v", files)
+	}
+	if files[0].Path != filepath.Join("1", "2", "3", "4", "5", "deep.md") || files[0].FileType != "markdown" {
+		t.Errorf("Unexpected first file: %+v", files[0])
+	}
+	if files[1].Path != "top.png" || files[1].FileType != "image" {
+		t.Errorf("Unexpected second file: %+v", files[1])
+	}
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kazuph/obails/models"
+)
+
+// fileTypeEntry is what the registry knows about an extension
+type fileTypeEntry struct {
+	fileType string
+	mimeType string
+}
+
+// builtinFileTypes maps lowercase extensions (with the dot) to the file types and
+// MIME types known without configuration. It is not changed after init.
+var builtinFileTypes = make(map[string]fileTypeEntry)
+
+const markdownMimeType = "text/markdown; charset=utf-8"
+
+// noteExt is the extension of notes created by the app
+const noteExt = ".md"
+
+// fileTypeMimeTypes are the MIME types of configured extensions the built-in
+// types don't know
+var fileTypeMimeTypes = map[string]string{
+	models.FileTypeMarkdown: markdownMimeType,
+	models.FileTypeImage:    "application/octet-stream",
+	models.FileTypePDF:      "application/pdf",
+	models.FileTypeHTML:     "text/html",
+	models.FileTypeAudio:    "application/octet-stream",
+	models.FileTypeVideo:    "application/octet-stream",
+	models.FileTypeText:     "text/plain; charset=utf-8",
+	models.FileTypeData:     "text/plain; charset=utf-8",
+	models.FileTypeCanvas:   "application/json",
+	models.FileTypeOther:    "application/octet-stream",
+}
+
+func init() {
+	register := func(fileType string, mimeTypes map[string]string) {
+		for ext, mimeType := range mimeTypes {
+			builtinFileTypes[ext] = fileTypeEntry{fileType: fileType, mimeType: mimeType}
+		}
+	}
+
+	register(models.FileTypeMarkdown, map[string]string{noteExt: markdownMimeType})
+	register(models.FileTypeImage, map[string]string{
+		".png": "image/png", ".jpg": "image/jpeg", ".jpeg": "image/jpeg", ".gif": "image/gif",
+		".webp": "image/webp", ".svg": "image/svg+xml", ".bmp": "image/bmp", ".ico": "image/x-icon",
+		".avif": "image/avif",
+	})
+	register(models.FileTypePDF, map[string]string{".pdf": "application/pdf"})
+	register(models.FileTypeHTML, map[string]string{".html": "text/html", ".htm": "text/html"})
+	register(models.FileTypeAudio, map[string]string{
+		".mp3": "audio/mpeg", ".wav": "audio/wav", ".m4a": "audio/mp4", ".ogg": "audio/ogg",
+		".flac": "audio/flac", ".aac": "audio/aac", ".opus": "audio/opus",
+	})
+	register(models.FileTypeVideo, map[string]string{
+		".mp4": "video/mp4", ".m4v": "video/mp4", ".webm": "video/webm", ".mov": "video/quicktime",
+		".mkv": "video/x-matroska", ".ogv": "video/ogg",
+	})
+	register(models.FileTypeCanvas, map[string]string{".canvas": "application/json"})
+	register(models.FileTypeData, map[string]string{
+		".csv": "text/csv; charset=utf-8", ".tsv": "text/tab-separated-values; charset=utf-8",
+		".json": "application/json", ".yaml": "application/yaml", ".yml": "application/yaml",
+		".xml": "application/xml", ".toml": "application/toml",
+	})
+
+	text := "text/plain; charset=utf-8"
+	register(models.FileTypeText, map[string]string{
+		".txt": text, ".log": text, ".js": "text/javascript; charset=utf-8", ".css": "text/css; charset=utf-8",
+		".ts": text, ".tsx": text, ".jsx": text, ".go": text, ".py": text, ".rb": text, ".rs": text,
+		".java": text, ".kt": text, ".swift": text, ".c": text, ".h": text, ".cpp": text, ".hpp": text,
+		".cs": text, ".php": text, ".lua": text, ".sh": text, ".sql": text,
+	})
+}
+
+// fileTypeRegistry maps extensions to file types and MIME types: the built-in
+// ones with the extensions configured in [files] on top. Config reloads and vault
+// overrides apply to the next lookup.
+type fileTypeRegistry struct {
+	configService *ConfigService
+}
+
+func newFileTypeRegistry(configService *ConfigService) *fileTypeRegistry {
+	return &fileTypeRegistry{configService: configService}
+}
+
+// fileType determines the file type based on extension
+func (r *fileTypeRegistry) fileType(filename string) string {
+	if entry, ok := r.lookup(filename); ok {
+		return entry.fileType
+	}
+	return models.FileTypeOther
+}
+
+// mimeType returns the MIME type for a file based on extension
+func (r *fileTypeRegistry) mimeType(filename string) string {
+	if entry, ok := r.lookup(filename); ok {
+		return entry.mimeType
+	}
+	return "application/octet-stream"
+}
+
+// detect determines the file type by extension, falling back to sniffing the
+// content of files with an unknown extension. It returns the file type and MIME type.
+func (r *fileTypeRegistry) detect(fullPath string) (string, string) {
+	if entry, ok := r.lookup(fullPath); ok {
+		return entry.fileType, entry.mimeType
+	}
+
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return models.FileTypeOther, "application/octet-stream"
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, _ := f.Read(head)
+	mimeType := http.DetectContentType(head[:n])
+
+	base, _, _ := strings.Cut(mimeType, ";")
+	switch {
+	case n == 0:
+		return models.FileTypeOther, "application/octet-stream"
+	case base == "text/plain":
+		return models.FileTypeText, mimeType
+	case base == "text/html":
+		return models.FileTypeHTML, mimeType
+	case base == "application/pdf":
+		return models.FileTypePDF, mimeType
+	case strings.HasPrefix(base, "image/"):
+		return models.FileTypeImage, mimeType
+	case strings.HasPrefix(base, "audio/"), base == "application/ogg":
+		return models.FileTypeAudio, mimeType
+	case strings.HasPrefix(base, "video/"):
+		return models.FileTypeVideo, mimeType
+	case base == "application/json", base == "text/xml":
+		return models.FileTypeData, mimeType
+	}
+	return models.FileTypeOther, mimeType
+}
+
+// isNote reports whether a file is a note: .md or an extension configured as markdown
+func (r *fileTypeRegistry) isNote(filename string) bool {
+	return r.fileType(filename) == models.FileTypeMarkdown
+}
+
+// trimNoteExt removes a note extension from a file name or path
+func (r *fileTypeRegistry) trimNoteExt(name string) string {
+	if r.isNote(name) {
+		return strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return name
+}
+
+// noteExts returns the note extensions, the one of new notes first
+func (r *fileTypeRegistry) noteExts() []string {
+	files := r.configService.filesConfig()
+	var configured []string
+	for _, ext := range files.MarkdownExtensions {
+		configured = append(configured, normalizeExt(ext))
+	}
+	for ext, fileType := range files.Types {
+		if fileType == models.FileTypeMarkdown {
+			configured = append(configured, normalizeExt(ext))
+		}
+	}
+	slices.Sort(configured)
+
+	exts := []string{noteExt}
+	for _, ext := range slices.Compact(configured) {
+		if ext != noteExt && ext != "." {
+			exts = append(exts, ext)
+		}
+	}
+	return exts
+}
+
+// lookup returns the configured type of an extension, else the built-in one.
+// Configured types that aren't file type constants are ignored.
+func (r *fileTypeRegistry) lookup(filename string) (fileTypeEntry, bool) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == "" {
+		return fileTypeEntry{}, false
+	}
+
+	files := r.configService.filesConfig()
+	fileType := ""
+	if slices.ContainsFunc(files.MarkdownExtensions, func(e string) bool { return normalizeExt(e) == ext }) {
+		fileType = models.FileTypeMarkdown
+	}
+	for e, t := range files.Types {
+		if fileType == "" && normalizeExt(e) == ext {
+			fileType = t
+		}
+	}
+
+	builtin, ok := builtinFileTypes[ext]
+	mimeType, known := fileTypeMimeTypes[fileType]
+	switch {
+	case !known || fileType == builtin.fileType:
+		return builtin, ok
+	case fileType == models.FileTypeMarkdown || !ok:
+		return fileTypeEntry{fileType: fileType, mimeType: mimeType}, true
+	}
+	return fileTypeEntry{fileType: fileType, mimeType: builtin.mimeType}, true
+}
+
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kazuph/obails/models"
+)
+
+func TestFileTypeRegistry(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+	types := newFileTypeRegistry(cs)
+
+	tests := map[string]string{
+		"note.md":        models.FileTypeMarkdown,
+		"photo.JPG":      models.FileTypeImage,
+		"doc.pdf":        models.FileTypePDF,
+		"page.htm":       models.FileTypeHTML,
+		"song.mp3":       models.FileTypeAudio,
+		"clip.webm":      models.FileTypeVideo,
+		"main.go":        models.FileTypeText,
+		"table.csv":      models.FileTypeData,
+		"board.canvas":   models.FileTypeCanvas,
+		"archive.zip":    models.FileTypeOther,
+		"no-extension":   models.FileTypeOther,
+		"notes.markdown": models.FileTypeOther,
+	}
+	for name, want := range tests {
+		if got := types.fileType(name); got != want {
+			t.Errorf("fileType(%q) = %q, want %q", name, got, want)
+		}
+	}
+
+	if got := types.mimeType("clip.mov"); got != "video/quicktime" {
+		t.Errorf("Unexpected MIME type %q", got)
+	}
+	if got := types.mimeType("archive.zip"); got != "application/octet-stream" {
+		t.Errorf("Unexpected MIME type %q", got)
+	}
+}
+
+func TestFileTypeRegistry_ConfiguredTypes(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+	types := newFileTypeRegistry(cs)
+
+	cs.config.Files.Types = map[string]string{
+		"EXCALIDRAW": models.FileTypeCanvas,
+		".json":      models.FileTypeText,
+		".org":       models.FileTypeText,
+		".xyz":       "unknown",
+	}
+	if got := types.fileType("drawing.excalidraw"); got != models.FileTypeCanvas {
+		t.Errorf("Expected canvas, got %q", got)
+	}
+	if got := types.mimeType("drawing.excalidraw"); got != "application/json" {
+		t.Errorf("Unexpected MIME type %q", got)
+	}
+	if types.fileType("a.json") != models.FileTypeText || types.mimeType("a.json") != "application/json" {
+		t.Error("A remapped extension should keep its built-in MIME type")
+	}
+	if types.mimeType("a.org") != "text/plain; charset=utf-8" {
+		t.Errorf("Unexpected MIME type %q", types.mimeType("a.org"))
+	}
+	if got := types.fileType("a.xyz"); got != models.FileTypeOther {
+		t.Errorf("Unknown file types should be ignored, got %q", got)
+	}
+
+	cs.config.Files.Types = nil
+	if types.fileType("drawing.excalidraw") != models.FileTypeOther || types.fileType("a.json") != models.FileTypeData {
+		t.Error("Removing the config should restore the built-in types")
+	}
+}
+
+func TestFileTypeRegistry_MarkdownExtensions(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+	types := newFileTypeRegistry(cs)
+
+	cs.config.Files.MarkdownExtensions = []string{".markdown", "MDX", ".txt"}
+	cs.config.Files.Types = map[string]string{".rmd": models.FileTypeMarkdown}
+
+	for _, name := range []string{"a.md", "a.markdown", "a.mdx", "a.txt", "a.Rmd"} {
+		if !types.isNote(name) {
+			t.Errorf("%s should be a note", name)
+		}
+	}
+	if types.mimeType("a.txt") != markdownMimeType {
+		t.Errorf("Markdown-like extensions should be served as markdown")
+	}
+	if got := types.trimNoteExt("dir/Note.mdx"); got != "dir/Note" {
+		t.Errorf("Unexpected trimmed name %q", got)
+	}
+	if got := types.trimNoteExt("image.png"); got != "image.png" {
+		t.Errorf("Non-note names should be kept, got %q", got)
+	}
+	if got := strings.Join(types.noteExts(), ","); got != ".md,.markdown,.mdx,.rmd,.txt" {
+		t.Errorf("Unexpected note extensions %s", got)
+	}
+
+	cs.config.Files.MarkdownExtensions = nil
+	if types.isNote("a.mdx") || types.fileType("a.txt") != models.FileTypeText {
+		t.Error("Resetting the extensions should restore the built-in types")
+	}
+}
+
+func TestDetectFileType(t *testing.T) {
+	tmpDir := t.TempDir()
+	write := func(name string, content []byte) string {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name     string
+		content  []byte
+		fileType string
+		mimeType string
+	}{
+		{"known.csv", []byte("\x89PNG"), models.FileTypeData, "text/csv; charset=utf-8"},
+		{"pasted", testPNG, models.FileTypeImage, "image/png"},
+		{"README", []byte("plain words\n"), models.FileTypeText, "text/plain; charset=utf-8"},
+		{"recording", []byte("ID3\x03\x00\x00\x00\x00\x00\x00"), models.FileTypeAudio, "audio/mpeg"},
+		{"blob", []byte{0x00, 0x01, 0x02, 0xff}, models.FileTypeOther, "application/octet-stream"},
+		{"empty", nil, models.FileTypeOther, "application/octet-stream"},
+	}
+	types := newFileTypeRegistry(NewConfigService())
+	for _, tt := range tests {
+		fileType, mimeType := types.detect(write(tt.name, tt.content))
+		if fileType != tt.fileType || mimeType != tt.mimeType {
+			t.Errorf("DetectFileType(%s) = %q, %q, want %q, %q", tt.name, fileType, mimeType, tt.fileType, tt.mimeType)
+		}
+	}
+
+	if fileType, _ := types.detect(filepath.Join(tmpDir, "missing")); fileType != models.FileTypeOther {
+		t.Errorf("Missing files should be other, got %q", fileType)
+	}
+}
+
+func TestNotesWithMarkdownExtensions(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+	cs.config.Files.MarkdownExtensions = []string{".mdx"}
+
+	os.WriteFile(filepath.Join(tmpDir, "Page.mdx"), []byte("# Page\n[[Other]]"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "Other.md"), []byte("# Other"), 0644)
+
+	fs := NewFileService(cs)
+	results, err := fs.SearchFiles("page")
+	if err != nil || len(results) != 1 || results[0].Path != "Page.mdx" {
+		t.Errorf("SearchFiles should find .mdx notes: %v, %v", results, err)
+	}
+
+	ls := NewLinkService(fs, cs)
+	if err := ls.RebuildIndex(); err != nil {
+		t.Fatalf("RebuildIndex failed: %v", err)
+	}
+	if path, ok := ls.ResolveLink("Page"); !ok || path != "Page.mdx" {
+		t.Errorf("ResolveLink should find Page.mdx, got %q", path)
+	}
+	backlinks := ls.GetBacklinks("Other.md")
+	if len(backlinks) != 1 || backlinks[0].SourcePath != "Page.mdx" || backlinks[0].SourceTitle != "Page" {
+		t.Errorf("Unexpected backlinks: %+v", backlinks)
+	}
+
+	t.Run("daily notes", func(t *testing.T) {
+		cs.config.DailyNotes = models.DailyNotesConfig{Folder: "daily", Format: "2006-01-02"}
+		ns := NewNoteService(fs, cs)
+		daily := filepath.Join("daily", "2026-10-16.mdx")
+		fs.CreateFile(daily, "# Friday\n")
+
+		note, err := ns.GetDailyNote("2026-10-16")
+		if err != nil || note.Path != daily {
+			t.Errorf("GetDailyNote should find the .mdx note: %+v, %v", note, err)
+		}
+		notes, err := ns.ListDailyNotes()
+		if err != nil || len(notes) != 1 || notes[0].Date != "2026-10-16" {
+			t.Errorf("ListDailyNotes should list the .mdx note: %+v, %v", notes, err)
+		}
+	})
+}
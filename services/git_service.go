@@ -0,0 +1,757 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/kazuph/obails/models"
+)
+
+// gitSaveCommitDelay batches the saves of an editing burst into one auto-commit
+const gitSaveCommitDelay = 10 * time.Second
+
+// gitTreeStatusMaxAge is how long the file tree's git status is reused. Saves,
+// moves, deletes and commits drop it sooner; the age catches other changes.
+const gitTreeStatusMaxAge = 5 * time.Second
+
+// GitService versions the vault with the git repository containing it
+type GitService struct {
+	fileService   *FileService
+	configService *ConfigService
+
+	mu         sync.Mutex // Serializes repository operations
+	saveDelay  time.Duration
+	saveTimer  *time.Timer
+	lastCommit time.Time
+	started    bool
+
+	// The file tree's git status is cached apart from mu, so listing the tree
+	// never waits for a pull or push
+	statusMu     sync.Mutex
+	treeStatus   map[string]string // Status of changed vault paths and their folders, nil when stale
+	treeStatusAt time.Time
+}
+
+// NewGitService creates a new GitService that auto-commits on note saves when configured
+func NewGitService(noteService *NoteService, fileService *FileService, configService *ConfigService) *GitService {
+	s := &GitService{
+		fileService:   fileService,
+		configService: configService,
+		saveDelay:     gitSaveCommitDelay,
+	}
+	noteService.onSave(s.noteSaved)
+	fileService.onMove(func(from string, to string) { s.invalidateTreeStatus() })
+	fileService.onDelete(func(relativePath string) { s.invalidateTreeStatus() })
+	fileService.onRestore(func(original string, restored string) { s.invalidateTreeStatus() })
+	fileService.annotateTreeBy(s.annotateTree)
+	return s
+}
+
+// vaultRepo is the repository containing the vault. prefix is the vault folder
+// relative to the worktree root ("" when the vault is the root).
+type vaultRepo struct {
+	repo   *git.Repository
+	wt     *git.Worktree
+	prefix string
+}
+
+// GetStatus returns the branch and the changed files of the vault repository.
+// IsRepo is false if the vault is not inside a git repository.
+func (s *GitService) GetStatus() (*models.GitStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.open()
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		return &models.GitStatus{Files: []models.GitFileStatus{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := r.changedFiles()
+	if err != nil {
+		return nil, err
+	}
+	return &models.GitStatus{
+		IsRepo: true,
+		Branch: r.branch(),
+		Clean:  len(files) == 0,
+		Files:  files,
+	}, nil
+}
+
+// Commit commits all changes in the vault. An empty message is generated from the
+// configured template. It returns nil if there was nothing to commit.
+func (s *GitService) Commit(message string) (*models.GitCommit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.commitAll(message)
+}
+
+// StartAutoCommit starts committing the vault periodically when the interval mode
+// is configured. The mode is checked on every tick, so config reloads apply.
+func (s *GitService) StartAutoCommit() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return
+	}
+	s.started = true
+	s.lastCommit = time.Now()
+
+	go func() {
+		for range time.Tick(time.Minute) {
+			cfg := s.configService.GetGit()
+			if cfg.AutoCommit != models.GitAutoCommitInterval {
+				continue
+			}
+			s.mu.Lock()
+			if time.Since(s.lastCommit) >= time.Duration(cfg.IntervalMinutes)*time.Minute {
+				s.commitAll("") // Best effort, retried on the next interval
+			}
+			s.mu.Unlock()
+		}
+	}()
+}
+
+// GetNoteLog returns the commits that changed a note, newest first. A limit of 0
+// returns all of them.
+func (s *GitService) GetNoteLog(relativePath string, limit int) ([]models.GitCommit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, repoPath, err := s.openForNote(relativePath)
+	if err != nil {
+		return nil, err
+	}
+	head, err := r.repo.Head()
+	if err != nil {
+		return nil, err
+	}
+
+	iter, err := r.repo.Log(&git.LogOptions{From: head.Hash(), FileName: &repoPath})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	commits := []models.GitCommit{}
+	for limit == 0 || len(commits) < limit {
+		commit, err := iter.Next()
+		if err != nil {
+			break
+		}
+		commits = append(commits, commitInfo(commit))
+	}
+	return commits, nil
+}
+
+// GetNoteBlame attributes each line of the committed version of a note to the commit
+// that last changed it
+func (s *GitService) GetNoteBlame(relativePath string) ([]models.GitBlameLine, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, repoPath, err := s.openForNote(relativePath)
+	if err != nil {
+		return nil, err
+	}
+	head, err := r.repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	commit, err := r.repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	blame, err := git.Blame(commit, repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]models.GitBlameLine, len(blame.Lines))
+	for i, line := range blame.Lines {
+		lines[i] = models.GitBlameLine{
+			Line:   i + 1,
+			Text:   line.Text,
+			Hash:   line.Hash.String(),
+			Author: line.AuthorName,
+			When:   line.Date,
+		}
+	}
+	return lines, nil
+}
+
+// DiffNote compares a note between two commits. An empty hash stands for the note
+// in the working tree; a note missing from a commit compares as empty.
+func (s *GitService) DiffNote(relativePath string, fromHash string, toHash string) ([]models.DiffLine, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, repoPath, err := s.openForNote(relativePath)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := s.contentAt(r, relativePath, repoPath, fromHash)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.contentAt(r, relativePath, repoPath, toHash)
+	if err != nil {
+		return nil, err
+	}
+	return diffLines(from, to), nil
+}
+
+// Pull fast-forwards the current branch to the configured remote. Nothing is changed
+// if there are uncommitted changes or both sides have new commits; the result then
+// lists the files in the way.
+func (s *GitService) Pull() (*models.GitSyncResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := r.changedFiles()
+	if err != nil {
+		return nil, err
+	}
+	var uncommitted []string
+	for _, file := range files {
+		if file.Status != models.GitStatusUntracked {
+			uncommitted = append(uncommitted, file.Path)
+		}
+	}
+	if len(uncommitted) > 0 {
+		return &models.GitSyncResult{Status: models.GitSyncUncommitted, Conflicts: uncommitted}, nil
+	}
+
+	head, remote, err := s.fetch(r)
+	if err != nil {
+		return nil, err
+	}
+	if remote == nil {
+		return &models.GitSyncResult{Status: models.GitSyncUpToDate, Conflicts: []string{}}, nil
+	}
+	if behind, err := remote.IsAncestor(head); err != nil || behind {
+		return &models.GitSyncResult{Status: models.GitSyncUpToDate, Conflicts: []string{}}, err
+	}
+	if ahead, err := head.IsAncestor(remote); err != nil || !ahead {
+		return s.diverged(r, head, remote, err)
+	}
+
+	err = r.wt.Pull(&git.PullOptions{
+		RemoteName:    s.configService.GetGit().Remote,
+		ReferenceName: plumbing.NewBranchReferenceName(r.branch()),
+	})
+	s.invalidateTreeStatus()
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, err
+	}
+	return &models.GitSyncResult{Status: models.GitSyncUpdated, Conflicts: []string{}}, nil
+}
+
+// Push pushes the current branch to the configured remote. Nothing is pushed if the
+// remote has commits that were not pulled yet.
+func (s *GitService) Push() (*models.GitSyncResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+
+	head, remote, err := s.fetch(r)
+	if err != nil {
+		return nil, err
+	}
+	if remote != nil {
+		if ok, err := remote.IsAncestor(head); err != nil || !ok {
+			return s.diverged(r, head, remote, err)
+		}
+	}
+
+	branch := plumbing.NewBranchReferenceName(r.branch())
+	err = r.repo.Push(&git.PushOptions{
+		RemoteName: s.configService.GetGit().Remote,
+		RefSpecs:   []config.RefSpec{config.RefSpec(branch + ":" + branch)},
+	})
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return &models.GitSyncResult{Status: models.GitSyncUpToDate, Conflicts: []string{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &models.GitSyncResult{Status: models.GitSyncUpdated, Conflicts: []string{}}, nil
+}
+
+// annotateTree sets the git status of file tree entries and their children.
+// Folders containing changed files are marked modified.
+func (s *GitService) annotateTree(entries []models.FileInfo) {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+
+	if s.treeStatus == nil || time.Since(s.treeStatusAt) > gitTreeStatusMaxAge {
+		r, err := s.open()
+		if err != nil {
+			return // Not a repository, the tree has no status
+		}
+		files, err := r.changedFiles()
+		if err != nil {
+			return // Best effort, the tree is shown without status
+		}
+
+		s.treeStatus = make(map[string]string, len(files))
+		for _, file := range files {
+			s.treeStatus[file.Path] = file.Status
+			for dir := path.Dir(file.Path); dir != "." && s.treeStatus[dir] == ""; dir = path.Dir(dir) {
+				s.treeStatus[dir] = models.GitStatusModified
+			}
+		}
+		s.treeStatusAt = time.Now()
+	}
+	setTreeStatus(entries, s.treeStatus)
+}
+
+// invalidateTreeStatus makes the next tree listing read the git status again
+func (s *GitService) invalidateTreeStatus() {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	s.treeStatus = nil
+}
+
+func setTreeStatus(entries []models.FileInfo, statuses map[string]string) {
+	for i := range entries {
+		entries[i].GitStatus = statuses[filepath.ToSlash(entries[i].Path)]
+		setTreeStatus(entries[i].Children, statuses)
+	}
+}
+
+// noteSaved drops the tree status and schedules an auto-commit after a burst of saves
+func (s *GitService) noteSaved(relativePath string) {
+	s.invalidateTreeStatus()
+	if s.configService.GetGit().AutoCommit != models.GitAutoCommitSave {
+		return
+	}
+
+	// The commit goes to the vault saved in, even if another one is opened meanwhile
+	vaultPath, err := s.fileService.getFullPath("")
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.saveTimer != nil {
+		s.saveTimer.Stop()
+	}
+	s.saveTimer = time.AfterFunc(s.saveDelay, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if r, err := openVaultRepo(vaultPath); err == nil {
+			s.commitRepo(r, "") // Best effort, retried on the next save
+		}
+	})
+}
+
+// closeVault commits the saves of the previously open vault at vaultPath still
+// waiting for their auto-commit, once another vault was opened, and restarts the
+// interval for the new vault
+func (s *GitService) closeVault(vaultPath string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.saveTimer != nil && s.saveTimer.Stop() {
+		if r, err := openVaultRepo(vaultPath); err == nil {
+			s.commitRepo(r, "") // Best effort, the changes stay in the old vault's worktree
+		}
+	}
+	s.saveTimer = nil
+	s.lastCommit = time.Now()
+	s.invalidateTreeStatus()
+}
+
+// commitAll stages and commits every change in the vault. The caller must hold mu.
+func (s *GitService) commitAll(message string) (*models.GitCommit, error) {
+	r, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	return s.commitRepo(r, message)
+}
+
+// commitRepo stages and commits every change in a vault's repository. The caller
+// must hold mu.
+func (s *GitService) commitRepo(r *vaultRepo, message string) (*models.GitCommit, error) {
+	files, err := r.changedFiles()
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+
+	if message == "" {
+		paths := make([]string, len(files))
+		for i, file := range files {
+			paths[i] = file.Path
+		}
+		message, err = renderCommitMessage(s.configService.GetGit().MessageTemplate, paths, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Only the listed files are staged, so hidden and app folders stay out
+	defer s.invalidateTreeStatus()
+	for _, file := range files {
+		repoPath := path.Join(r.prefix, file.Path)
+		if file.Status == models.GitStatusDeleted {
+			_, err = r.wt.Remove(repoPath)
+		} else {
+			err = r.wt.AddWithOptions(&git.AddOptions{Path: repoPath, SkipStatus: true})
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hash, err := r.wt.Commit(message, &git.CommitOptions{Author: s.signature(r.repo)})
+	if err != nil {
+		return nil, err
+	}
+	commit, err := r.repo.CommitObject(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lastCommit = time.Now()
+	info := commitInfo(commit)
+	return &info, nil
+}
+
+// signature returns the configured author, falling back to the git user config
+func (s *GitService) signature(repo *git.Repository) *object.Signature {
+	cfg := s.configService.GetGit()
+	sig := &object.Signature{Name: cfg.AuthorName, Email: cfg.AuthorEmail, When: time.Now()}
+
+	if user, err := repo.ConfigScoped(config.GlobalScope); err == nil {
+		if sig.Name == "" {
+			sig.Name = user.User.Name
+		}
+		if sig.Email == "" {
+			sig.Email = user.User.Email
+		}
+	}
+	if sig.Name == "" {
+		sig.Name = "Obails"
+	}
+	if sig.Email == "" {
+		sig.Email = "obails@localhost"
+	}
+	return sig
+}
+
+// fetch updates the remote-tracking branch and returns the local and remote head
+// commits. remote is nil if the branch doesn't exist on the remote.
+func (s *GitService) fetch(r *vaultRepo) (*object.Commit, *object.Commit, error) {
+	head, err := r.repo.Head()
+	if err != nil {
+		return nil, nil, err
+	}
+	headCommit, err := r.repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	remoteName := s.configService.GetGit().Remote
+	err = r.repo.Fetch(&git.FetchOptions{RemoteName: remoteName})
+	if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		return headCommit, nil, nil
+	}
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, nil, err
+	}
+
+	ref, err := r.repo.Reference(plumbing.NewRemoteReferenceName(remoteName, r.branch()), true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return headCommit, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	remoteCommit, err := r.repo.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, nil, err
+	}
+	return headCommit, remoteCommit, nil
+}
+
+// diverged reports the files changed on both sides since the common ancestor
+func (s *GitService) diverged(r *vaultRepo, head *object.Commit, remote *object.Commit, err error) (*models.GitSyncResult, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.GitSyncResult{Status: models.GitSyncDiverged, Conflicts: []string{}}
+	bases, err := head.MergeBase(remote)
+	if err != nil || len(bases) == 0 {
+		return result, err
+	}
+
+	ours, err := changedPaths(bases[0], head)
+	if err != nil {
+		return nil, err
+	}
+	theirs, err := changedPaths(bases[0], remote)
+	if err != nil {
+		return nil, err
+	}
+	for repoPath := range ours {
+		if vaultPath, ok := r.vaultPath(repoPath); ok && theirs[repoPath] {
+			result.Conflicts = append(result.Conflicts, vaultPath)
+		}
+	}
+	sort.Strings(result.Conflicts)
+	return result, nil
+}
+
+// contentAt returns a note's content at a commit, or in the working tree for ""
+func (s *GitService) contentAt(r *vaultRepo, relativePath string, repoPath string, hash string) (string, error) {
+	if hash == "" {
+		if !s.fileService.FileExists(relativePath) {
+			return "", nil
+		}
+		return s.fileService.ReadFile(relativePath)
+	}
+
+	commit, err := r.repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return "", err
+	}
+	file, err := commit.File(repoPath)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return file.Contents()
+}
+
+// open opens the repository containing the vault
+func (s *GitService) open() (*vaultRepo, error) {
+	vaultPath, err := s.fileService.getFullPath("")
+	if err != nil {
+		return nil, err
+	}
+	return openVaultRepo(vaultPath)
+}
+
+// openVaultRepo opens the repository containing a vault folder
+func openVaultRepo(vaultPath string) (*vaultRepo, error) {
+	if vaultPath == "" {
+		return nil, git.ErrRepositoryNotExists // No vault open
+	}
+	repo, err := git.PlainOpenWithOptions(vaultPath, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, err := filepath.Rel(wt.Filesystem.Root(), vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	if prefix == "." {
+		prefix = ""
+	}
+	return &vaultRepo{repo: repo, wt: wt, prefix: filepath.ToSlash(prefix)}, nil
+}
+
+// openForNote opens the repository and returns the note's path in it
+func (s *GitService) openForNote(relativePath string) (*vaultRepo, string, error) {
+	rel, err := cleanRelativePath(relativePath)
+	if err != nil {
+		return nil, "", err
+	}
+	if rel == "." {
+		return nil, "", &VaultPathError{Path: relativePath, Err: ErrVaultRoot}
+	}
+
+	r, err := s.open()
+	if err != nil {
+		return nil, "", err
+	}
+	return r, path.Join(r.prefix, filepath.ToSlash(rel)), nil
+}
+
+// changedFiles returns the changed files inside the vault, sorted by path. Files
+// in hidden folders such as .trash and .obails are left out.
+func (r *vaultRepo) changedFiles() ([]models.GitFileStatus, error) {
+	status, err := r.wt.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	files := []models.GitFileStatus{}
+	for repoPath, fileStatus := range status {
+		vaultPath, ok := r.vaultPath(repoPath)
+		if !ok || inHiddenFolder(vaultPath) {
+			continue
+		}
+		if state, staged, changed := gitFileState(fileStatus); changed {
+			files = append(files, models.GitFileStatus{Path: vaultPath, Status: state, Staged: staged})
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
+
+// branch returns the name of the checked out branch, also before the first commit
+func (r *vaultRepo) branch() string {
+	head, err := r.repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return ""
+	}
+	if head.Type() == plumbing.SymbolicReference {
+		return head.Target().Short()
+	}
+	return head.Hash().String()[:7] // Detached HEAD
+}
+
+// vaultPath converts a worktree path to a vault-relative path
+func (r *vaultRepo) vaultPath(repoPath string) (string, bool) {
+	if r.prefix == "" {
+		return repoPath, true
+	}
+	rel, ok := strings.CutPrefix(repoPath, r.prefix+"/")
+	return rel, ok
+}
+
+// inHiddenFolder reports whether a slash-separated vault path is inside a folder
+// hidden from the file tree. Hidden files like .gitignore are not.
+func inHiddenFolder(vaultPath string) bool {
+	dir, _ := path.Split(vaultPath)
+	return slices.ContainsFunc(strings.Split(dir, "/"), func(name string) bool {
+		return strings.HasPrefix(name, ".")
+	})
+}
+
+// gitFileState maps a git status entry to a status name. The worktree state wins
+// over the staged one, as it is what the file tree shows.
+func gitFileState(status *git.FileStatus) (string, bool, bool) {
+	if status.Staging == git.UpdatedButUnmerged || status.Worktree == git.UpdatedButUnmerged {
+		return models.GitStatusConflicted, false, true
+	}
+	if status.Worktree == git.Untracked {
+		return models.GitStatusUntracked, false, true
+	}
+
+	code, staged := status.Worktree, false
+	if code == git.Unmodified {
+		code, staged = status.Staging, true
+	}
+	switch code {
+	case git.Modified:
+		return models.GitStatusModified, staged, true
+	case git.Added, git.Copied:
+		return models.GitStatusAdded, staged, true
+	case git.Deleted:
+		return models.GitStatusDeleted, staged, true
+	case git.Renamed:
+		return models.GitStatusRenamed, staged, true
+	}
+	return "", false, false
+}
+
+// changedPaths returns the worktree paths that differ between two commits
+func changedPaths(from *object.Commit, to *object.Commit) (map[string]bool, error) {
+	fromTree, err := from.Tree()
+	if err != nil {
+		return nil, err
+	}
+	toTree, err := to.Tree()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := fromTree.Diff(toTree)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]bool)
+	for _, change := range changes {
+		if change.From.Name != "" {
+			paths[change.From.Name] = true
+		}
+		if change.To.Name != "" {
+			paths[change.To.Name] = true
+		}
+	}
+	return paths, nil
+}
+
+func commitInfo(commit *object.Commit) models.GitCommit {
+	return models.GitCommit{
+		Hash:    commit.Hash.String(),
+		Message: strings.TrimSpace(commit.Message),
+		Author:  commit.Author.Name,
+		Email:   commit.Author.Email,
+		When:    commit.Author.When,
+	}
+}
+
+// renderCommitMessage executes a commit message template with the date, time and
+// changed files
+func renderCommitMessage(text string, files []string, now time.Time) (string, error) {
+	tmpl, err := template.New("commit").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid commit message template: %w", err)
+	}
+
+	data := struct {
+		Date  string
+		Time  string
+		Count int
+		Files string
+	}{
+		Date:  now.Format("2006-01-02"),
+		Time:  now.Format("15:04:05"),
+		Count: len(files),
+		Files: strings.Join(files, ", "),
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("invalid commit message template: %w", err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/kazuph/obails/models"
+)
+
+func newTestGitService(t *testing.T) (*GitService, *NoteService, *FileService, string) {
+	t.Helper()
+	ns, fs, tmpDir := newTestNoteService(t)
+	ns.configService.config.Git = models.GitConfig{AuthorName: "Tester", AuthorEmail: "tester@example.com"}
+
+	if _, err := git.PlainInit(tmpDir, false); err != nil {
+		t.Fatalf("Failed to init repository: %v", err)
+	}
+	return NewGitService(ns, fs, ns.configService), ns, fs, tmpDir
+}
+
+func TestGitService_NotARepository(t *testing.T) {
+	ns, fs, tmpDir := newTestNoteService(t)
+	defer os.RemoveAll(tmpDir)
+
+	status, err := NewGitService(ns, fs, ns.configService).GetStatus()
+	if err != nil || status.IsRepo {
+		t.Errorf("Expected no repository, got %+v, %v", status, err)
+	}
+}
+
+func TestGitService_StatusAndCommit(t *testing.T) {
+	gs, ns, fs, tmpDir := newTestGitService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs.CreateFile("notes/a.md", "line 1\n")
+	fs.CreateFile("b.md", "b\n")
+
+	status, err := gs.GetStatus()
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+	if !status.IsRepo || status.Branch != "master" || status.Clean || len(status.Files) != 2 {
+		t.Fatalf("Unexpected status: %+v", status)
+	}
+	if status.Files[1].Path != filepath.Join("notes", "a.md") || status.Files[1].Status != models.GitStatusUntracked {
+		t.Errorf("Unexpected file status: %+v", status.Files[1])
+	}
+
+	first, err := gs.Commit("")
+	if err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if !strings.HasPrefix(first.Message, "vault backup: ") || first.Author != "Tester" {
+		t.Errorf("Unexpected commit: %+v", first)
+	}
+
+	t.Run("nothing to commit", func(t *testing.T) {
+		commit, err := gs.Commit("")
+		if commit != nil || err != nil {
+			t.Errorf("Expected no commit, got %+v, %v", commit, err)
+		}
+	})
+
+	ns.SaveNote("notes/a.md", "line 1\nline 2\n")
+	fs.DeletePath("b.md")
+
+	status, _ = gs.GetStatus()
+	states := map[string]string{}
+	for _, file := range status.Files {
+		states[file.Path] = file.Status
+	}
+	if states[filepath.Join("notes", "a.md")] != models.GitStatusModified || states["b.md"] != models.GitStatusDeleted {
+		t.Errorf("Unexpected status: %+v", status.Files)
+	}
+
+	second, err := gs.Commit("Edit a")
+	if err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	t.Run("log", func(t *testing.T) {
+		log, err := gs.GetNoteLog("notes/a.md", 0)
+		if err != nil {
+			t.Fatalf("GetNoteLog failed: %v", err)
+		}
+		if len(log) != 2 || log[0].Hash != second.Hash || log[1].Hash != first.Hash {
+			t.Errorf("Unexpected log: %+v", log)
+		}
+		if log, _ := gs.GetNoteLog("notes/a.md", 1); len(log) != 1 {
+			t.Errorf("Limit not applied: %+v", log)
+		}
+	})
+
+	t.Run("blame", func(t *testing.T) {
+		lines, err := gs.GetNoteBlame("notes/a.md")
+		if err != nil {
+			t.Fatalf("GetNoteBlame failed: %v", err)
+		}
+		if len(lines) != 2 || lines[0].Hash != first.Hash || lines[1].Hash != second.Hash || lines[1].Text != "line 2" {
+			t.Errorf("Unexpected blame: %+v", lines)
+		}
+	})
+
+	t.Run("diff", func(t *testing.T) {
+		ns.SaveNote("notes/a.md", "line 1\nline 2\nline 3\n")
+
+		diff, err := gs.DiffNote("notes/a.md", first.Hash, "")
+		if err != nil {
+			t.Fatalf("DiffNote failed: %v", err)
+		}
+		if len(diff) != 3 || diff[1].Op != models.DiffOpInsert || diff[2].Text != "line 3" {
+			t.Errorf("Unexpected diff: %+v", diff)
+		}
+	})
+}
+
+func TestGitService_SkipsHiddenFolders(t *testing.T) {
+	gs, ns, fs, tmpDir := newTestGitService(t)
+	defer os.RemoveAll(tmpDir)
+
+	ns.SaveNote("a.md", "a\n") // Records history in .obails
+	fs.CreateFile("b.md", "b\n")
+	fs.CreateFile(".gitignore", "*.tmp\n")
+	gs.Commit("")
+	fs.DeletePath("b.md") // Moves it to .trash
+
+	status, err := gs.GetStatus()
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+	if len(status.Files) != 1 || status.Files[0].Path != "b.md" || status.Files[0].Status != models.GitStatusDeleted {
+		t.Errorf("Expected only the deleted note, got %+v", status.Files)
+	}
+	if _, err := gs.Commit(""); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	r, _ := gs.open()
+	head, _ := r.repo.Head()
+	commit, _ := r.repo.CommitObject(head.Hash())
+	tree, _ := commit.Tree()
+	var committed []string
+	tree.Files().ForEach(func(file *object.File) error {
+		committed = append(committed, file.Name)
+		return nil
+	})
+	if strings.Join(committed, ",") != ".gitignore,a.md" {
+		t.Errorf("Unexpected committed files %v", committed)
+	}
+}
+
+func TestGitService_AnnotatesTree(t *testing.T) {
+	gs, ns, fs, tmpDir := newTestGitService(t)
+	defer os.RemoveAll(tmpDir)
+
+	ns.SaveNote("notes/a.md", "a\n")
+	fs.CreateFile("b.md", "b\n")
+	gs.Commit("")
+	ns.SaveNote("notes/a.md", "aa\n")
+	fs.CreateFile("c.md", "c\n")
+
+	entries, err := fs.ListDirectoryTree()
+	if err != nil {
+		t.Fatalf("ListDirectoryTree failed: %v", err)
+	}
+	notes, err := fs.ListTreeChildren("notes", "", 0)
+	if err != nil {
+		t.Fatalf("ListTreeChildren failed: %v", err)
+	}
+	states := map[string]string{}
+	for _, entry := range append(entries, notes.Entries...) {
+		states[entry.Path] = entry.GitStatus
+	}
+	want := map[string]string{
+		"notes":                        models.GitStatusModified,
+		filepath.Join("notes", "a.md"): models.GitStatusModified,
+		"b.md":                         "",
+		"c.md":                         models.GitStatusUntracked,
+	}
+	for path, status := range want {
+		if states[path] != status {
+			t.Errorf("Expected %s to be %q, got %q", path, status, states[path])
+		}
+	}
+
+	t.Run("commit refreshes the status", func(t *testing.T) {
+		if _, err := gs.Commit(""); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+		entries, _ := fs.ListDirectoryTree()
+		for _, entry := range entries {
+			if entry.GitStatus != "" {
+				t.Errorf("Expected %s to be clean after a commit, got %q", entry.Path, entry.GitStatus)
+			}
+		}
+	})
+
+	t.Run("listing doesn't wait for a sync", func(t *testing.T) {
+		gs.mu.Lock() // As held during a pull or push
+		defer gs.mu.Unlock()
+
+		done := make(chan struct{})
+		go func() {
+			fs.ListDirectoryTree()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Listing the tree waited for the repository lock")
+		}
+	})
+}
+
+func TestGitService_AutoCommitOnSave(t *testing.T) {
+	gs, ns, _, tmpDir := newTestGitService(t)
+	defer os.RemoveAll(tmpDir)
+
+	gs.saveDelay = 10 * time.Millisecond
+	ns.configService.config.Git.AutoCommit = models.GitAutoCommitSave
+	ns.configService.config.Git.MessageTemplate = "{{.Count}} changed: {{.Files}}"
+
+	ns.SaveNote("a.md", "a")
+	ns.SaveNote("a.md", "aa")
+
+	var log []models.GitCommit
+	for i := 0; i < 100 && len(log) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		log, _ = gs.GetNoteLog("a.md", 0)
+	}
+	if len(log) != 1 || log[0].Message != "1 changed: a.md" {
+		t.Errorf("Unexpected auto-commits: %+v", log)
+	}
+}
+
+func TestGitService_PullPush(t *testing.T) {
+	gs, _, fs, tmpDir := newTestGitService(t)
+	defer os.RemoveAll(tmpDir)
+
+	remoteDir := t.TempDir()
+	if _, err := git.PlainInit(remoteDir, true); err != nil {
+		t.Fatalf("Failed to init remote: %v", err)
+	}
+	repo, _ := git.PlainOpen(tmpDir)
+	repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remoteDir}})
+
+	fs.CreateFile("shared.md", "base\n")
+	gs.Commit("Initial")
+
+	result, err := gs.Push()
+	if err != nil || result.Status != models.GitSyncUpdated {
+		t.Fatalf("Push = %+v, %v", result, err)
+	}
+
+	// A second vault cloned from the same remote
+	otherDir := t.TempDir()
+	if _, err := git.PlainClone(otherDir, false, &git.CloneOptions{URL: remoteDir}); err != nil {
+		t.Fatalf("Failed to clone: %v", err)
+	}
+	otherConfig := &ConfigService{config: &models.Config{
+		Vault: models.VaultConfig{Path: otherDir},
+		Git:   models.GitConfig{AuthorName: "Other", AuthorEmail: "other@example.com"},
+	}}
+	otherFiles := NewFileService(otherConfig)
+	other := NewGitService(NewNoteService(otherFiles, otherConfig), otherFiles, otherConfig)
+
+	t.Run("pull fast-forward", func(t *testing.T) {
+		fs.WriteFile("shared.md", "base\nfrom first\n")
+		gs.Commit("First edit")
+		gs.Push()
+
+		result, err := other.Pull()
+		if err != nil || result.Status != models.GitSyncUpdated {
+			t.Fatalf("Pull = %+v, %v", result, err)
+		}
+		content, _ := otherFiles.ReadFile("shared.md")
+		if content != "base\nfrom first\n" {
+			t.Errorf("Pulled content = %q", content)
+		}
+
+		if result, _ := other.Pull(); result.Status != models.GitSyncUpToDate {
+			t.Errorf("Expected up to date, got %+v", result)
+		}
+	})
+
+	t.Run("pull refuses uncommitted changes", func(t *testing.T) {
+		otherFiles.WriteFile("shared.md", "dirty\n")
+		result, err := other.Pull()
+		if err != nil || result.Status != models.GitSyncUncommitted || len(result.Conflicts) != 1 {
+			t.Errorf("Pull = %+v, %v", result, err)
+		}
+	})
+
+	t.Run("diverged", func(t *testing.T) {
+		otherFiles.WriteFile("shared.md", "base\nfrom other\n")
+		otherFiles.CreateFile("only-other.md", "x")
+		other.Commit("Other edit")
+
+		fs.WriteFile("shared.md", "base\nfrom first, again\n")
+		gs.Commit("Second edit")
+		gs.Push()
+
+		result, err := other.Push()
+		if err != nil || result.Status != models.GitSyncDiverged {
+			t.Fatalf("Push = %+v, %v", result, err)
+		}
+		if len(result.Conflicts) != 1 || result.Conflicts[0] != "shared.md" {
+			t.Errorf("Unexpected conflicts: %+v", result.Conflicts)
+		}
+
+		result, err = other.Pull()
+		if err != nil || result.Status != models.GitSyncDiverged {
+			t.Errorf("Pull = %+v, %v", result, err)
+		}
+	})
+}
+
+func TestRenderCommitMessage(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 30, 0, 0, time.Local)
+	message, err := renderCommitMessage("backup {{.Date}} {{.Time}} ({{.Count}})", []string{"a.md", "b.md"}, now)
+	if err != nil || message != "backup 2026-10-18 09:30:00 (2)" {
+		t.Errorf("renderCommitMessage = %q, %v", message, err)
+	}
+
+	if _, err := renderCommitMessage("{{.Nope", nil, now); err == nil {
+		t.Error("Should fail for an invalid template")
+	}
+}
@@ -24,12 +24,12 @@ func NewGraphService(linkService *LinkService, fileService *FileService, configS
 }
 
 // isMarkdownFile checks if a file path is a markdown file
-func isMarkdownFile(path string) bool {
+func (s *GraphService) isMarkdownFile(path string) bool {
 	// If no extension, treat as markdown
 	if !strings.Contains(path, ".") {
 		return true
 	}
-	return strings.HasSuffix(path, ".md")
+	return s.fileService.types.isNote(path)
 }
 
 // GetFullGraph returns the complete knowledge graph (markdown files only)
@@ -42,7 +42,7 @@ func (s *GraphService) GetFullGraph() models.Graph {
 	// Build nodes from forwardIndex (only markdown files)
 	for filePath, links := range forwardIndex {
 		// Skip non-markdown files
-		if !isMarkdownFile(filePath) {
+		if !s.isMarkdownFile(filePath) {
 			continue
 		}
 
@@ -65,7 +65,7 @@ func (s *GraphService) GetFullGraph() models.Graph {
 			}
 
 			// Skip non-markdown targets
-			if !isMarkdownFile(targetPath) {
+			if !s.isMarkdownFile(targetPath) {
 				continue
 			}
 
@@ -113,7 +113,6 @@ func (s *GraphService) GetGraphStats() map[string]int {
 
 // getNodeLabel extracts a display label from a file path
 func (s *GraphService) getNodeLabel(filePath string) string {
-	// Remove .md extension and get base name
-	baseName := filepath.Base(filePath)
-	return strings.TrimSuffix(baseName, ".md")
+	// Remove the note extension and get base name
+	return s.fileService.types.trimNoteExt(filepath.Base(filePath))
 }
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kazuph/obails/models"
+)
+
+// GetUnusedAttachments lists the attachments (images, PDFs and other non-note files)
+// that no note references with a wiki link, embed or markdown link, largest first.
+// It relies on the link index, so the index should be up to date.
+func (s *LinkService) GetUnusedAttachments() ([]models.UnusedAttachment, error) {
+	vaultPath, err := s.fileService.getFullPath("")
+	if err != nil {
+		return nil, err
+	}
+
+	refs := s.attachmentRefs()
+	ignore := s.fileService.ignoreRules()
+	unused := []models.UnusedAttachment{}
+
+	err = filepath.WalkDir(vaultPath, func(fullPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip errors
+		}
+		relativePath, _ := filepath.Rel(vaultPath, fullPath)
+		if fullPath != vaultPath && (strings.HasPrefix(d.Name(), ".") || ignore.ignored(relativePath, d.IsDir())) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !s.isAttachment(d.Name()) {
+			return nil
+		}
+
+		if refs.references(relativePath) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		unused = append(unused, models.UnusedAttachment{
+			Path:       relativePath,
+			Name:       d.Name(),
+			FileType:   s.fileService.types.fileType(d.Name()),
+			Size:       info.Size(),
+			ModifiedAt: info.ModTime(),
+		})
+		return nil
+	})
+
+	sort.Slice(unused, func(i, j int) bool {
+		if unused[i].Size != unused[j].Size {
+			return unused[i].Size > unused[j].Size
+		}
+		return unused[i].Path < unused[j].Path
+	})
+	return unused, err
+}
+
+// TrashUnusedAttachments moves the given attachments to the vault trash, as
+// deleting them does. Each path must be an existing, unused attachment outside the
+// trash; nothing is moved otherwise.
+func (s *LinkService) TrashUnusedAttachments(paths []string) ([]models.TrashItem, error) {
+	refs := s.attachmentRefs()
+	for _, p := range paths {
+		rel, err := cleanRelativePath(p)
+		if err != nil {
+			return nil, err
+		}
+		if isSubPath(rel, trashFolder) || !s.isAttachment(rel) || refs.references(rel) {
+			return nil, fmt.Errorf("%s is not an unused attachment", p)
+		}
+		fullPath, err := s.fileService.getEntryPath(rel)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Lstat(fullPath)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("%s is not an unused attachment", p)
+		}
+	}
+
+	items := []models.TrashItem{}
+	for _, p := range paths {
+		item, err := s.fileService.deletePath(p)
+		if err != nil {
+			return items, err
+		}
+		items = append(items, *item)
+	}
+	return items, nil
+}
+
+// attachmentRefSet holds the link targets of all indexed notes
+type attachmentRefSet struct {
+	paths map[string]bool // Vault-relative slash paths of markdown links
+	names map[string]bool // Wiki link targets: a file name or a path suffix
+}
+
+// attachmentRefs collects the link targets from the index. Markdown link targets are
+// resolved against the linking note's folder, or the vault root if they start with "/".
+func (s *LinkService) attachmentRefs() *attachmentRefSet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	refs := &attachmentRefSet{paths: make(map[string]bool), names: make(map[string]bool)}
+	for _, links := range s.forwardIndex {
+		for _, link := range links {
+			refs.names[strings.TrimPrefix(filepath.ToSlash(link), "/")] = true
+		}
+	}
+	for notePath, targets := range s.markdownIndex {
+		noteDir := path.Dir(filepath.ToSlash(notePath))
+		for _, target := range targets {
+			if strings.HasPrefix(target, "/") {
+				refs.paths[path.Clean(strings.TrimPrefix(target, "/"))] = true
+			} else {
+				refs.paths[path.Join(noteDir, target)] = true
+			}
+		}
+	}
+	return refs
+}
+
+// references reports whether a vault-relative file is the target of any link
+func (r *attachmentRefSet) references(relativePath string) bool {
+	slashPath := filepath.ToSlash(relativePath)
+	if r.paths[slashPath] {
+		return true
+	}
+
+	// Wiki links may use the file name or any trailing part of the path
+	for suffix := slashPath; ; {
+		if r.names[suffix] {
+			return true
+		}
+		_, rest, ok := strings.Cut(suffix, "/")
+		if !ok {
+			return false
+		}
+		suffix = rest
+	}
+}
+
+// isAttachment reports whether a file is an attachment rather than a note or page
+func (s *LinkService) isAttachment(name string) bool {
+	switch s.fileService.types.fileType(name) {
+	case models.FileTypeImage, models.FileTypePDF, models.FileTypeAudio, models.FileTypeVideo, models.FileTypeOther:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLinkService_ParseMarkdownLinks(t *testing.T) {
+	ls, _, tmpDir := newTestLinkService(t)
+	defer os.RemoveAll(tmpDir)
+
+	content := `![shot](images/my%20shot.png) and [doc](<files/a b.pdf> "Title")
+[site](https://example.com) [mail](mailto:a@example.com) [section](note.md#Heading) [[wiki]]`
+
+	targets := ls.ParseMarkdownLinks(content)
+	expected := []string{"images/my shot.png", "files/a b.pdf", "note.md"}
+	if len(targets) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, targets)
+	}
+	for i := range expected {
+		if targets[i] != expected[i] {
+			t.Errorf("targets[%d] = %q, want %q", i, targets[i], expected[i])
+		}
+	}
+}
+
+func TestLinkService_UnusedAttachments(t *testing.T) {
+	ls, fs, tmpDir := newTestLinkService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs.CreateFile("notes/a.md", "![[used.png|300]]\n![[sub/by-suffix.pdf]]\n![rel](img/rel.jpg)\n[root](/files/root.zip)")
+	fs.CreateFile("attachments/used.png", "png")
+	fs.CreateFile("deep/sub/by-suffix.pdf", "pdf")
+	fs.CreateFile("notes/img/rel.jpg", "jpg")
+	fs.CreateFile("files/root.zip", "zip")
+	fs.CreateFile("attachments/orphan.png", "orphan!")
+	fs.CreateFile("img/rel.jpg", "not relative to the note")
+	fs.CreateFile("page.html", "<p>pages are not attachments</p>")
+	fs.CreateFile(".obails/cache.bin", "hidden")
+	ls.RebuildIndex()
+
+	unused, err := ls.GetUnusedAttachments()
+	if err != nil {
+		t.Fatalf("GetUnusedAttachments failed: %v", err)
+	}
+	if len(unused) != 2 {
+		t.Fatalf("Expected 2 unused attachments, got %+v", unused)
+	}
+	if unused[0].Path != filepath.Join("img", "rel.jpg") || unused[0].Size != 24 || unused[0].FileType != "image" {
+		t.Errorf("Unexpected first attachment: %+v", unused[0])
+	}
+	if unused[1].Path != filepath.Join("attachments", "orphan.png") || unused[1].ModifiedAt.IsZero() {
+		t.Errorf("Unexpected second attachment: %+v", unused[1])
+	}
+
+	t.Run("refuses used attachments", func(t *testing.T) {
+		_, err := ls.TrashUnusedAttachments([]string{"attachments/orphan.png", "attachments/used.png"})
+		if err == nil {
+			t.Fatal("Should refuse to trash a used attachment")
+		}
+		if !fs.FileExists("attachments/orphan.png") {
+			t.Error("Nothing should be trashed when a path is refused")
+		}
+	})
+
+	t.Run("refuses missing attachments", func(t *testing.T) {
+		_, err := ls.TrashUnusedAttachments([]string{"attachments/orphan.png", "attachments/gone.png"})
+		if err == nil {
+			t.Fatal("Should refuse to trash a missing attachment")
+		}
+		if !fs.FileExists("attachments/orphan.png") {
+			t.Error("Nothing should be trashed when a path is missing")
+		}
+	})
+
+	t.Run("moves unused attachments to trash", func(t *testing.T) {
+		var deleted []string
+		fs.onDelete(func(relativePath string) { deleted = append(deleted, relativePath) })
+
+		items, err := ls.TrashUnusedAttachments([]string{"attachments/orphan.png", "img/rel.jpg"})
+		if err != nil {
+			t.Fatalf("TrashUnusedAttachments failed: %v", err)
+		}
+		if len(deleted) != 2 {
+			t.Errorf("Expected the delete hooks to run for both attachments, got %v", deleted)
+		}
+		if len(items) != 2 || fs.FileExists("attachments/orphan.png") || fs.FileExists("img/rel.jpg") {
+			t.Errorf("Unexpected trash result: %+v", items)
+		}
+		if trash, _ := fs.ListTrash(); len(trash) != 2 {
+			t.Errorf("Expected 2 items in trash, got %+v", trash)
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -19,18 +20,29 @@ type LinkService struct {
 	forwardIndex map[string][]string
 	// Backlink index: file path -> files that link to it
 	backwardIndex map[string][]string
+	// Markdown link index: file path -> targets of ![alt](target) and [text](target)
+	markdownIndex map[string][]string
 
 	mu sync.RWMutex
 }
 
-// NewLinkService creates a new LinkService
+// NewLinkService creates a new LinkService. Notes restored from the trash are indexed
+// again.
 func NewLinkService(fileService *FileService, configService *ConfigService) *LinkService {
-	return &LinkService{
+	s := &LinkService{
 		fileService:   fileService,
 		configService: configService,
 		forwardIndex:  make(map[string][]string),
 		backwardIndex: make(map[string][]string),
+		markdownIndex: make(map[string][]string),
 	}
+	fileService.onRestore(s.pathRestored)
+	return s
+}
+
+// pathRestored rebuilds the index, since a restored note can resolve links of others
+func (s *LinkService) pathRestored(original string, restored string) {
+	s.RebuildIndex() // Best effort, the restore itself succeeded
 }
 
 // ParseLinks extracts all wiki-style links from content
@@ -59,37 +71,68 @@ func (s *LinkService) ParseLinks(content string) []string {
 	return links
 }
 
+// Match ![alt](target) or [text](target "title"), with an optional <...> around the target
+var markdownLinkRegex = regexp.MustCompile(`!?\[[^\]]*\]\(\s*(<[^>]+>|[^)\s]+)(?:\s+"[^"]*")?\s*\)`)
+
+// ParseMarkdownLinks extracts the local targets of markdown-style links and embeds,
+// URL-decoded and without heading references. External URLs are skipped.
+func (s *LinkService) ParseMarkdownLinks(content string) []string {
+	var targets []string
+	seen := make(map[string]bool)
+
+	for _, match := range markdownLinkRegex.FindAllStringSubmatch(content, -1) {
+		target := strings.TrimSuffix(strings.TrimPrefix(match[1], "<"), ">")
+		if strings.Contains(target, "://") || strings.HasPrefix(target, "mailto:") {
+			continue
+		}
+		if idx := strings.Index(target, "#"); idx != -1 {
+			target = target[:idx]
+		}
+		if decoded, err := url.PathUnescape(target); err == nil {
+			target = decoded
+		}
+		if target != "" && !seen[target] {
+			targets = append(targets, target)
+			seen[target] = true
+		}
+	}
+
+	return targets
+}
+
 // ResolveLink resolves a link text to a file path
 func (s *LinkService) ResolveLink(linkText string) (string, bool) {
 	vaultPath := s.configService.GetVaultPath()
+	ignore := s.fileService.ignoreRules()
 
-	// Try exact match with .md extension
-	exactPath := linkText
-	if !strings.HasSuffix(exactPath, ".md") {
-		exactPath += ".md"
-	}
-
-	fullPath := filepath.Join(vaultPath, exactPath)
-	if _, err := os.Stat(fullPath); err == nil {
+	// Try exact match, with a note extension if the link has none
+	if exactPath, ok := s.fileService.findNote(linkText); ok && !ignore.ignored(exactPath, false) {
 		return exactPath, true
 	}
 
 	// Search for file by name in the vault
 	var foundPath string
 	filepath.Walk(vaultPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() {
+		if err != nil {
 			return nil
 		}
 
-		// Skip hidden files
-		if strings.HasPrefix(info.Name(), ".") {
+		// Skip hidden and ignored files and directories
+		relPath, _ := filepath.Rel(vaultPath, path)
+		if info.IsDir() {
+			if path != vaultPath && (strings.HasPrefix(info.Name(), ".") || ignore.ignored(relPath, true)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(info.Name(), ".") || ignore.ignored(relPath, false) {
 			return nil
 		}
 
 		// Match by filename (without extension)
-		nameWithoutExt := strings.TrimSuffix(info.Name(), ".md")
+		nameWithoutExt := s.fileService.types.trimNoteExt(info.Name())
 		if nameWithoutExt == linkText {
-			foundPath, _ = filepath.Rel(vaultPath, path)
+			foundPath = relPath
 			return filepath.SkipAll
 		}
 
@@ -109,7 +152,7 @@ func (s *LinkService) GetBacklinks(relativePath string) []models.Backlink {
 	defer s.mu.RUnlock()
 
 	// Get the base name for matching
-	baseName := strings.TrimSuffix(filepath.Base(relativePath), ".md")
+	baseName := s.fileService.types.trimNoteExt(filepath.Base(relativePath))
 
 	var backlinks []models.Backlink
 	seen := make(map[string]bool)
@@ -123,7 +166,7 @@ func (s *LinkService) GetBacklinks(relativePath string) []models.Backlink {
 			}
 			seen[sourcePath] = true
 
-			sourceTitle := strings.TrimSuffix(filepath.Base(sourcePath), ".md")
+			sourceTitle := s.fileService.types.trimNoteExt(filepath.Base(sourcePath))
 			context := s.getBacklinkContext(sourcePath, baseName)
 
 			backlinks = append(backlinks, models.Backlink{
@@ -145,36 +188,37 @@ func (s *LinkService) RebuildIndex() error {
 	// Clear existing indices
 	s.forwardIndex = make(map[string][]string)
 	s.backwardIndex = make(map[string][]string)
+	s.markdownIndex = make(map[string][]string)
 
 	vaultPath := s.configService.GetVaultPath()
 	if vaultPath == "" {
 		return nil
 	}
+	ignore := s.fileService.ignoreRules()
 
 	return filepath.Walk(vaultPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil // Skip errors
 		}
 
-		// Skip directories and hidden files
+		// Skip directories and hidden or ignored files
+		relativePath, _ := filepath.Rel(vaultPath, path)
 		if info.IsDir() {
-			if strings.HasPrefix(info.Name(), ".") {
+			if strings.HasPrefix(info.Name(), ".") || ignore.ignored(relativePath, true) {
 				return filepath.SkipDir
 			}
 			return nil
 		}
 
-		if strings.HasPrefix(info.Name(), ".") {
+		if strings.HasPrefix(info.Name(), ".") || ignore.ignored(relativePath, false) {
 			return nil
 		}
 
-		// Only process markdown files
-		if !strings.HasSuffix(info.Name(), ".md") {
+		// Only process notes
+		if !s.fileService.types.isNote(info.Name()) {
 			return nil
 		}
 
-		relativePath, _ := filepath.Rel(vaultPath, path)
-
 		// Read file content
 		content, err := os.ReadFile(path)
 		if err != nil {
@@ -184,9 +228,10 @@ func (s *LinkService) RebuildIndex() error {
 		// Parse links
 		links := s.ParseLinks(string(content))
 		s.forwardIndex[relativePath] = links
+		s.markdownIndex[relativePath] = s.ParseMarkdownLinks(string(content))
 
 		// Build backward index
-		baseName := strings.TrimSuffix(info.Name(), ".md")
+		baseName := s.fileService.types.trimNoteExt(info.Name())
 		for _, link := range links {
 			s.backwardIndex[link] = append(s.backwardIndex[link], relativePath)
 			// Also index by resolved path if different
@@ -260,19 +305,7 @@ func (s *LinkService) countTotalLinks() int {
 }
 
 func (s *LinkService) resolveWithoutLock(linkText string) (string, bool) {
-	vaultPath := s.configService.GetVaultPath()
-
-	exactPath := linkText
-	if !strings.HasSuffix(exactPath, ".md") {
-		exactPath += ".md"
-	}
-
-	fullPath := filepath.Join(vaultPath, exactPath)
-	if _, err := os.Stat(fullPath); err == nil {
-		return exactPath, true
-	}
-
-	return "", false
+	return s.fileService.findNote(linkText)
 }
 
 func (s *LinkService) getBacklinkContext(sourcePath string, targetName string) string {
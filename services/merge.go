@@ -0,0 +1,129 @@
+package services
+
+import (
+	"slices"
+	"strings"
+)
+
+// Conflict markers used in merge results
+const (
+	conflictMarkerOurs   = "<<<<<<< Editor"
+	conflictMarkerSep    = "======="
+	conflictMarkerTheirs = ">>>>>>> Disk"
+)
+
+// maxDiffCells bounds the LCS table size; larger changed regions are treated as
+// replaced wholesale instead of diffed line by line
+const maxDiffCells = 4_000_000
+
+// mergeLines performs a line-based three-way merge of ours and theirs, both derived
+// from base. Regions changed on only one side (or identically on both) merge cleanly;
+// others are emitted between conflict markers. It returns the merged text and the
+// number of conflicting regions.
+func mergeLines(base string, ours string, theirs string) (string, int) {
+	baseLines := strings.Split(base, "\n")
+	ourLines := strings.Split(ours, "\n")
+	theirLines := strings.Split(theirs, "\n")
+
+	ourMatch := matchLines(baseLines, ourLines)
+	theirMatch := matchLines(baseLines, theirLines)
+
+	var result []string
+	conflicts := 0
+	i, a, b := 0, 0, 0
+
+	for {
+		// Copy lines unchanged on both sides
+		for i < len(baseLines) && ourMatch[i] == a && theirMatch[i] == b {
+			result = append(result, baseLines[i])
+			i, a, b = i+1, a+1, b+1
+		}
+		if i == len(baseLines) && a == len(ourLines) && b == len(theirLines) {
+			break
+		}
+
+		// The changed region ends at the next base line both sides kept
+		k := i
+		for k < len(baseLines) && (ourMatch[k] < 0 || theirMatch[k] < 0) {
+			k++
+		}
+		aEnd, bEnd := len(ourLines), len(theirLines)
+		if k < len(baseLines) {
+			aEnd, bEnd = ourMatch[k], theirMatch[k]
+		}
+
+		baseChunk, ourChunk, theirChunk := baseLines[i:k], ourLines[a:aEnd], theirLines[b:bEnd]
+		switch {
+		case slices.Equal(ourChunk, baseChunk):
+			result = append(result, theirChunk...)
+		case slices.Equal(theirChunk, baseChunk), slices.Equal(ourChunk, theirChunk):
+			result = append(result, ourChunk...)
+		default:
+			conflicts++
+			result = append(result, conflictMarkerOurs)
+			result = append(result, ourChunk...)
+			result = append(result, conflictMarkerSep)
+			result = append(result, theirChunk...)
+			result = append(result, conflictMarkerTheirs)
+		}
+
+		i, a, b = k, aEnd, bEnd
+	}
+
+	return strings.Join(result, "\n"), conflicts
+}
+
+// matchLines returns, for each line of base, the index of the matching line in other
+// according to a longest common subsequence, or -1 if the line was removed or changed
+func matchLines(base []string, other []string) []int {
+	match := make([]int, len(base))
+	for i := range match {
+		match[i] = -1
+	}
+
+	// Common prefix and suffix
+	prefix := 0
+	for prefix < len(base) && prefix < len(other) && base[prefix] == other[prefix] {
+		match[prefix] = prefix
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(base)-prefix && suffix < len(other)-prefix &&
+		base[len(base)-1-suffix] == other[len(other)-1-suffix] {
+		match[len(base)-1-suffix] = len(other) - 1 - suffix
+		suffix++
+	}
+
+	x := base[prefix : len(base)-suffix]
+	y := other[prefix : len(other)-suffix]
+	if len(x) == 0 || len(y) == 0 || len(x)*len(y) > maxDiffCells {
+		return match
+	}
+
+	// lcs[i][j] = LCS length of x[i:] and y[j:]
+	width := len(y) + 1
+	lcs := make([]int32, (len(x)+1)*width)
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i*width+j] = lcs[(i+1)*width+j+1] + 1
+			} else {
+				lcs[i*width+j] = max(lcs[(i+1)*width+j], lcs[i*width+j+1])
+			}
+		}
+	}
+
+	for i, j := 0, 0; i < len(x) && j < len(y); {
+		switch {
+		case x[i] == y[j]:
+			match[prefix+i] = prefix + j
+			i, j = i+1, j+1
+		case lcs[(i+1)*width+j] >= lcs[i*width+j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return match
+}
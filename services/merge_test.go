@@ -0,0 +1,64 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMergeLines(t *testing.T) {
+	base := "a\nb\nc\nd\ne\n"
+
+	tests := []struct {
+		name      string
+		ours      string
+		theirs    string
+		expected  string
+		conflicts int
+	}{
+		{"no changes", base, base, base, 0},
+		{"only ours changed", "a\nB\nc\nd\ne\n", base, "a\nB\nc\nd\ne\n", 0},
+		{"only theirs changed", base, "a\nb\nc\nD\ne\n", "a\nb\nc\nD\ne\n", 0},
+		{"separate regions", "a\nB\nc\nd\ne\n", "a\nb\nc\nD\ne\n", "a\nB\nc\nD\ne\n", 0},
+		{"same change on both sides", "a\nX\nc\nd\ne\n", "a\nX\nc\nd\ne\n", "a\nX\nc\nd\ne\n", 0},
+		{"insertions in different places", "a\nnew1\nb\nc\nd\ne\n", "a\nb\nc\nd\nnew2\ne\n", "a\nnew1\nb\nc\nd\nnew2\ne\n", 0},
+		{"deletion and edit elsewhere", "a\nc\nd\ne\n", "a\nb\nc\nd\nE\n", "a\nc\nd\nE\n", 0},
+		{"appends on both sides", base + "ours", base + "theirs", "a\nb\nc\nd\ne\n" +
+			conflictMarkerOurs + "\nours\n" + conflictMarkerSep + "\ntheirs\n" + conflictMarkerTheirs, 1},
+		{"same line changed differently", "a\nB1\nc\nd\ne\n", "a\nB2\nc\nd\ne\n", "a\n" +
+			conflictMarkerOurs + "\nB1\n" + conflictMarkerSep + "\nB2\n" + conflictMarkerTheirs + "\nc\nd\ne\n", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, conflicts := mergeLines(base, tt.ours, tt.theirs)
+			if conflicts != tt.conflicts {
+				t.Errorf("conflicts = %d, want %d", conflicts, tt.conflicts)
+			}
+			if merged != tt.expected {
+				t.Errorf("merged =\n%s\nwant\n%s", merged, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMergeLines_LargeNote(t *testing.T) {
+	var lines []string
+	for i := 0; i < 5000; i++ {
+		lines = append(lines, strings.Repeat("x", i%7)+"line")
+	}
+	base := strings.Join(lines, "\n")
+
+	ourLines := append([]string{}, lines...)
+	ourLines[10] = "ours"
+	theirLines := append([]string{}, lines...)
+	theirLines[4990] = "theirs"
+
+	merged, conflicts := mergeLines(base, strings.Join(ourLines, "\n"), strings.Join(theirLines, "\n"))
+	if conflicts != 0 {
+		t.Fatalf("Expected clean merge, got %d conflicts", conflicts)
+	}
+	mergedLines := strings.Split(merged, "\n")
+	if mergedLines[10] != "ours" || mergedLines[4990] != "theirs" || len(mergedLines) != 5000 {
+		t.Error("Merged content mismatch")
+	}
+}
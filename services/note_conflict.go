@@ -0,0 +1,133 @@
+package services
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/kazuph/obails/models"
+)
+
+// Limits of the remembered versions: per note, and notes; the least recently used
+// note is forgotten first
+const (
+	maxNoteBases     = 8
+	maxNoteBaseNotes = 64
+)
+
+// noteBaseCache remembers recently loaded or saved note contents by hash, so a save
+// can find the version the editor started from
+type noteBaseCache struct {
+	mu      sync.Mutex
+	entries map[string][]noteBase // path -> versions, oldest first
+	recent  []string              // paths, least recently used first
+}
+
+type noteBase struct {
+	hash    string
+	content string
+}
+
+func newNoteBaseCache() *noteBaseCache {
+	return &noteBaseCache{entries: make(map[string][]noteBase)}
+}
+
+// clear forgets all versions, e.g. when another vault is opened
+func (c *noteBaseCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string][]noteBase)
+	c.recent = nil
+}
+
+func (c *noteBaseCache) remember(relativePath string, content string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hash := contentHash(content)
+	bases := c.entries[relativePath]
+	for i, base := range bases {
+		if base.hash == hash {
+			bases = append(bases[:i], bases[i+1:]...)
+			break
+		}
+	}
+	bases = append(bases, noteBase{hash: hash, content: content})
+	if len(bases) > maxNoteBases {
+		bases = bases[len(bases)-maxNoteBases:]
+	}
+	c.entries[relativePath] = bases
+
+	c.recent = slices.DeleteFunc(c.recent, func(p string) bool { return p == relativePath })
+	c.recent = append(c.recent, relativePath)
+	for len(c.recent) > maxNoteBaseNotes {
+		delete(c.entries, c.recent[0])
+		c.recent = c.recent[1:]
+	}
+}
+
+func (c *noteBaseCache) lookup(relativePath string, hash string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, base := range c.entries[relativePath] {
+		if base.hash == hash {
+			return base.content, true
+		}
+	}
+	return "", false
+}
+
+// SaveNoteMerged saves a note edited from the base version the editor loaded. If the
+// file changed on disk since then, the external changes are merged line by line with
+// the editor's. A clean merge is written and returned with status "merged"; otherwise
+// nothing is written and the result carries both versions and a conflict-marked merge.
+// To resolve, save the resolved content again with the returned Version as base.
+func (s *NoteService) SaveNoteMerged(relativePath string, content string, base models.NoteVersion) (*models.SaveResult, error) {
+	disk, err := s.fileService.ReadFile(relativePath)
+	exists := err == nil
+	if err != nil && s.fileService.FileExists(relativePath) {
+		return nil, err
+	}
+
+	// Nothing changed on disk (or nothing to compare against): plain save
+	if !exists || base.Hash == "" || contentHash(disk) == base.Hash || disk == content {
+		if err := s.SaveNote(relativePath, content); err != nil {
+			return nil, err
+		}
+		return s.saveResult(relativePath, models.SaveStatusSaved, content)
+	}
+
+	// With an unknown base, every difference between the two versions is a conflict
+	baseContent, _ := s.bases.lookup(relativePath, base.Hash)
+
+	merged, conflicts := mergeLines(baseContent, content, disk)
+	if conflicts == 0 {
+		if err := s.SaveNote(relativePath, merged); err != nil {
+			return nil, err
+		}
+		return s.saveResult(relativePath, models.SaveStatusMerged, merged)
+	}
+
+	result, err := s.saveResult(relativePath, models.SaveStatusConflict, merged)
+	if err != nil {
+		return nil, err
+	}
+	result.Version.Hash = contentHash(disk)
+	result.Base = baseContent
+	result.Ours = content
+	result.Theirs = disk
+	result.Conflicts = conflicts
+	return result, nil
+}
+
+func (s *NoteService) saveResult(relativePath string, status string, content string) (*models.SaveResult, error) {
+	info, err := s.fileService.GetFileInfo(relativePath)
+	if err != nil {
+		return nil, err
+	}
+	return &models.SaveResult{
+		Status:  status,
+		Content: content,
+		Version: models.NoteVersion{ModifiedAt: info.ModifiedAt, Hash: contentHash(content)},
+	}, nil
+}
@@ -0,0 +1,446 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kazuph/obails/models"
+)
+
+// historyFolder holds note snapshots. Contents are stored once per hash, gzip
+// compressed, in objects/; each note has an index of its versions in notes/.
+const historyFolder = ".obails/history"
+
+// noteHistoryIndex lists the recorded versions of one note, oldest first
+type noteHistoryIndex struct {
+	Path     string                `json:"path"`
+	NextID   int                   `json:"nextId"`
+	Versions []models.NoteSnapshot `json:"versions"`
+}
+
+// GetNoteHistory returns the recorded versions of a note, newest first
+func (s *NoteService) GetNoteHistory(relativePath string) ([]models.NoteSnapshot, error) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	index, err := s.loadHistoryIndex(relativePath)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]models.NoteSnapshot, 0, len(index.Versions))
+	for i := len(index.Versions) - 1; i >= 0; i-- {
+		versions = append(versions, index.Versions[i])
+	}
+	return versions, nil
+}
+
+// GetNoteVersion returns the content of a recorded version of a note
+func (s *NoteService) GetNoteVersion(relativePath string, id int) (string, error) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	return s.versionContent(relativePath, id)
+}
+
+// DiffNoteVersions compares two versions of a note line by line. An id of 0 stands
+// for the current content of the note.
+func (s *NoteService) DiffNoteVersions(relativePath string, fromID int, toID int) ([]models.DiffLine, error) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	from, err := s.versionContent(relativePath, fromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.versionContent(relativePath, toID)
+	if err != nil {
+		return nil, err
+	}
+	return diffLines(from, to), nil
+}
+
+// RestoreNoteVersion replaces a note with a recorded version. The content being
+// replaced is recorded first, so the restore can be undone.
+func (s *NoteService) RestoreNoteVersion(relativePath string, id int) (*models.Note, error) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	content, err := s.versionContent(relativePath, id)
+	if err != nil {
+		return nil, err
+	}
+	if current, err := s.fileService.ReadFile(relativePath); err == nil {
+		if err := s.recordSnapshot(relativePath, current, models.SnapshotSourceSave, true); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.fileService.WriteFile(relativePath, content); err != nil {
+		return nil, err
+	}
+	s.bases.remember(relativePath, content)
+	if err := s.recordSnapshot(relativePath, content, models.SnapshotSourceRestore, true); err != nil {
+		return nil, err
+	}
+	s.notifySaved(relativePath)
+
+	return s.GetNote(relativePath)
+}
+
+// PruneHistory drops snapshots older than the retention period and deletes stored
+// contents no version refers to anymore. It returns the number of dropped snapshots.
+func (s *NoteService) PruneHistory() (int, error) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	notesDir, err := s.fileService.getFullPath(filepath.Join(historyFolder, "notes"))
+	if err != nil {
+		return 0, err
+	}
+	entries, err := os.ReadDir(notesDir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	pruned := 0
+	referenced := make(map[string]bool)
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(notesDir, entry.Name()))
+		if err != nil {
+			return pruned, err
+		}
+		var index noteHistoryIndex
+		if err := json.Unmarshal(data, &index); err != nil {
+			continue
+		}
+
+		before := len(index.Versions)
+		s.expireVersions(&index)
+		if len(index.Versions) != before {
+			pruned += before - len(index.Versions)
+			if err := s.saveHistoryIndex(&index); err != nil {
+				return pruned, err
+			}
+		}
+		for _, version := range index.Versions {
+			referenced[version.Hash] = true
+		}
+	}
+
+	objectsDir, err := s.fileService.getFullPath(filepath.Join(historyFolder, "objects"))
+	if err != nil {
+		return pruned, err
+	}
+	err = filepath.WalkDir(objectsDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		hash := filepath.Base(filepath.Dir(path)) + strings.TrimSuffix(d.Name(), ".gz")
+		if !referenced[hash] {
+			os.Remove(path)
+		}
+		return nil
+	})
+	return pruned, err
+}
+
+// historyPathMoved moves the history of a moved note, or of the notes in a moved
+// folder, to the new path. History left at the new path by a deleted note is replaced.
+func (s *NoteService) historyPathMoved(from string, to string) {
+	from, to = filepath.ToSlash(from), filepath.ToSlash(to)
+
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	notesDir, err := s.fileService.getFullPath(filepath.Join(historyFolder, "notes"))
+	if err != nil {
+		return
+	}
+	entries, err := os.ReadDir(notesDir)
+	if err != nil {
+		return // No history yet
+	}
+
+	for _, entry := range entries {
+		oldPath := filepath.Join(notesDir, entry.Name())
+		data, err := os.ReadFile(oldPath)
+		if err != nil {
+			continue
+		}
+		var index noteHistoryIndex
+		if err := json.Unmarshal(data, &index); err != nil {
+			continue
+		}
+		moved, ok := movedPath(index.Path, from, to)
+		if !ok {
+			continue
+		}
+
+		index.Path = moved
+		for i := range index.Versions {
+			index.Versions[i].Path = moved
+		}
+		if err := s.saveHistoryIndex(&index); err != nil {
+			continue // Best effort, the history stays at the old path
+		}
+		os.Remove(oldPath)
+	}
+}
+
+// captureExternalChange records the note on disk before it is overwritten, if its
+// content was changed outside the app (it doesn't match a version loaded or saved here)
+func (s *NoteService) captureExternalChange(relativePath string, content string) {
+	if !s.configService.GetHistory().Enabled {
+		return
+	}
+
+	disk, err := s.fileService.ReadFile(relativePath)
+	if err != nil || disk == content {
+		return
+	}
+	if _, known := s.bases.lookup(relativePath, contentHash(disk)); known {
+		return
+	}
+
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+	s.recordSnapshot(relativePath, disk, models.SnapshotSourceExternal, true)
+}
+
+// recordSaveSnapshot records saved content, at most once per configured interval
+func (s *NoteService) recordSaveSnapshot(relativePath string, content string) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+	s.recordSnapshot(relativePath, content, models.SnapshotSourceSave, false)
+}
+
+// recordSnapshot adds a version to a note's history unless it matches the latest one.
+// Unless forced, no version is added within the configured interval of the previous.
+// The caller must hold historyMu.
+func (s *NoteService) recordSnapshot(relativePath string, content string, source string, force bool) error {
+	config := s.configService.GetHistory()
+	if !config.Enabled {
+		return nil
+	}
+
+	index, err := s.loadHistoryIndex(relativePath)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	hash := contentHash(content)
+	if n := len(index.Versions); n > 0 {
+		last := index.Versions[n-1]
+		if last.Hash == hash {
+			return nil
+		}
+		interval := time.Duration(config.IntervalMinutes) * time.Minute
+		if !force && now.Sub(last.CreatedAt) < interval {
+			return nil
+		}
+	}
+
+	if err := s.writeHistoryObject(hash, content); err != nil {
+		return err
+	}
+
+	index.NextID++
+	index.Versions = append(index.Versions, models.NoteSnapshot{
+		ID:        index.NextID,
+		Path:      index.Path,
+		Hash:      hash,
+		Size:      len(content),
+		CreatedAt: now,
+		Source:    source,
+	})
+	s.expireVersions(index)
+	return s.saveHistoryIndex(index)
+}
+
+// expireVersions drops versions older than the retention period
+func (s *NoteService) expireVersions(index *noteHistoryIndex) {
+	days := s.configService.GetHistory().RetentionDays
+	if days == 0 {
+		return
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -days)
+	kept := index.Versions[:0]
+	for _, version := range index.Versions {
+		if version.CreatedAt.After(cutoff) {
+			kept = append(kept, version)
+		}
+	}
+	index.Versions = kept
+}
+
+// versionContent returns the content of a version, or of the note on disk for id 0
+func (s *NoteService) versionContent(relativePath string, id int) (string, error) {
+	if id == 0 {
+		return s.fileService.ReadFile(relativePath)
+	}
+
+	index, err := s.loadHistoryIndex(relativePath)
+	if err != nil {
+		return "", err
+	}
+	for _, version := range index.Versions {
+		if version.ID == id {
+			return s.readHistoryObject(version.Hash)
+		}
+	}
+	return "", fmt.Errorf("version %d of %s not found", id, relativePath)
+}
+
+func (s *NoteService) loadHistoryIndex(relativePath string) (*noteHistoryIndex, error) {
+	rel, err := cleanRelativePath(relativePath)
+	if err != nil {
+		return nil, err
+	}
+	index := &noteHistoryIndex{Path: filepath.ToSlash(rel)}
+
+	indexPath, err := s.historyIndexPath(index.Path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(indexPath)
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func (s *NoteService) saveHistoryIndex(index *noteHistoryIndex) error {
+	indexPath, err := s.historyIndexPath(index.Path)
+	if err != nil {
+		return err
+	}
+	if len(index.Versions) == 0 {
+		if err := os.Remove(indexPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(indexPath), 0755); err != nil {
+		return err
+	}
+	return atomicWriteFile(indexPath, data, 0644)
+}
+
+// historyIndexPath names the index file after a hash of the note path
+func (s *NoteService) historyIndexPath(notePath string) (string, error) {
+	sum := sha256.Sum256([]byte(notePath))
+	return s.fileService.getFullPath(filepath.Join(historyFolder, "notes", hex.EncodeToString(sum[:8])+".json"))
+}
+
+func (s *NoteService) historyObjectPath(hash string) (string, error) {
+	if len(hash) < 3 {
+		return "", fmt.Errorf("invalid content hash %q", hash)
+	}
+	return s.fileService.getFullPath(filepath.Join(historyFolder, "objects", hash[:2], hash[2:]+".gz"))
+}
+
+// writeHistoryObject stores content compressed, once per hash
+func (s *NoteService) writeHistoryObject(hash string, content string) error {
+	objectPath, err := s.historyObjectPath(hash)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(objectPath); err == nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(content)); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(objectPath), 0755); err != nil {
+		return err
+	}
+	return atomicWriteFile(objectPath, buf.Bytes(), 0644)
+}
+
+func (s *NoteService) readHistoryObject(hash string) (string, error) {
+	objectPath, err := s.historyObjectPath(hash)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(objectPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return "", err
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// diffLines returns a line diff turning from into to
+func diffLines(from string, to string) []models.DiffLine {
+	fromLines := splitDiffLines(from)
+	toLines := splitDiffLines(to)
+	match := matchLines(fromLines, toLines)
+
+	diff := []models.DiffLine{}
+	j := 0
+	for i, line := range fromLines {
+		if match[i] < 0 {
+			diff = append(diff, models.DiffLine{Op: models.DiffOpDelete, Text: line, OldLine: i + 1})
+			continue
+		}
+		for ; j < match[i]; j++ {
+			diff = append(diff, models.DiffLine{Op: models.DiffOpInsert, Text: toLines[j], NewLine: j + 1})
+		}
+		diff = append(diff, models.DiffLine{Op: models.DiffOpEqual, Text: line, OldLine: i + 1, NewLine: j + 1})
+		j++
+	}
+	for ; j < len(toLines); j++ {
+		diff = append(diff, models.DiffLine{Op: models.DiffOpInsert, Text: toLines[j], NewLine: j + 1})
+	}
+	return diff
+}
+
+// splitDiffLines splits content into lines, ignoring the final newline
+func splitDiffLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kazuph/obails/models"
+)
+
+func newTestHistoryNoteService(t *testing.T) (*NoteService, *FileService, string) {
+	t.Helper()
+	ns, fs, tmpDir := newTestNoteService(t)
+	ns.configService.config.History = models.HistoryConfig{Enabled: true}
+	return ns, fs, tmpDir
+}
+
+func TestNoteService_History(t *testing.T) {
+	ns, fs, tmpDir := newTestHistoryNoteService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs.CreateFile("note.md", "one\n")
+	ns.GetNote("note.md")
+
+	ns.SaveNote("note.md", "one\ntwo\n")
+	ns.SaveNote("note.md", "one\ntwo\n") // Unchanged, not recorded again
+	ns.SaveNote("note.md", "one\nthree\n")
+
+	versions, err := ns.GetNoteHistory("note.md")
+	if err != nil {
+		t.Fatalf("GetNoteHistory failed: %v", err)
+	}
+	if len(versions) != 2 || versions[0].ID != 2 || versions[1].ID != 1 {
+		t.Fatalf("Unexpected versions: %+v", versions)
+	}
+
+	content, err := ns.GetNoteVersion("note.md", 1)
+	if err != nil || content != "one\ntwo\n" {
+		t.Errorf("GetNoteVersion = %q, %v", content, err)
+	}
+
+	t.Run("diff", func(t *testing.T) {
+		diff, err := ns.DiffNoteVersions("note.md", 1, 2)
+		if err != nil {
+			t.Fatalf("DiffNoteVersions failed: %v", err)
+		}
+		expected := []models.DiffLine{
+			{Op: models.DiffOpEqual, Text: "one", OldLine: 1, NewLine: 1},
+			{Op: models.DiffOpDelete, Text: "two", OldLine: 2},
+			{Op: models.DiffOpInsert, Text: "three", NewLine: 2},
+		}
+		if len(diff) != len(expected) {
+			t.Fatalf("Unexpected diff: %+v", diff)
+		}
+		for i := range expected {
+			if diff[i] != expected[i] {
+				t.Errorf("diff[%d] = %+v, want %+v", i, diff[i], expected[i])
+			}
+		}
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		note, err := ns.RestoreNoteVersion("note.md", 1)
+		if err != nil {
+			t.Fatalf("RestoreNoteVersion failed: %v", err)
+		}
+		if note.Content != "one\ntwo\n" {
+			t.Errorf("Restored content = %q", note.Content)
+		}
+		versions, _ := ns.GetNoteHistory("note.md")
+		if versions[0].Source != models.SnapshotSourceRestore || versions[1].Hash != contentHash("one\nthree\n") {
+			t.Errorf("Unexpected versions after restore: %+v", versions)
+		}
+	})
+
+	t.Run("external change is captured before overwrite", func(t *testing.T) {
+		os.WriteFile(filepath.Join(tmpDir, "note.md"), []byte("synced from phone\n"), 0644)
+		ns.SaveNote("note.md", "editor\n")
+
+		versions, _ := ns.GetNoteHistory("note.md")
+		found := false
+		for _, version := range versions {
+			if version.Source == models.SnapshotSourceExternal {
+				content, _ := ns.GetNoteVersion("note.md", version.ID)
+				found = content == "synced from phone\n"
+			}
+		}
+		if !found {
+			t.Errorf("External content not captured: %+v", versions)
+		}
+	})
+}
+
+func TestNoteService_HistoryFollowsMoves(t *testing.T) {
+	ns, fs, tmpDir := newTestHistoryNoteService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs.CreateFile("projects/note.md", "one\n")
+	ns.SaveNote("projects/note.md", "two\n")
+
+	if err := fs.MoveFile("projects/note.md", "projects/renamed.md"); err != nil {
+		t.Fatalf("MoveFile failed: %v", err)
+	}
+	if err := fs.MoveFile("projects", "archive"); err != nil {
+		t.Fatalf("MoveFile failed: %v", err)
+	}
+
+	versions, err := ns.GetNoteHistory("archive/renamed.md")
+	if err != nil || len(versions) != 2 || versions[0].Path != "archive/renamed.md" {
+		t.Fatalf("Expected the history at the new path, got %+v, %v", versions, err)
+	}
+	if content, _ := ns.GetNoteVersion("archive/renamed.md", versions[0].ID); content != "two\n" {
+		t.Errorf("Unexpected version content %q", content)
+	}
+	if old, _ := ns.GetNoteHistory("projects/note.md"); len(old) != 0 {
+		t.Errorf("Expected no history at the old path, got %+v", old)
+	}
+}
+
+func TestNoteService_HistoryInterval(t *testing.T) {
+	ns, _, tmpDir := newTestHistoryNoteService(t)
+	defer os.RemoveAll(tmpDir)
+	ns.configService.config.History.IntervalMinutes = 5
+
+	ns.SaveNote("note.md", "a")
+	ns.SaveNote("note.md", "b")
+	ns.SaveNote("note.md", "c")
+
+	versions, _ := ns.GetNoteHistory("note.md")
+	if len(versions) != 1 {
+		t.Errorf("Expected one snapshot within the interval, got %+v", versions)
+	}
+}
+
+func TestNoteService_PruneHistory(t *testing.T) {
+	ns, _, tmpDir := newTestHistoryNoteService(t)
+	defer os.RemoveAll(tmpDir)
+
+	ns.SaveNote("note.md", "old")
+	ns.SaveNote("note.md", "new")
+
+	// Backdate the first snapshot
+	ns.historyMu.Lock()
+	index, _ := ns.loadHistoryIndex("note.md")
+	index.Versions[0].CreatedAt = time.Now().AddDate(0, 0, -10)
+	ns.saveHistoryIndex(index)
+	ns.historyMu.Unlock()
+
+	ns.configService.config.History.RetentionDays = 7
+	pruned, err := ns.PruneHistory()
+	if err != nil {
+		t.Fatalf("PruneHistory failed: %v", err)
+	}
+
+	versions, _ := ns.GetNoteHistory("note.md")
+	if pruned != 1 || len(versions) != 1 || versions[0].Hash != contentHash("new") {
+		t.Errorf("Unexpected history after prune (%d): %+v", pruned, versions)
+	}
+
+	objectPath, _ := ns.historyObjectPath(contentHash("old"))
+	if _, err := os.Stat(objectPath); !os.IsNotExist(err) {
+		t.Error("Unreferenced content should be deleted")
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	diff := diffLines("", "a\nb\n")
+	if len(diff) != 2 || diff[0].Op != models.DiffOpInsert || diff[1].NewLine != 2 {
+		t.Errorf("Unexpected diff: %+v", diff)
+	}
+}
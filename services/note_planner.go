@@ -0,0 +1,332 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kazuph/obails/models"
+)
+
+// Match Day Planner entries: "- [ ] 09:00 text", "- 09:00 - 10:30 text" or "- [x] 9:00-9:45 text"
+var plannerLineRegex = regexp.MustCompile(`^([-*+]\s+)(?:\[(.)\]\s+)?(\d{1,2}:\d{2})(?:\s*-\s*(\d{1,2}:\d{2}))?\s+(.*?)\s*$`)
+
+// Match "9:05" or "09:05"
+var clockRegex = regexp.MustCompile(`^(\d{1,2}):(\d{2})$`)
+
+// GetPlanner returns the Day Planner blocks of a daily note in note order
+func (s *NoteService) GetPlanner(dateStr string) ([]models.PlannerBlock, error) {
+	note, err := s.GetDailyNote(dateStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.parsePlanner(note.Content), nil
+}
+
+// GetPlannerNowNext returns the block in progress and the next upcoming block of today's planner
+func (s *NoteService) GetPlannerNowNext() (*models.PlannerStatus, error) {
+	now := time.Now()
+	status := &models.PlannerStatus{Date: now.Format("2006-01-02")}
+
+	blocks, err := s.GetPlanner(status.Date)
+	if err != nil {
+		// No daily note yet means nothing is planned
+		return status, nil
+	}
+
+	status.Now, status.Next = plannerNowNext(blocks, now.Hour()*60+now.Minute())
+	return status, nil
+}
+
+// AddPlannerBlock adds a block to the Day Planner of a daily note (created if missing),
+// keeping blocks ordered by start time. end may be empty for an open-ended block.
+func (s *NoteService) AddPlannerBlock(dateStr string, start string, end string, text string) ([]models.PlannerBlock, error) {
+	startMin, endMin, err := parsePlannerRange(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("planner block text is empty")
+	}
+
+	note, err := s.getOrCreateDailyNote(dateStr)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(note.Content, "\n")
+	entry := formatPlannerLine("- ", " ", startMin, endMin, text)
+	lines = s.insertPlannerLines(lines, []string{entry}, startMin)
+
+	return s.savePlanner(note.Path, lines)
+}
+
+// MovePlannerBlock moves the block at the given line to a new start time. A written end
+// time moves along so the block keeps its duration; an end time that isn't after the
+// start is dropped, as the block is open-ended.
+func (s *NoteService) MovePlannerBlock(dateStr string, line int, start string) ([]models.PlannerBlock, error) {
+	startMin, err := parseClock(start)
+	if err != nil {
+		return nil, err
+	}
+
+	note, lines, matches, err := s.plannerLineAt(dateStr, line)
+	if err != nil {
+		return nil, err
+	}
+
+	oldStart, oldEnd, err := plannerEntryRange(matches)
+	if err != nil {
+		return nil, err
+	}
+	endMin := -1
+	if oldEnd >= 0 {
+		endMin = startMin + oldEnd - oldStart
+		if endMin > 24*60 {
+			return nil, fmt.Errorf("block would end after midnight")
+		}
+	}
+
+	// Take the block out together with its nested lines and re-insert it in order
+	last := line
+	for last < len(lines) && isIndented(lines[last]) {
+		last++
+	}
+	block := append([]string{}, lines[line-1:last]...)
+	block[0] = formatPlannerLine(matches[1], matches[2], startMin, endMin, matches[5])
+	lines = append(lines[:line-1], lines[last:]...)
+	lines = s.insertPlannerLines(lines, block, startMin)
+
+	return s.savePlanner(note.Path, lines)
+}
+
+// ResizePlannerBlock changes the end time of the block at the given line.
+// An empty end removes the written end time.
+func (s *NoteService) ResizePlannerBlock(dateStr string, line int, end string) ([]models.PlannerBlock, error) {
+	note, lines, matches, err := s.plannerLineAt(dateStr, line)
+	if err != nil {
+		return nil, err
+	}
+
+	startMin, endMin, err := parsePlannerRange(matches[3], end)
+	if err != nil {
+		return nil, err
+	}
+
+	lines[line-1] = formatPlannerLine(matches[1], matches[2], startMin, endMin, matches[5])
+	return s.savePlanner(note.Path, lines)
+}
+
+// parsePlanner extracts the time blocks from the Day Planner section. Blocks without a
+// written end time last until the next block starts, at most the default duration.
+func (s *NoteService) parsePlanner(content string) []models.PlannerBlock {
+	var blocks []models.PlannerBlock
+
+	lines := strings.Split(content, "\n")
+	start, end, ok := findSection(lines, s.configService.GetPlannerSection())
+	if !ok {
+		return blocks
+	}
+
+	for i := start; i < end; i++ {
+		matches := plannerLineRegex.FindStringSubmatch(lines[i])
+		if matches == nil {
+			continue
+		}
+
+		startMin, endMin, err := plannerEntryRange(matches)
+		if err != nil {
+			continue
+		}
+
+		block := models.PlannerBlock{
+			Line:   i + 1,
+			Start:  formatClock(startMin),
+			Text:   matches[5],
+			IsTodo: matches[2] != "",
+			Done:   strings.EqualFold(matches[2], "x"),
+		}
+		if endMin >= 0 {
+			block.End = formatClock(endMin)
+			block.ExplicitEnd = true
+			block.Duration = endMin - startMin
+		}
+		blocks = append(blocks, block)
+	}
+
+	// Fill in the end of open-ended blocks
+	starts := make([]int, len(blocks))
+	for i, block := range blocks {
+		starts[i], _ = parseClock(block.Start)
+	}
+	sorted := append([]int{}, starts...)
+	sort.Ints(sorted)
+
+	defaultDuration := s.configService.GetPlannerDefaultDuration()
+	for i := range blocks {
+		if blocks[i].ExplicitEnd {
+			continue
+		}
+		endMin := min(starts[i]+defaultDuration, 24*60)
+		if idx := sort.SearchInts(sorted, starts[i]+1); idx < len(sorted) {
+			endMin = min(endMin, sorted[idx])
+		}
+		blocks[i].End = formatClock(endMin)
+		blocks[i].Duration = endMin - starts[i]
+	}
+
+	return blocks
+}
+
+// insertPlannerLines inserts block lines into the Day Planner section before the first
+// block starting later, or after the section's list
+func (s *NoteService) insertPlannerLines(lines []string, block []string, startMin int) []string {
+	section := s.configService.GetPlannerSection()
+	start, end, ok := findSection(lines, section)
+	if !ok {
+		return s.insertSection(lines, section, block)
+	}
+
+	for i := start; i < end; i++ {
+		matches := plannerLineRegex.FindStringSubmatch(lines[i])
+		if matches == nil {
+			continue
+		}
+		if m, err := parseClock(matches[3]); err == nil && m > startMin {
+			return insertLines(lines, i, block)
+		}
+	}
+
+	at := start
+	if listStart, listEnd := findListBlock(lines[start:end]); listStart >= 0 {
+		at = start + listEnd
+	} else if at < len(lines) && strings.TrimSpace(lines[at]) != "" {
+		block = append(block, "")
+	}
+	return insertLines(lines, at, block)
+}
+
+// plannerLineAt loads a daily note and returns the planner entry at the given 1-based line
+func (s *NoteService) plannerLineAt(dateStr string, line int) (*models.Note, []string, []string, error) {
+	note, err := s.GetDailyNote(dateStr)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	for _, block := range s.parsePlanner(note.Content) {
+		if block.Line == line {
+			lines := strings.Split(note.Content, "\n")
+			return note, lines, plannerLineRegex.FindStringSubmatch(lines[line-1]), nil
+		}
+	}
+
+	return nil, nil, nil, fmt.Errorf("line %d in %s is not a planner block", line, note.Path)
+}
+
+func (s *NoteService) savePlanner(relativePath string, lines []string) ([]models.PlannerBlock, error) {
+	content := strings.Join(lines, "\n")
+	if err := s.SaveNote(relativePath, content); err != nil {
+		return nil, err
+	}
+	return s.parsePlanner(content), nil
+}
+
+func (s *NoteService) getOrCreateDailyNote(dateStr string) (*models.Note, error) {
+	if note, err := s.GetDailyNote(dateStr); err == nil {
+		return note, nil
+	}
+	return s.CreateDailyNote(dateStr)
+}
+
+// plannerNowNext finds the block in progress at minute now (the latest started one)
+// and the next block to start
+func plannerNowNext(blocks []models.PlannerBlock, now int) (*models.PlannerBlock, *models.PlannerBlock) {
+	var current, next *models.PlannerBlock
+	currentStart, nextStart := -1, 24*60+1
+	for i := range blocks {
+		start, _ := parseClock(blocks[i].Start)
+		end, _ := parseClock(blocks[i].End)
+
+		if start <= now && now < end && start >= currentStart {
+			current, currentStart = &blocks[i], start
+		}
+		if start > now && start < nextStart {
+			next, nextStart = &blocks[i], start
+		}
+	}
+	return current, next
+}
+
+// parsePlannerRange parses a start and optional end time, returning -1 for no end
+func parsePlannerRange(start string, end string) (int, int, error) {
+	startMin, err := parseClock(start)
+	if err != nil {
+		return 0, 0, err
+	}
+	if end == "" {
+		return startMin, -1, nil
+	}
+	endMin, err := parseClock(end)
+	if err != nil {
+		return 0, 0, err
+	}
+	if endMin <= startMin {
+		return 0, 0, fmt.Errorf("end time %s is not after start time %s", end, start)
+	}
+	return startMin, endMin, nil
+}
+
+// plannerEntryRange returns the start and end minute of a matched planner entry.
+// An end time that can't be parsed or isn't after the start is ignored, so the
+// end is -1 as for an open-ended block.
+func plannerEntryRange(matches []string) (int, int, error) {
+	startMin, err := parseClock(matches[3])
+	if err != nil {
+		return 0, 0, err
+	}
+	if endMin, err := parseClock(matches[4]); err == nil && endMin > startMin {
+		return startMin, endMin, nil
+	}
+	return startMin, -1, nil
+}
+
+// formatPlannerLine renders a planner entry. mark is the checkbox character ("" for none)
+// and endMin is -1 for an open-ended block.
+func formatPlannerLine(prefix string, mark string, startMin int, endMin int, text string) string {
+	var b strings.Builder
+	b.WriteString(prefix)
+	if mark != "" {
+		b.WriteString("[" + mark + "] ")
+	}
+	b.WriteString(formatClock(startMin))
+	if endMin >= 0 {
+		b.WriteString(" - " + formatClock(endMin))
+	}
+	b.WriteString(" " + text)
+	return b.String()
+}
+
+// parseClock parses "H:MM" or "HH:MM" (up to "24:00") into minutes since midnight
+func parseClock(clock string) (int, error) {
+	matches := clockRegex.FindStringSubmatch(clock)
+	if matches == nil {
+		return 0, fmt.Errorf("invalid time %q", clock)
+	}
+	h, _ := strconv.Atoi(matches[1])
+	m, _ := strconv.Atoi(matches[2])
+	if m > 59 || h*60+m > 24*60 {
+		return 0, fmt.Errorf("invalid time %q", clock)
+	}
+	return h*60 + m, nil
+}
+
+// formatClock formats minutes since midnight as "HH:MM"
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNoteService_ParsePlanner(t *testing.T) {
+	ns, _, tmpDir := newTestNoteService(t)
+	defer os.RemoveAll(tmpDir)
+
+	content := `## Day Planner
+- [ ] 09:00 Plan the day
+- [x] 09:15 - 10:30 Deep work
+- 13:00 Lunch
+  - with team
+- 25:00 Invalid time
+- [ ] No time
+
+## Memos
+- 14:00 Not a planner block
+`
+	blocks := ns.parsePlanner(content)
+	if len(blocks) != 3 {
+		t.Fatalf("Expected 3 blocks, got %d: %+v", len(blocks), blocks)
+	}
+
+	// Open-ended block ends at the next block
+	if blocks[0].Start != "09:00" || blocks[0].End != "09:15" || blocks[0].ExplicitEnd || blocks[0].Duration != 15 {
+		t.Errorf("Unexpected first block: %+v", blocks[0])
+	}
+	if blocks[1].End != "10:30" || !blocks[1].ExplicitEnd || !blocks[1].Done || blocks[1].Line != 3 {
+		t.Errorf("Unexpected second block: %+v", blocks[1])
+	}
+	// Last open-ended block uses the default duration (30 minutes without config)
+	if blocks[2].End != "13:30" || blocks[2].IsTodo || blocks[2].Text != "Lunch" {
+		t.Errorf("Unexpected third block: %+v", blocks[2])
+	}
+}
+
+func TestNoteService_PlannerBlocks(t *testing.T) {
+	ns, fs, tmpDir := newTestNoteService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs.CreateFile("dailynotes/2026-10-18.md", "## Day Planner\n- [ ] 09:00 Plan the day\n- [ ] 14:00 Review\n  - PR 42\n\n## Memos\n")
+
+	t.Run("add keeps blocks ordered", func(t *testing.T) {
+		blocks, err := ns.AddPlannerBlock("2026-10-18", "10:00", "11:30", "Deep work")
+		if err != nil {
+			t.Fatalf("AddPlannerBlock failed: %v", err)
+		}
+		if len(blocks) != 3 || blocks[1].Text != "Deep work" || blocks[1].End != "11:30" {
+			t.Errorf("Unexpected blocks: %+v", blocks)
+		}
+
+		content, _ := fs.ReadFile("dailynotes/2026-10-18.md")
+		if !strings.Contains(content, "- [ ] 09:00 Plan the day\n- [ ] 10:00 - 11:30 Deep work\n- [ ] 14:00 Review") {
+			t.Errorf("Block not inserted in order:\n%s", content)
+		}
+	})
+
+	t.Run("move keeps duration and nested lines", func(t *testing.T) {
+		// Move "14:00 Review" (line 4) before everything else
+		blocks, err := ns.MovePlannerBlock("2026-10-18", 4, "08:00")
+		if err != nil {
+			t.Fatalf("MovePlannerBlock failed: %v", err)
+		}
+		if blocks[0].Text != "Review" || blocks[0].Start != "08:00" {
+			t.Errorf("Unexpected blocks: %+v", blocks)
+		}
+
+		content, _ := fs.ReadFile("dailynotes/2026-10-18.md")
+		if !strings.HasPrefix(content, "## Day Planner\n- [ ] 08:00 Review\n  - PR 42\n- [ ] 09:00 Plan the day") {
+			t.Errorf("Block not moved:\n%s", content)
+		}
+
+		// Deep work is now on line 5 with a 90 minute duration
+		blocks, err = ns.MovePlannerBlock("2026-10-18", 5, "15:00")
+		if err != nil {
+			t.Fatalf("MovePlannerBlock failed: %v", err)
+		}
+		last := blocks[len(blocks)-1]
+		if last.Text != "Deep work" || last.Start != "15:00" || last.End != "16:30" {
+			t.Errorf("Unexpected moved block: %+v", last)
+		}
+	})
+
+	t.Run("resize", func(t *testing.T) {
+		blocks, err := ns.ResizePlannerBlock("2026-10-18", 4, "10:00")
+		if err != nil {
+			t.Fatalf("ResizePlannerBlock failed: %v", err)
+		}
+		if blocks[1].Text != "Plan the day" || blocks[1].End != "10:00" || !blocks[1].ExplicitEnd {
+			t.Errorf("Unexpected resized block: %+v", blocks[1])
+		}
+
+		if _, err := ns.ResizePlannerBlock("2026-10-18", 4, "08:30"); err == nil {
+			t.Error("Should fail when end is before start")
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if _, err := ns.MovePlannerBlock("2026-10-18", 1, "10:00"); err == nil {
+			t.Error("Should fail for non-block line")
+		}
+		if _, err := ns.AddPlannerBlock("2026-10-18", "9am", "", "Bad"); err == nil {
+			t.Error("Should fail for invalid time")
+		}
+	})
+
+	t.Run("move a block ending before it starts", func(t *testing.T) {
+		fs.WriteFile("dailynotes/2026-10-18.md", "## Day Planner\n- [ ] 10:00 - 09:00 Backwards\n")
+		blocks, err := ns.MovePlannerBlock("2026-10-18", 2, "11:00")
+		if err != nil {
+			t.Fatalf("MovePlannerBlock failed: %v", err)
+		}
+		if len(blocks) != 1 || blocks[0].Start != "11:00" || blocks[0].ExplicitEnd {
+			t.Errorf("Expected an open-ended block, got %+v", blocks)
+		}
+
+		content, _ := fs.ReadFile("dailynotes/2026-10-18.md")
+		if !strings.Contains(content, "- [ ] 11:00 Backwards\n") {
+			t.Errorf("Expected the end time dropped:\n%s", content)
+		}
+	})
+
+	t.Run("add creates daily note", func(t *testing.T) {
+		blocks, err := ns.AddPlannerBlock("2026-10-19", "11:00", "", "Standup")
+		if err != nil {
+			t.Fatalf("AddPlannerBlock failed: %v", err)
+		}
+		if len(blocks) != 2 || blocks[1].Text != "Standup" {
+			t.Errorf("Unexpected blocks: %+v", blocks)
+		}
+	})
+}
+
+func TestPlannerNowNext(t *testing.T) {
+	ns, _, tmpDir := newTestNoteService(t)
+	defer os.RemoveAll(tmpDir)
+
+	blocks := ns.parsePlanner("## Day Planner\n- 09:00 - 12:00 Morning\n- 10:00 - 11:00 Meeting\n- 13:00 Lunch\n")
+
+	tests := []struct {
+		clock     string
+		now, next string
+	}{
+		{"08:00", "", "Morning"},
+		{"09:30", "Morning", "Meeting"},
+		{"10:30", "Meeting", "Lunch"},
+		{"12:30", "", "Lunch"},
+		{"13:10", "Lunch", ""},
+	}
+
+	for _, tt := range tests {
+		minutes, _ := parseClock(tt.clock)
+		now, next := plannerNowNext(blocks, minutes)
+
+		gotNow, gotNext := "", ""
+		if now != nil {
+			gotNow = now.Text
+		}
+		if next != nil {
+			gotNext = next.Text
+		}
+		if gotNow != tt.now || gotNext != tt.next {
+			t.Errorf("At %s: now=%q next=%q, want now=%q next=%q", tt.clock, gotNow, gotNext, tt.now, tt.next)
+		}
+	}
+}
@@ -1,11 +1,19 @@
 package services
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/kazuph/obails/models"
 )
@@ -14,14 +22,26 @@ import (
 type NoteService struct {
 	fileService   *FileService
 	configService *ConfigService
+
+	// Recently loaded versions, the bases for merging external changes
+	bases *noteBaseCache
+
+	// Guards the snapshot files in .obails/history
+	historyMu sync.Mutex
+
+	// Called with the path of every saved note
+	saveHooks []func(relativePath string)
 }
 
-// NewNoteService creates a new NoteService
+// NewNoteService creates a new NoteService. The history of moved notes moves along.
 func NewNoteService(fileService *FileService, configService *ConfigService) *NoteService {
-	return &NoteService{
+	s := &NoteService{
 		fileService:   fileService,
 		configService: configService,
+		bases:         newNoteBaseCache(),
 	}
+	fileService.onMove(s.historyPathMoved)
+	return s
 }
 
 // GetNote reads a note from the vault
@@ -41,14 +61,69 @@ func (s *NoteService) GetNote(relativePath string) (*models.Note, error) {
 		Title:      s.extractTitle(content, relativePath),
 		Content:    content,
 		ModifiedAt: fileInfo.ModifiedAt,
+		Hash:       contentHash(content),
 	}
+	s.bases.remember(relativePath, content)
 
 	return note, nil
 }
 
-// SaveNote saves a note to the vault
-func (s *NoteService) SaveNote(relativePath string, content string) error {
-	return s.fileService.WriteFile(relativePath, content)
+// SaveNote saves a note to the vault. If an expected version is given, the save fails
+// with a ConflictError when the file on disk no longer matches it.
+func (s *NoteService) SaveNote(relativePath string, content string, expected ...models.NoteVersion) error {
+	if len(expected) > 0 {
+		if err := s.checkVersion(relativePath, expected[0]); err != nil {
+			return err
+		}
+	}
+	// History is best effort and never fails a save
+	s.captureExternalChange(relativePath, content)
+	if err := s.fileService.WriteFile(relativePath, content); err != nil {
+		return err
+	}
+	s.bases.remember(relativePath, content)
+	s.recordSaveSnapshot(relativePath, content)
+	s.notifySaved(relativePath)
+	return nil
+}
+
+// onSave registers a function called after each note save
+func (s *NoteService) onSave(hook func(relativePath string)) {
+	s.saveHooks = append(s.saveHooks, hook)
+}
+
+func (s *NoteService) notifySaved(relativePath string) {
+	for _, hook := range s.saveHooks {
+		hook(relativePath)
+	}
+}
+
+// checkVersion compares the note on disk with the version the caller loaded
+func (s *NoteService) checkVersion(relativePath string, expected models.NoteVersion) error {
+	if expected.Hash == "" && expected.ModifiedAt.IsZero() {
+		return nil
+	}
+
+	content, err := s.fileService.ReadFile(relativePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &ConflictError{Path: relativePath, Deleted: true}
+	}
+	if err != nil {
+		return err
+	}
+	info, err := s.fileService.GetFileInfo(relativePath)
+	if err != nil {
+		return err
+	}
+
+	current := models.NoteVersion{ModifiedAt: info.ModifiedAt, Hash: contentHash(content)}
+	if expected.Hash != "" && expected.Hash != current.Hash {
+		return &ConflictError{Path: relativePath, Current: current}
+	}
+	if expected.Hash == "" && !expected.ModifiedAt.Equal(current.ModifiedAt) {
+		return &ConflictError{Path: relativePath, Current: current}
+	}
+	return nil
 }
 
 // GetDailyNote gets or creates a daily note for a specific date
@@ -61,15 +136,14 @@ func (s *NoteService) GetDailyNote(dateStr string) (*models.Note, error) {
 
 	folder := s.configService.GetDailyNotesFolder()
 	format := s.configService.GetDailyNotesFormat()
-	filename := date.Format(format) + ".md"
-	relativePath := filepath.Join(folder, filename)
+	name := filepath.Join(folder, date.Format(format))
 
-	// Check if the daily note exists
-	if s.fileService.FileExists(relativePath) {
+	// Check if the daily note exists, with any note extension
+	if relativePath, ok := s.fileService.findNote(name); ok {
 		return s.GetNote(relativePath)
 	}
 
-	return nil, fmt.Errorf("daily note not found: %s", relativePath)
+	return nil, fmt.Errorf("daily note not found: %s", name+noteExt)
 }
 
 // CreateDailyNote creates a new daily note for a specific date
@@ -81,19 +155,56 @@ func (s *NoteService) CreateDailyNote(dateStr string) (*models.Note, error) {
 
 	folder := s.configService.GetDailyNotesFolder()
 	format := s.configService.GetDailyNotesFormat()
-	filename := date.Format(format) + ".md"
+	filename := date.Format(format) + noteExt
 	relativePath := filepath.Join(folder, filename)
 
 	// Create initial content with template
 	content := s.generateDailyNoteTemplate(date)
 
-	if err := s.fileService.WriteFile(relativePath, content); err != nil {
+	// Carry over unfinished tasks from the previous daily note
+	var previous *models.DailyNoteInfo
+	var previousContent string
+	if rollover := s.configService.GetDailyNotesRollover(); rollover.Enabled {
+		previous, err = s.previousDailyNote(dateStr)
+		if err != nil {
+			return nil, err
+		}
+		if previous != nil {
+			if previousContent, err = s.fileService.ReadFile(previous.Path); err != nil {
+				return nil, err
+			}
+			content, previousContent = s.rolloverTasks(previousContent, content, rollover.Sections)
+		}
+	}
+
+	if err := s.SaveNote(relativePath, content); err != nil {
 		return nil, err
 	}
 
+	if previous != nil && s.configService.GetDailyNotesRollover().Mode == models.RolloverModeMove {
+		if err := s.SaveNote(previous.Path, previousContent); err != nil {
+			return nil, err
+		}
+	}
+
 	return s.GetNote(relativePath)
 }
 
+// previousDailyNote returns the most recent daily note before dateStr, or nil if none
+func (s *NoteService) previousDailyNote(dateStr string) (*models.DailyNoteInfo, error) {
+	notes, err := s.ListDailyNotes()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(notes) - 1; i >= 0; i-- {
+		if notes[i].Date < dateStr {
+			return &notes[i], nil
+		}
+	}
+	return nil, nil
+}
+
 // GetTodayDailyNote gets or creates today's daily note
 func (s *NoteService) GetTodayDailyNote() (*models.Note, error) {
 	today := time.Now().Format("2006-01-02")
@@ -142,39 +253,234 @@ func (s *NoteService) GetTodayTimelines() ([]models.Timeline, error) {
 // GetRecentTimelines gets Timeline entries from the last N days
 func (s *NoteService) GetRecentTimelines(days int) ([]models.Timeline, error) {
 	var allTimelines []models.Timeline
+	if days <= 0 {
+		return allTimelines, nil
+	}
+
+	now := time.Now()
+	from := now.AddDate(0, 0, -(days - 1)).Format("2006-01-02")
+	to := now.Format("2006-01-02")
+
+	notes, err := s.ListDailyNotes()
+	if err != nil {
+		return nil, err
+	}
 
-	for i := 0; i < days; i++ {
-		date := time.Now().AddDate(0, 0, -i)
-		dateStr := date.Format("2006-01-02")
+	// Newest day first
+	for i := len(notes) - 1; i >= 0; i-- {
+		info := notes[i]
+		if info.Date < from || info.Date > to {
+			continue
+		}
 
-		timelines, err := s.GetTimelines(dateStr)
+		timelines, err := s.noteTimelines(info, now)
 		if err != nil {
-			// Skip if daily note doesn't exist for that day
 			continue
 		}
+		allTimelines = append(allTimelines, timelines...)
+	}
 
-		// Add date prefix to each timeline for display
-		for j := range timelines {
-			if i == 0 {
-				// Today - just show time
-				timelines[j].Date = "Today"
-			} else if i == 1 {
-				// Yesterday
-				timelines[j].Date = "Yesterday"
-			} else {
-				// Other days - show date
-				timelines[j].Date = date.Format("01/02")
+	return allTimelines, nil
+}
+
+// QueryTimelines returns Timeline entries across all daily notes matching the query,
+// newest first. Entries are ordered by their SortKey, so paging is stable.
+func (s *NoteService) QueryTimelines(query models.TimelineQuery) (*models.TimelinePage, error) {
+	for _, dateStr := range []string{query.From, query.To} {
+		if dateStr == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", dateStr); err != nil {
+			return nil, fmt.Errorf("invalid date %q: %w", dateStr, err)
+		}
+	}
+
+	notes, err := s.ListDailyNotes()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	text := strings.ToLower(query.Text)
+	var matches []models.Timeline
+
+	for _, info := range notes {
+		if (query.From != "" && info.Date < query.From) || (query.To != "" && info.Date > query.To) {
+			continue
+		}
+
+		timelines, err := s.noteTimelines(info, now)
+		if err != nil {
+			continue
+		}
+
+		for _, timeline := range timelines {
+			if query.TodoOnly && !timeline.IsTodo {
+				continue
+			}
+			if text != "" && !strings.Contains(strings.ToLower(timeline.Content), text) {
+				continue
 			}
+			matches = append(matches, timeline)
 		}
+	}
 
-		allTimelines = append(allTimelines, timelines...)
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].SortKey > matches[j].SortKey
+	})
+
+	page := &models.TimelinePage{
+		Items: []models.Timeline{},
+		Total: len(matches),
 	}
 
-	return allTimelines, nil
+	offset := max(query.Offset, 0)
+	if offset >= len(matches) {
+		return page, nil
+	}
+	end := len(matches)
+	if query.Limit > 0 && offset+query.Limit < end {
+		end = offset + query.Limit
+	}
+	page.Items = matches[offset:end]
+	page.HasMore = end < len(matches)
+
+	return page, nil
+}
+
+// ListDailyNotes returns all daily notes in the daily notes folder, oldest first.
+// Files are recognized by parsing their path with the configured date format.
+func (s *NoteService) ListDailyNotes() ([]models.DailyNoteInfo, error) {
+	folder := s.configService.GetDailyNotesFolder()
+	format := s.configService.GetDailyNotesFormat()
+	root, err := s.fileService.getFullPath(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []models.DailyNoteInfo
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root && errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipAll
+			}
+			return nil // Skip errors
+		}
+
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !s.fileService.types.isNote(d.Name()) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		name := s.fileService.types.trimNoteExt(filepath.ToSlash(rel))
+
+		date, err := time.Parse(format, name)
+		if err != nil || date.Format(format) != name {
+			return nil
+		}
+
+		notes = append(notes, models.DailyNoteInfo{
+			Date: date.Format("2006-01-02"),
+			Path: filepath.Join(folder, rel),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(notes, func(i, j int) bool {
+		return notes[i].Date < notes[j].Date
+	})
+
+	return notes, nil
+}
+
+// noteTimelines reads the Timeline entries of a daily note and fills in their date,
+// location and sort key
+func (s *NoteService) noteTimelines(info models.DailyNoteInfo, now time.Time) ([]models.Timeline, error) {
+	content, err := s.fileService.ReadFile(info.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", info.Date, now.Location())
+	if err != nil {
+		return nil, err
+	}
+
+	layout := s.configService.GetTimelineTimeFormat()
+	timelines := s.parseTimelines(content)
+	for i := range timelines {
+		clock := "00:00:00"
+		if t, err := time.Parse(layout, timelines[i].Time); err == nil {
+			clock = t.Format("15:04:05")
+		}
+
+		timelines[i].Date = relativeDateLabel(date, now)
+		timelines[i].ISODate = info.Date
+		timelines[i].Path = info.Path
+		timelines[i].SortKey = fmt.Sprintf("%sT%s#%04d", info.Date, clock, timelines[i].Line)
+	}
+
+	return timelines, nil
+}
+
+// relativeDateLabel formats a date for display: "Today", "Yesterday", or "MM/DD"
+func relativeDateLabel(date time.Time, now time.Time) string {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+
+	switch {
+	case date.Equal(today):
+		return "Today"
+	case date.Equal(today.AddDate(0, 0, -1)):
+		return "Yesterday"
+	default:
+		return date.Format("01/02")
+	}
+}
+
+// ErrConflict is returned (wrapped in a ConflictError) when a note changed on disk
+// since it was loaded
+var ErrConflict = errors.New("note changed on disk")
+
+// ConflictError reports a save that was refused because the note changed on disk
+type ConflictError struct {
+	Path    string
+	Current models.NoteVersion // Version now on disk
+	Deleted bool               // The note was deleted on disk
+}
+
+func (e *ConflictError) Error() string {
+	if e.Deleted {
+		return fmt.Sprintf("%v: %s was deleted", ErrConflict, e.Path)
+	}
+	return fmt.Sprintf("%v: %s", ErrConflict, e.Path)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
 }
 
 // Helper functions
 
+// contentHash returns the hex SHA-256 of a note's content
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
 func (s *NoteService) extractTitle(content string, path string) string {
 	// Try to find a # heading
 	lines := strings.Split(content, "\n")
@@ -185,7 +491,7 @@ func (s *NoteService) extractTitle(content string, path string) string {
 		}
 	}
 	// Fall back to filename without extension
-	return strings.TrimSuffix(filepath.Base(path), ".md")
+	return s.fileService.types.trimNoteExt(filepath.Base(path))
 }
 
 func (s *NoteService) generateDailyNoteTemplate(date time.Time) string {
@@ -210,30 +516,195 @@ tags:
 `, dateStr, timeStr)
 }
 
+// insertAfterSection inserts a Timeline entry into the section's list. Depending on the
+// configured insert order the entry goes first (newest first) or last (oldest first) in
+// the list. A missing section is created where the daily note template would have it.
 func (s *NoteService) insertAfterSection(content string, section string, entry string) string {
 	lines := strings.Split(content, "\n")
-	var result []string
-	found := false
 
-	for i, line := range lines {
-		result = append(result, line)
-		if strings.TrimSpace(line) == section {
-			found = true
-			// Insert the entry after the section header
-			// Check if next line is empty, if not add an empty line first
-			if i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
-				result = append(result, "")
+	start, end, ok := findSection(lines, section)
+	if !ok {
+		return strings.Join(s.insertSection(lines, section, []string{entry}), "\n")
+	}
+
+	at := start
+	block := []string{entry}
+	listStart, listEnd := findListBlock(lines[start:end])
+	switch {
+	case listStart < 0:
+		// No list yet: start one right under the heading, keeping other content apart
+		if at < len(lines) && strings.TrimSpace(lines[at]) != "" {
+			block = append(block, "")
+		}
+	case s.configService.GetTimelineInsertOrder() == models.TimelineOrderOldestFirst:
+		at = start + listEnd
+	default:
+		at = start + listStart
+	}
+
+	return strings.Join(insertLines(lines, at, block), "\n")
+}
+
+// insertSection adds a new section containing entries. It is placed after the closest
+// preceding (or before the closest following) template section present in the note,
+// and appended at the end otherwise.
+func (s *NoteService) insertSection(lines []string, section string, entries []string) []string {
+	at := len(lines)
+
+	order := s.templateSections()
+	if idx := slices.Index(order, strings.TrimSpace(section)); idx >= 0 {
+		placed := false
+		for j := idx - 1; j >= 0 && !placed; j-- {
+			if _, end, ok := findSection(lines, order[j]); ok {
+				at, placed = end, true
+			}
+		}
+		for j := idx + 1; j < len(order) && !placed; j++ {
+			if start, _, ok := findSection(lines, order[j]); ok {
+				at, placed = start-1, true
 			}
-			result = append(result, entry)
 		}
 	}
 
-	if !found {
-		// Section not found, append at the end
-		result = append(result, "", section, entry)
+	// Keep the blank lines separating the previous content from what follows
+	for at > 0 && strings.TrimSpace(lines[at-1]) == "" {
+		at--
+	}
+
+	block := append([]string{section}, entries...)
+	if at > 0 {
+		block = append([]string{""}, block...)
+	}
+	if at < len(lines) && strings.TrimSpace(lines[at]) != "" {
+		block = append(block, "")
+	}
+
+	return insertLines(lines, at, block)
+}
+
+// rolloverTasks copies the open tasks (with their nested lines) found in the given
+// sections of previous into the same sections of content. Tasks already present are
+// skipped. It returns the new content and previous with the rolled over tasks removed;
+// skipped tasks stay in previous.
+func (s *NoteService) rolloverTasks(previous string, content string, sections []string) (string, string) {
+	prevLines := strings.Split(previous, "\n")
+	lines := strings.Split(content, "\n")
+	moved := make(map[int]bool)
+
+	for _, section := range sections {
+		start, end, ok := findSection(prevLines, section)
+		if !ok {
+			continue
+		}
+
+		// Existing tasks in the target section
+		existing := make(map[string]bool)
+		if tStart, tEnd, ok := findSection(lines, section); ok {
+			for _, line := range lines[tStart:tEnd] {
+				if matches := taskRegex.FindStringSubmatch(line); matches != nil {
+					existing[strings.TrimSpace(matches[4])] = true
+				}
+			}
+		}
+
+		var block []string
+		for i := start; i < end; i++ {
+			matches := taskRegex.FindStringSubmatch(prevLines[i])
+			if matches == nil || isIndented(prevLines[i]) || isDoneMark(matches[2]) {
+				continue
+			}
+
+			// Take the task with its indented children
+			j := i + 1
+			for j < end && isIndented(prevLines[j]) {
+				j++
+			}
+			if !existing[strings.TrimSpace(matches[4])] {
+				block = append(block, prevLines[i:j]...)
+				for k := i; k < j; k++ {
+					moved[k] = true
+				}
+			}
+			i = j - 1
+		}
+
+		if len(block) == 0 {
+			continue
+		}
+
+		tStart, tEnd, ok := findSection(lines, section)
+		if !ok {
+			lines = s.insertSection(lines, section, block)
+			continue
+		}
+		at := tStart
+		if listStart, listEnd := findListBlock(lines[tStart:tEnd]); listStart >= 0 {
+			at = tStart + listEnd
+		} else if at < len(lines) && strings.TrimSpace(lines[at]) != "" {
+			block = append(block, "")
+		}
+		lines = insertLines(lines, at, block)
+	}
+
+	var remaining []string
+	for i, line := range prevLines {
+		if !moved[i] {
+			remaining = append(remaining, line)
+		}
 	}
 
-	return strings.Join(result, "\n")
+	return strings.Join(lines, "\n"), strings.Join(remaining, "\n")
+}
+
+// isIndented reports whether a non-blank line starts with whitespace
+func isIndented(line string) bool {
+	return line != "" && (line[0] == ' ' || line[0] == '\t')
+}
+
+// templateSections returns the heading lines of the daily note template in order
+func (s *NoteService) templateSections() []string {
+	var headings []string
+	for _, line := range strings.Split(s.generateDailyNoteTemplate(time.Now()), "\n") {
+		if headingLevel(line) > 0 {
+			headings = append(headings, strings.TrimSpace(line))
+		}
+	}
+	return headings
+}
+
+// listItemRegex matches a top-level markdown list item
+var listItemRegex = regexp.MustCompile(`^(?:[-*+]|\d+[.)])(?:\s|$)`)
+
+// findListBlock returns the range [start, end) of the first list in lines, including
+// indented continuation lines and blank lines between items. start is -1 if there is none.
+func findListBlock(lines []string) (start int, end int) {
+	start = -1
+	for i, line := range lines {
+		switch {
+		case listItemRegex.MatchString(line):
+			if start < 0 {
+				start = i
+			}
+			end = i + 1
+		case start < 0:
+			continue
+		case strings.TrimSpace(line) == "":
+			// A blank line may separate items of a loose list
+		case line[0] == ' ' || line[0] == '\t':
+			end = i + 1
+		default:
+			return start, end
+		}
+	}
+	return start, end
+}
+
+// insertLines returns lines with block inserted before index at
+func insertLines(lines []string, at int, block []string) []string {
+	result := make([]string, 0, len(lines)+len(block))
+	result = append(result, lines[:at]...)
+	result = append(result, block...)
+	return append(result, lines[at:]...)
 }
 
 func (s *NoteService) parseTimelines(content string) []models.Timeline {
@@ -241,41 +712,140 @@ func (s *NoteService) parseTimelines(content string) []models.Timeline {
 
 	section := s.configService.GetTimelineSection()
 	lines := strings.Split(content, "\n")
-	inMemosSection := false
+	start, end, ok := findSection(lines, section)
+	if !ok {
+		return timelines
+	}
 
-	// Regex to match Timeline entries: - HH:MM content or - [x] HH:MM content
-	timelineRegex := regexp.MustCompile(`^-\s+(?:\[([ x])\]\s+)?(\d{1,2}:\d{2})\s+(.+)$`)
+	timelineRegex := s.timelineRegex()
+
+	for i, line := range lines[start:end] {
+		matches := timelineRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if len(matches) == 4 {
+			checkbox := matches[1]
+			timeStr := matches[2]
+			text := matches[3]
+
+			timeline := models.Timeline{
+				Time:    timeStr,
+				Content: text,
+				IsTodo:  checkbox != "",
+				Done:    strings.EqualFold(checkbox, "x"),
+				Line:    start + i + 1,
+			}
+			timelines = append(timelines, timeline)
+		}
+	}
 
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-
-		// Check for section headers
-		if strings.HasPrefix(trimmed, "## ") {
-			if trimmed == section {
-				inMemosSection = true
-			} else {
-				inMemosSection = false
+	return timelines
+}
+
+// timelineRegex builds the regex matching Timeline entries for the configured time format:
+// "- <time> content" or "- [x] <time> content"
+func (s *NoteService) timelineRegex() *regexp.Regexp {
+	layout := s.configService.GetTimelineTimeFormat()
+	if layout == "" {
+		layout = "15:04"
+	}
+	return regexp.MustCompile(`^-\s+(?:\[([ xX])\]\s+)?(` + timeLayoutPattern(layout) + `)\s+(.+)$`)
+}
+
+// timeLayoutTokens maps Go reference time tokens to the regex fragments matching them.
+// Longer tokens come first so "15" wins over "1" and "03" over "3".
+var timeLayoutTokens = []struct {
+	token   string
+	pattern string
+}{
+	{"15", `\d{1,2}`},
+	{"03", `\d{2}`},
+	{"04", `\d{2}`},
+	{"05", `\d{2}`},
+	{"PM", `(?:AM|PM)`},
+	{"pm", `(?:am|pm)`},
+	{"3", `\d{1,2}`},
+	{"4", `\d{1,2}`},
+	{"5", `\d{1,2}`},
+}
+
+// timeLayoutPattern converts a Go time layout (e.g. "15:04", "3:04 PM") into a regex
+// fragment. Characters that are not time tokens are matched literally.
+func timeLayoutPattern(layout string) string {
+	var b strings.Builder
+	for layout != "" {
+		matched := false
+		for _, t := range timeLayoutTokens {
+			if strings.HasPrefix(layout, t.token) {
+				b.WriteString(t.pattern)
+				layout = layout[len(t.token):]
+				matched = true
+				break
 			}
-			continue
 		}
+		if !matched {
+			r, size := utf8.DecodeRuneInString(layout)
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			layout = layout[size:]
+		}
+	}
+	return b.String()
+}
 
-		if inMemosSection {
-			matches := timelineRegex.FindStringSubmatch(trimmed)
-			if len(matches) == 4 {
-				checkbox := matches[1]
-				timeStr := matches[2]
-				text := matches[3]
+// headingLevel returns the level (1-6) of a markdown ATX heading line, or 0 if it isn't one
+func headingLevel(line string) int {
+	trimmed := strings.TrimSpace(line)
+	level := 0
+	for level < len(trimmed) && trimmed[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0
+	}
+	// "#tag" is not a heading
+	if level < len(trimmed) && trimmed[level] != ' ' && trimmed[level] != '\t' {
+		return 0
+	}
+	return level
+}
 
-				timeline := models.Timeline{
-					Time:    timeStr,
-					Content: text,
-					IsTodo:  checkbox != "",
-					Done:    checkbox == "x",
-				}
-				timelines = append(timelines, timeline)
+// isCodeFence reports whether a line opens or closes a fenced code block
+func isCodeFence(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+}
+
+// findSection locates the body of the section whose heading line equals section.
+// It returns the line range [start, end): start is the line after the heading and end is
+// the next heading of the same or higher level (or len(lines)). Headings inside fenced
+// code blocks are ignored.
+func findSection(lines []string, section string) (start int, end int, ok bool) {
+	section = strings.TrimSpace(section)
+	level := headingLevel(section)
+	if level == 0 {
+		// Not a heading: the section runs until any heading
+		level = 6
+	}
+
+	inFence := false
+	for i, line := range lines {
+		if isCodeFence(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		if !ok {
+			if strings.TrimSpace(line) == section {
+				start, end, ok = i+1, len(lines), true
 			}
+			continue
+		}
+
+		if l := headingLevel(line); l > 0 && l <= level {
+			return start, i, true
 		}
 	}
 
-	return timelines
+	return start, end, ok
 }
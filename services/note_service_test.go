@@ -1,6 +1,8 @@
 package services
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -282,6 +284,96 @@ func TestNoteService_ParseTimelines(t *testing.T) {
 			t.Errorf("Expected 1 timeline (only from Memos section), got %d", len(timelines))
 		}
 	})
+
+	t.Run("subheadings do not end the section", func(t *testing.T) {
+		content := `## Memos
+- 10:00 Before subheading
+
+### Morning
+- 10:30 Under subheading
+
+## Todo
+- 11:00 Ignored
+`
+		timelines := ns.parseTimelines(content)
+
+		if len(timelines) != 2 {
+			t.Fatalf("Expected 2 timelines, got %d: %+v", len(timelines), timelines)
+		}
+		if timelines[1].Content != "Under subheading" {
+			t.Errorf("Unexpected timeline: %+v", timelines[1])
+		}
+	})
+
+	t.Run("headings in code blocks are ignored", func(t *testing.T) {
+		content := "## Memos\n- 10:00 First\n```sh\n# comment\n```\n- 10:30 Second\n"
+		timelines := ns.parseTimelines(content)
+
+		if len(timelines) != 2 {
+			t.Errorf("Expected 2 timelines, got %d", len(timelines))
+		}
+	})
+}
+
+func TestNoteService_ParseTimelines_Config(t *testing.T) {
+	ns, _, tmpDir := newTestNoteService(t)
+	defer os.RemoveAll(tmpDir)
+
+	t.Run("deeper section level", func(t *testing.T) {
+		ns.configService.config.Timeline.Section = "### Log"
+		defer func() { ns.configService.config.Timeline.Section = "## Memos" }()
+
+		content := `## Journal
+### Log
+- 10:00 In log
+#### Detail
+- 10:15 Still in log
+### Other
+- 11:00 Ignored
+`
+		timelines := ns.parseTimelines(content)
+
+		if len(timelines) != 2 {
+			t.Errorf("Expected 2 timelines, got %d: %+v", len(timelines), timelines)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		format   string
+		line     string
+		expected string
+	}{
+		{"seconds", "15:04:05", "- 10:30:15 With seconds", "10:30:15"},
+		{"12-hour", "3:04 PM", "- 3:04 PM Afternoon", "3:04 PM"},
+		{"12-hour padded lowercase", "03:04pm", "- [x] 09:15am Morning", "09:15am"},
+		{"empty format falls back", "", "- 9:05 Default", "9:05"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ns.configService.config.Timeline.TimeFormat = tt.format
+			defer func() { ns.configService.config.Timeline.TimeFormat = "15:04" }()
+
+			timelines := ns.parseTimelines("## Memos\n" + tt.line + "\n")
+			if len(timelines) != 1 {
+				t.Fatalf("Expected 1 timeline, got %d", len(timelines))
+			}
+			if timelines[0].Time != tt.expected {
+				t.Errorf("Time = %q, want %q", timelines[0].Time, tt.expected)
+			}
+		})
+	}
+
+	t.Run("mismatched format yields nothing", func(t *testing.T) {
+		ns.configService.config.Timeline.TimeFormat = "15:04:05"
+		defer func() { ns.configService.config.Timeline.TimeFormat = "15:04" }()
+
+		timelines := ns.parseTimelines("## Memos\n- 10:30 No seconds\n")
+		if len(timelines) != 0 {
+			t.Errorf("Expected no timelines, got %+v", timelines)
+		}
+	})
 }
 
 func TestNoteService_ExtractTitle(t *testing.T) {
@@ -349,3 +441,458 @@ Just some content
 		}
 	})
 }
+
+func TestNoteService_InsertAfterSection_Order(t *testing.T) {
+	ns, _, tmpDir := newTestNoteService(t)
+	defer os.RemoveAll(tmpDir)
+
+	content := `## Memos
+Intro paragraph
+
+- 10:00 First
+- 11:00 Second
+  continued
+
+## Todo
+`
+
+	t.Run("newest first goes to the top of the list", func(t *testing.T) {
+		result := ns.insertAfterSection(content, "## Memos", "- 12:00 Third")
+
+		expected := "Intro paragraph\n\n- 12:00 Third\n- 10:00 First"
+		if !strings.Contains(result, expected) {
+			t.Errorf("Entry not at top of list:\n%s", result)
+		}
+	})
+
+	t.Run("oldest first goes to the end of the list", func(t *testing.T) {
+		ns.configService.config.Timeline.InsertOrder = models.TimelineOrderOldestFirst
+		defer func() { ns.configService.config.Timeline.InsertOrder = "" }()
+
+		result := ns.insertAfterSection(content, "## Memos", "- 12:00 Third")
+
+		expected := "- 11:00 Second\n  continued\n- 12:00 Third\n\n## Todo"
+		if !strings.Contains(result, expected) {
+			t.Errorf("Entry not at end of list:\n%s", result)
+		}
+	})
+
+	t.Run("entries keep chronological order", func(t *testing.T) {
+		ns.configService.config.Timeline.InsertOrder = models.TimelineOrderOldestFirst
+		defer func() { ns.configService.config.Timeline.InsertOrder = "" }()
+
+		result := "## Memos\n\n## Todo\n"
+		result = ns.insertAfterSection(result, "## Memos", "- 09:00 A")
+		result = ns.insertAfterSection(result, "## Memos", "- 09:30 B")
+
+		if result != "## Memos\n- 09:00 A\n- 09:30 B\n\n## Todo\n" {
+			t.Errorf("Unexpected result:\n%s", result)
+		}
+	})
+
+	t.Run("missing section is created in template position", func(t *testing.T) {
+		content := `# Today's
+
+## Day Planner
+- [ ] 09:00 Plan the day
+
+## Todo
+- [ ] Something
+`
+		result := ns.insertAfterSection(content, "## Memos", "- 10:00 New entry")
+
+		expected := "- [ ] 09:00 Plan the day\n\n## Memos\n- 10:00 New entry\n\n## Todo"
+		if !strings.Contains(result, expected) {
+			t.Errorf("Section not created between Day Planner and Todo:\n%s", result)
+		}
+	})
+
+	t.Run("missing section before following template section", func(t *testing.T) {
+		content := "## Todo\n- [ ] Something\n"
+		result := ns.insertAfterSection(content, "## Memos", "- 10:00 New entry")
+
+		if result != "## Memos\n- 10:00 New entry\n\n## Todo\n- [ ] Something\n" {
+			t.Errorf("Unexpected result:\n%s", result)
+		}
+	})
+}
+
+func TestNoteService_QueryTimelines(t *testing.T) {
+	ns, fs, tmpDir := newTestNoteService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs.CreateFile("dailynotes/2025-12-31.md", "## Memos\n- 09:00 Last day\n- [ ] 23:30 Countdown\n")
+	fs.CreateFile("dailynotes/2026-01-01.md", "## Memos\n- 10:00 New year\n- [x] 08:00 Early run\n")
+	fs.CreateFile("dailynotes/2026-01-02.md", "## Memos\n- 12:00 Back to work\n")
+	fs.CreateFile("dailynotes/notes.md", "## Memos\n- 12:00 Not a daily note\n")
+
+	t.Run("list daily notes", func(t *testing.T) {
+		notes, err := ns.ListDailyNotes()
+		if err != nil {
+			t.Fatalf("ListDailyNotes failed: %v", err)
+		}
+		if len(notes) != 3 {
+			t.Fatalf("Expected 3 daily notes, got %d: %+v", len(notes), notes)
+		}
+		if notes[0].Date != "2025-12-31" || notes[0].Path != filepath.Join("dailynotes", "2025-12-31.md") {
+			t.Errorf("Unexpected first note: %+v", notes[0])
+		}
+	})
+
+	t.Run("range across new year, newest first", func(t *testing.T) {
+		page, err := ns.QueryTimelines(models.TimelineQuery{From: "2025-12-31", To: "2026-01-01"})
+		if err != nil {
+			t.Fatalf("QueryTimelines failed: %v", err)
+		}
+		if page.Total != 4 {
+			t.Fatalf("Expected 4 entries, got %d", page.Total)
+		}
+
+		var got []string
+		for _, item := range page.Items {
+			got = append(got, item.ISODate+" "+item.Time)
+		}
+		expected := []string{"2026-01-01 10:00", "2026-01-01 08:00", "2025-12-31 23:30", "2025-12-31 09:00"}
+		if strings.Join(got, ",") != strings.Join(expected, ",") {
+			t.Errorf("Order = %v, want %v", got, expected)
+		}
+		if page.Items[0].Path != filepath.Join("dailynotes", "2026-01-01.md") || page.Items[0].Line != 2 {
+			t.Errorf("Unexpected location: %+v", page.Items[0])
+		}
+	})
+
+	t.Run("paging", func(t *testing.T) {
+		first, _ := ns.QueryTimelines(models.TimelineQuery{Limit: 2})
+		second, _ := ns.QueryTimelines(models.TimelineQuery{Offset: 2, Limit: 2})
+		third, _ := ns.QueryTimelines(models.TimelineQuery{Offset: 4, Limit: 2})
+
+		if len(first.Items) != 2 || !first.HasMore {
+			t.Errorf("Unexpected first page: %+v", first)
+		}
+		if len(second.Items) != 2 || !second.HasMore {
+			t.Errorf("Unexpected second page: %+v", second)
+		}
+		if len(third.Items) != 1 || third.HasMore {
+			t.Errorf("Unexpected third page: %+v", third)
+		}
+		if first.Items[1].SortKey <= second.Items[0].SortKey {
+			t.Error("Pages should not overlap")
+		}
+	})
+
+	t.Run("text and todo filters", func(t *testing.T) {
+		page, _ := ns.QueryTimelines(models.TimelineQuery{Text: "NEW"})
+		if page.Total != 1 || page.Items[0].Content != "New year" {
+			t.Errorf("Unexpected text filter result: %+v", page.Items)
+		}
+
+		page, _ = ns.QueryTimelines(models.TimelineQuery{TodoOnly: true})
+		if page.Total != 2 {
+			t.Errorf("Expected 2 todos, got %d", page.Total)
+		}
+	})
+
+	t.Run("invalid date", func(t *testing.T) {
+		if _, err := ns.QueryTimelines(models.TimelineQuery{From: "12/31"}); err == nil {
+			t.Error("Should fail for invalid date")
+		}
+	})
+}
+
+func TestNoteService_GetRecentTimelines(t *testing.T) {
+	ns, fs, tmpDir := newTestNoteService(t)
+	defer os.RemoveAll(tmpDir)
+
+	now := time.Now()
+	today := now.Format("2006-01-02")
+	yesterday := now.AddDate(0, 0, -1).Format("2006-01-02")
+	old := now.AddDate(0, 0, -10).Format("2006-01-02")
+
+	fs.CreateFile("dailynotes/"+today+".md", "## Memos\n- 10:00 Today memo\n")
+	fs.CreateFile("dailynotes/"+yesterday+".md", "## Memos\n- 10:00 Yesterday memo\n")
+	fs.CreateFile("dailynotes/"+old+".md", "## Memos\n- 10:00 Old memo\n")
+
+	timelines, err := ns.GetRecentTimelines(3)
+	if err != nil {
+		t.Fatalf("GetRecentTimelines failed: %v", err)
+	}
+	if len(timelines) != 2 {
+		t.Fatalf("Expected 2 timelines, got %d", len(timelines))
+	}
+	if timelines[0].Date != "Today" || timelines[1].Date != "Yesterday" {
+		t.Errorf("Unexpected labels: %q, %q", timelines[0].Date, timelines[1].Date)
+	}
+	if timelines[1].ISODate != yesterday {
+		t.Errorf("ISODate = %q, want %q", timelines[1].ISODate, yesterday)
+	}
+}
+
+func TestNoteService_Rollover(t *testing.T) {
+	previous := `# Today's
+
+## Day Planner
+- [ ] 09:00 Plan the day
+- [ ] 14:00 Review PR
+- [x] 16:00 Finished meeting
+
+## Memos
+- [ ] 10:00 Memo todo stays
+
+## Todo
+- [ ] Write docs
+  - [x] Outline
+  - notes
+- [x] Done already
+- [-] Cancelled
+`
+
+	t.Run("disabled by default", func(t *testing.T) {
+		ns, fs, tmpDir := newTestNoteService(t)
+		defer os.RemoveAll(tmpDir)
+
+		fs.CreateFile("dailynotes/2026-10-17.md", previous)
+		note, err := ns.CreateDailyNote("2026-10-18")
+		if err != nil {
+			t.Fatalf("CreateDailyNote failed: %v", err)
+		}
+		if strings.Contains(note.Content, "Write docs") {
+			t.Error("Tasks should not roll over when disabled")
+		}
+	})
+
+	t.Run("copy open tasks", func(t *testing.T) {
+		ns, fs, tmpDir := newTestNoteService(t)
+		defer os.RemoveAll(tmpDir)
+		ns.configService.config.DailyNotes.Rollover.Enabled = true
+
+		fs.CreateFile("dailynotes/2026-10-15.md", "## Todo\n- [ ] Older task\n")
+		fs.CreateFile("dailynotes/2026-10-17.md", previous)
+
+		note, err := ns.CreateDailyNote("2026-10-18")
+		if err != nil {
+			t.Fatalf("CreateDailyNote failed: %v", err)
+		}
+
+		if !strings.Contains(note.Content, "## Todo\n- [ ] Write docs\n  - [x] Outline\n  - notes\n") {
+			t.Errorf("Todo not rolled over:\n%s", note.Content)
+		}
+		if !strings.Contains(note.Content, "- [ ] 09:00 Plan the day\n- [ ] 14:00 Review PR\n\n## Memos") {
+			t.Errorf("Day Planner not rolled over:\n%s", note.Content)
+		}
+		if strings.Count(note.Content, "09:00 Plan the day") != 1 {
+			t.Errorf("Duplicate task rolled over:\n%s", note.Content)
+		}
+		for _, unexpected := range []string{"Finished meeting", "Memo todo stays", "Done already", "Cancelled", "Older task"} {
+			if strings.Contains(note.Content, unexpected) {
+				t.Errorf("%q should not roll over:\n%s", unexpected, note.Content)
+			}
+		}
+
+		prev, _ := fs.ReadFile("dailynotes/2026-10-17.md")
+		if prev != previous {
+			t.Error("Previous note should be unchanged in copy mode")
+		}
+	})
+
+	t.Run("move open tasks", func(t *testing.T) {
+		ns, fs, tmpDir := newTestNoteService(t)
+		defer os.RemoveAll(tmpDir)
+		ns.configService.config.DailyNotes.Rollover = models.RolloverConfig{
+			Enabled:  true,
+			Mode:     models.RolloverModeMove,
+			Sections: []string{"## Day Planner", "## Todo"},
+		}
+		var saved []string
+		ns.onSave(func(relativePath string) { saved = append(saved, relativePath) })
+
+		fs.CreateFile("dailynotes/2026-10-17.md", previous)
+		if _, err := ns.CreateDailyNote("2026-10-18"); err != nil {
+			t.Fatalf("CreateDailyNote failed: %v", err)
+		}
+		if len(saved) != 2 || saved[1] != filepath.Join("dailynotes", "2026-10-17.md") {
+			t.Errorf("Expected both notes saved through SaveNote, got %v", saved)
+		}
+
+		prev, _ := fs.ReadFile("dailynotes/2026-10-17.md")
+		if strings.Contains(prev, "Write docs") || strings.Contains(prev, "Outline") {
+			t.Errorf("Moved task should be removed from previous note:\n%s", prev)
+		}
+		if strings.Contains(prev, "14:00 Review PR") {
+			t.Errorf("Moved planner task should be removed from previous note:\n%s", prev)
+		}
+		// Already in the new note, so not carried over and kept
+		if !strings.Contains(prev, "- [x] Done already") || !strings.Contains(prev, "09:00 Plan the day") {
+			t.Errorf("Other content should stay:\n%s", prev)
+		}
+	})
+}
+
+func TestNoteService_SaveNoteConflict(t *testing.T) {
+	ns, fs, tmpDir := newTestNoteService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs.CreateFile("note.md", "original")
+	note, _ := ns.GetNote("note.md")
+
+	t.Run("save with matching version", func(t *testing.T) {
+		err := ns.SaveNote("note.md", "edited", models.NoteVersion{Hash: note.Hash})
+		if err != nil {
+			t.Fatalf("SaveNote failed: %v", err)
+		}
+	})
+
+	t.Run("stale hash is rejected", func(t *testing.T) {
+		err := ns.SaveNote("note.md", "overwrite", models.NoteVersion{Hash: note.Hash})
+
+		var conflict *ConflictError
+		if !errors.As(err, &conflict) || !errors.Is(err, ErrConflict) {
+			t.Fatalf("Expected ConflictError, got %v", err)
+		}
+		if conflict.Current.Hash != contentHash("edited") {
+			t.Errorf("Current version hash mismatch")
+		}
+
+		content, _ := fs.ReadFile("note.md")
+		if content != "edited" {
+			t.Errorf("File should not be overwritten: %q", content)
+		}
+	})
+
+	t.Run("stale mtime is rejected", func(t *testing.T) {
+		current, _ := ns.GetNote("note.md")
+		stale := current.ModifiedAt.Add(-time.Hour)
+		os.Chtimes(filepath.Join(tmpDir, "note.md"), current.ModifiedAt, current.ModifiedAt)
+
+		if err := ns.SaveNote("note.md", "x", models.NoteVersion{ModifiedAt: stale}); !errors.Is(err, ErrConflict) {
+			t.Errorf("Expected conflict, got %v", err)
+		}
+		if err := ns.SaveNote("note.md", "x", models.NoteVersion{ModifiedAt: current.ModifiedAt}); err != nil {
+			t.Errorf("SaveNote with current mtime failed: %v", err)
+		}
+	})
+
+	t.Run("deleted file is a conflict", func(t *testing.T) {
+		fs.CreateFile("gone.md", "content")
+		gone, _ := ns.GetNote("gone.md")
+		os.Remove(filepath.Join(tmpDir, "gone.md"))
+
+		var conflict *ConflictError
+		err := ns.SaveNote("gone.md", "content", models.NoteVersion{Hash: gone.Hash})
+		if !errors.As(err, &conflict) || !conflict.Deleted {
+			t.Errorf("Expected deleted conflict, got %v", err)
+		}
+	})
+
+	t.Run("save without version always writes", func(t *testing.T) {
+		if err := ns.SaveNote("note.md", "forced"); err != nil {
+			t.Errorf("SaveNote failed: %v", err)
+		}
+	})
+}
+
+func TestNoteBaseCache_Limits(t *testing.T) {
+	cache := newNoteBaseCache()
+	for i := 0; i < maxNoteBases+2; i++ {
+		cache.remember("note.md", fmt.Sprint("version ", i))
+	}
+	if len(cache.entries["note.md"]) != maxNoteBases {
+		t.Errorf("Expected %d versions, got %d", maxNoteBases, len(cache.entries["note.md"]))
+	}
+	if _, ok := cache.lookup("note.md", contentHash("version 0")); ok {
+		t.Error("Oldest version should be forgotten")
+	}
+
+	for i := 0; i < maxNoteBaseNotes; i++ {
+		cache.remember(fmt.Sprintf("other-%d.md", i), "content")
+	}
+	if len(cache.entries) != maxNoteBaseNotes {
+		t.Errorf("Expected %d notes, got %d", maxNoteBaseNotes, len(cache.entries))
+	}
+	if _, ok := cache.entries["note.md"]; ok {
+		t.Error("Least recently used note should be forgotten")
+	}
+}
+
+func TestNoteService_SaveNoteMerged(t *testing.T) {
+	ns, fs, tmpDir := newTestNoteService(t)
+	defer os.RemoveAll(tmpDir)
+
+	original := "# Note\n\nfirst\nsecond\nthird\n"
+
+	t.Run("unchanged on disk", func(t *testing.T) {
+		fs.CreateFile("plain.md", original)
+		note, _ := ns.GetNote("plain.md")
+
+		result, err := ns.SaveNoteMerged("plain.md", original+"more\n", models.NoteVersion{Hash: note.Hash})
+		if err != nil {
+			t.Fatalf("SaveNoteMerged failed: %v", err)
+		}
+		if result.Status != models.SaveStatusSaved || result.Version.Hash != contentHash(original+"more\n") {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("external change merges cleanly", func(t *testing.T) {
+		fs.CreateFile("merge.md", original)
+		note, _ := ns.GetNote("merge.md")
+
+		// Sync client edits the last line
+		os.WriteFile(filepath.Join(tmpDir, "merge.md"), []byte("# Note\n\nfirst\nsecond\nTHIRD\n"), 0644)
+
+		result, err := ns.SaveNoteMerged("merge.md", "# Note\n\nFIRST\nsecond\nthird\n", models.NoteVersion{Hash: note.Hash})
+		if err != nil {
+			t.Fatalf("SaveNoteMerged failed: %v", err)
+		}
+		expected := "# Note\n\nFIRST\nsecond\nTHIRD\n"
+		if result.Status != models.SaveStatusMerged || result.Content != expected {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+		content, _ := fs.ReadFile("merge.md")
+		if content != expected {
+			t.Errorf("Merged content not written: %q", content)
+		}
+	})
+
+	t.Run("conflict is not written", func(t *testing.T) {
+		fs.CreateFile("conflict.md", original)
+		note, _ := ns.GetNote("conflict.md")
+
+		theirs := "# Note\n\nfirst\nchanged on disk\nthird\n"
+		os.WriteFile(filepath.Join(tmpDir, "conflict.md"), []byte(theirs), 0644)
+
+		ours := "# Note\n\nfirst\nchanged in editor\nthird\n"
+		result, err := ns.SaveNoteMerged("conflict.md", ours, models.NoteVersion{Hash: note.Hash})
+		if err != nil {
+			t.Fatalf("SaveNoteMerged failed: %v", err)
+		}
+		if result.Status != models.SaveStatusConflict || result.Conflicts != 1 {
+			t.Fatalf("Unexpected result: %+v", result)
+		}
+		if result.Ours != ours || result.Theirs != theirs || result.Base != original {
+			t.Errorf("Versions mismatch: %+v", result)
+		}
+		if !strings.Contains(result.Content, conflictMarkerOurs+"\nchanged in editor\n"+conflictMarkerSep+"\nchanged on disk\n"+conflictMarkerTheirs) {
+			t.Errorf("Conflict markers missing:\n%s", result.Content)
+		}
+
+		content, _ := fs.ReadFile("conflict.md")
+		if content != theirs {
+			t.Error("Conflicting save should not be written")
+		}
+
+		// Resolving saves against the version on disk
+		result, err = ns.SaveNoteMerged("conflict.md", "# Note\n\nresolved\n", result.Version)
+		if err != nil || result.Status != models.SaveStatusSaved {
+			t.Errorf("Resolution failed: %+v, %v", result, err)
+		}
+	})
+
+	t.Run("unknown base conflicts on any difference", func(t *testing.T) {
+		fs.CreateFile("unknown.md", "disk\n")
+
+		result, _ := ns.SaveNoteMerged("unknown.md", "editor\n", models.NoteVersion{Hash: "deadbeef"})
+		if result.Status != models.SaveStatusConflict {
+			t.Errorf("Expected conflict, got %+v", result)
+		}
+	})
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/kazuph/obails/models"
+)
+
+// GetBookmarks returns the bookmark groups in order
+func (s *StateService) GetBookmarks() []models.BookmarkGroup {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	groups := make([]models.BookmarkGroup, len(s.state.Bookmarks))
+	for i, group := range s.state.Bookmarks {
+		groups[i] = models.BookmarkGroup{Name: group.Name, Bookmarks: slices.Clone(group.Bookmarks)}
+	}
+	return groups
+}
+
+// AddBookmark adds a bookmark to the end of a group, which is created if needed.
+// An empty group name stands for the default group.
+func (s *StateService) AddBookmark(group string, bookmark models.Bookmark) (*models.Bookmark, error) {
+	switch bookmark.Type {
+	case models.BookmarkTypeNote, models.BookmarkTypeFolder, models.BookmarkTypeHeading:
+		if bookmark.Path == "" {
+			return nil, fmt.Errorf("%s bookmark has no path", bookmark.Type)
+		}
+		if bookmark.Type == models.BookmarkTypeHeading && bookmark.Heading == "" {
+			return nil, fmt.Errorf("heading bookmark has no heading")
+		}
+		key, err := stateKey(bookmark.Path)
+		if err != nil {
+			return nil, err
+		}
+		bookmark.Path = key
+	case models.BookmarkTypeSearch:
+		if bookmark.Query == "" {
+			return nil, fmt.Errorf("search bookmark has no query")
+		}
+	default:
+		return nil, fmt.Errorf("unknown bookmark type %q", bookmark.Type)
+	}
+
+	id, err := newBookmarkID()
+	if err != nil {
+		return nil, err
+	}
+	bookmark.ID = id
+	bookmark.CreatedAt = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.bookmarkGroup(group, true)
+	s.state.Bookmarks[i].Bookmarks = append(s.state.Bookmarks[i].Bookmarks, bookmark)
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return &bookmark, nil
+}
+
+// RemoveBookmark removes a bookmark from its group
+func (s *StateService) RemoveBookmark(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.takeBookmark(id); !ok {
+		return fmt.Errorf("bookmark %s not found", id)
+	}
+	return s.save()
+}
+
+// MoveBookmark moves a bookmark to a position in a group, which is created if needed.
+// Indexes past the end move it to the end.
+func (s *StateService) MoveBookmark(id string, group string, index int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bookmark, ok := s.takeBookmark(id)
+	if !ok {
+		return fmt.Errorf("bookmark %s not found", id)
+	}
+	i := s.bookmarkGroup(group, true)
+	bookmarks := s.state.Bookmarks[i].Bookmarks
+	s.state.Bookmarks[i].Bookmarks = slices.Insert(bookmarks, max(min(index, len(bookmarks)), 0), bookmark)
+	return s.save()
+}
+
+// AddBookmarkGroup adds an empty group after the existing ones
+func (s *StateService) AddBookmarkGroup(name string) error {
+	if name == "" {
+		return fmt.Errorf("bookmark group has no name")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.bookmarkGroup(name, false) >= 0 {
+		return fmt.Errorf("bookmark group %q already exists", name)
+	}
+	s.bookmarkGroup(name, true)
+	return s.save()
+}
+
+// RenameBookmarkGroup renames a group
+func (s *StateService) RenameBookmarkGroup(name string, newName string) error {
+	if newName == "" {
+		return fmt.Errorf("bookmark group has no name")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.bookmarkGroup(name, false)
+	if i < 0 {
+		return fmt.Errorf("bookmark group %q not found", name)
+	}
+	if j := s.bookmarkGroup(newName, false); j >= 0 && j != i {
+		return fmt.Errorf("bookmark group %q already exists", newName)
+	}
+	s.state.Bookmarks[i].Name = newName
+	return s.save()
+}
+
+// RemoveBookmarkGroup removes a group with its bookmarks
+func (s *StateService) RemoveBookmarkGroup(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.bookmarkGroup(name, false)
+	if i < 0 {
+		return fmt.Errorf("bookmark group %q not found", name)
+	}
+	s.state.Bookmarks = slices.Delete(s.state.Bookmarks, i, i+1)
+	return s.save()
+}
+
+// MoveBookmarkGroup moves a group to a position among the groups
+func (s *StateService) MoveBookmarkGroup(name string, index int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.bookmarkGroup(name, false)
+	if i < 0 {
+		return fmt.Errorf("bookmark group %q not found", name)
+	}
+	group := s.state.Bookmarks[i]
+	groups := slices.Delete(s.state.Bookmarks, i, i+1)
+	s.state.Bookmarks = slices.Insert(groups, max(min(index, len(groups)), 0), group)
+	return s.save()
+}
+
+// bookmarkGroup returns the index of a group, or -1 if there is none and create is
+// false. The caller must hold mu.
+func (s *StateService) bookmarkGroup(name string, create bool) int {
+	if name == "" {
+		name = models.DefaultBookmarkGroup
+	}
+	for i, group := range s.state.Bookmarks {
+		if group.Name == name {
+			return i
+		}
+	}
+	if !create {
+		return -1
+	}
+	s.state.Bookmarks = append(s.state.Bookmarks, models.BookmarkGroup{Name: name, Bookmarks: []models.Bookmark{}})
+	return len(s.state.Bookmarks) - 1
+}
+
+// takeBookmark removes a bookmark from its group and returns it. The caller must hold mu.
+func (s *StateService) takeBookmark(id string) (models.Bookmark, bool) {
+	for i, group := range s.state.Bookmarks {
+		for j, bookmark := range group.Bookmarks {
+			if bookmark.ID == id {
+				s.state.Bookmarks[i].Bookmarks = slices.Delete(group.Bookmarks, j, j+1)
+				return bookmark, true
+			}
+		}
+	}
+	return models.Bookmark{}, false
+}
+
+// bookmarksPathDeleted drops the bookmarks of a deleted note or folder and of
+// everything in it. Search bookmarks and emptied groups stay. The caller must hold mu.
+func (s *StateService) bookmarksPathDeleted(deleted string) {
+	for i := range s.state.Bookmarks {
+		group := &s.state.Bookmarks[i]
+		group.Bookmarks = slices.DeleteFunc(group.Bookmarks, func(bookmark models.Bookmark) bool {
+			return bookmark.Path != "" && isSubPath(filepath.FromSlash(bookmark.Path), filepath.FromSlash(deleted))
+		})
+	}
+}
+
+func newBookmarkID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
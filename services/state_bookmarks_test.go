@@ -0,0 +1,208 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kazuph/obails/models"
+)
+
+func TestStateService_Bookmarks(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs := NewFileService(cs)
+	ss := NewStateService(cs, fs)
+
+	add := func(group string, bookmark models.Bookmark) *models.Bookmark {
+		t.Helper()
+		added, err := ss.AddBookmark(group, bookmark)
+		if err != nil {
+			t.Fatalf("AddBookmark failed: %v", err)
+		}
+		return added
+	}
+
+	note := add("", models.Bookmark{Type: models.BookmarkTypeNote, Path: "projects/plan.md"})
+	heading := add("", models.Bookmark{Type: models.BookmarkTypeHeading, Path: "projects/plan.md", Heading: "Goals"})
+	folder := add("Work", models.Bookmark{Type: models.BookmarkTypeFolder, Path: "projects"})
+	search := add("Work", models.Bookmark{Type: models.BookmarkTypeSearch, Query: "tag:#todo", Title: "Todos"})
+
+	t.Run("groups", func(t *testing.T) {
+		groups := ss.GetBookmarks()
+		if len(groups) != 2 || groups[0].Name != models.DefaultBookmarkGroup || groups[1].Name != "Work" {
+			t.Fatalf("Unexpected groups: %+v", groups)
+		}
+		if len(groups[0].Bookmarks) != 2 || groups[0].Bookmarks[0].ID != note.ID || groups[0].Bookmarks[1].ID != heading.ID {
+			t.Errorf("Unexpected default group: %+v", groups[0])
+		}
+		if note.ID == "" || note.ID == heading.ID || note.CreatedAt.IsZero() {
+			t.Errorf("Bookmarks need unique IDs: %+v, %+v", note, heading)
+		}
+	})
+
+	t.Run("invalid bookmarks", func(t *testing.T) {
+		invalid := []models.Bookmark{
+			{Type: models.BookmarkTypeNote},
+			{Type: models.BookmarkTypeHeading, Path: "a.md"},
+			{Type: models.BookmarkTypeSearch},
+			{Type: "tag", Path: "a.md"},
+			{Type: models.BookmarkTypeNote, Path: "../outside.md"},
+		}
+		for _, bookmark := range invalid {
+			if _, err := ss.AddBookmark("", bookmark); err == nil {
+				t.Errorf("Should reject %+v", bookmark)
+			}
+		}
+	})
+
+	t.Run("reorder", func(t *testing.T) {
+		if err := ss.MoveBookmark(search.ID, "Work", 0); err != nil {
+			t.Fatalf("MoveBookmark failed: %v", err)
+		}
+		if err := ss.MoveBookmark(note.ID, "Work", 99); err != nil {
+			t.Fatalf("MoveBookmark failed: %v", err)
+		}
+		groups := ss.GetBookmarks()
+		work := groups[1].Bookmarks
+		if len(groups[0].Bookmarks) != 1 || len(work) != 3 || work[0].ID != search.ID || work[1].ID != folder.ID || work[2].ID != note.ID {
+			t.Errorf("Unexpected order: %+v", groups)
+		}
+
+		if err := ss.MoveBookmarkGroup("Work", 0); err != nil {
+			t.Fatalf("MoveBookmarkGroup failed: %v", err)
+		}
+		if groups := ss.GetBookmarks(); groups[0].Name != "Work" {
+			t.Errorf("Expected Work first, got %+v", groups)
+		}
+		if err := ss.MoveBookmark("missing", "", 0); err == nil {
+			t.Error("Should fail for unknown bookmarks")
+		}
+	})
+
+	t.Run("groups management", func(t *testing.T) {
+		if err := ss.AddBookmarkGroup("Work"); err == nil {
+			t.Error("Should reject duplicate groups")
+		}
+		if err := ss.AddBookmarkGroup("Reading"); err != nil {
+			t.Fatalf("AddBookmarkGroup failed: %v", err)
+		}
+		if err := ss.RenameBookmarkGroup("Reading", "Work"); err == nil {
+			t.Error("Should reject renaming to an existing group")
+		}
+		if err := ss.RenameBookmarkGroup("Reading", "Later"); err != nil {
+			t.Fatalf("RenameBookmarkGroup failed: %v", err)
+		}
+		if err := ss.RemoveBookmarkGroup("Later"); err != nil {
+			t.Fatalf("RemoveBookmarkGroup failed: %v", err)
+		}
+		if len(ss.GetBookmarks()) != 2 {
+			t.Error("Expected 2 groups")
+		}
+	})
+
+	t.Run("paths follow moves", func(t *testing.T) {
+		os.MkdirAll(filepath.Join(tmpDir, "projects"), 0755)
+		os.WriteFile(filepath.Join(tmpDir, "projects", "plan.md"), []byte("# Goals"), 0644)
+
+		if err := fs.MoveFile("projects", "archive/projects"); err != nil {
+			t.Fatalf("MoveFile failed: %v", err)
+		}
+		if err := fs.MoveFile("archive/projects/plan.md", "archive/projects/roadmap.md"); err != nil {
+			t.Fatalf("MoveFile failed: %v", err)
+		}
+
+		paths := map[string]string{}
+		for _, group := range ss.GetBookmarks() {
+			for _, bookmark := range group.Bookmarks {
+				paths[bookmark.ID] = bookmark.Path
+			}
+		}
+		if paths[note.ID] != "archive/projects/roadmap.md" || paths[heading.ID] != "archive/projects/roadmap.md" {
+			t.Errorf("Note bookmarks should follow the note: %v", paths)
+		}
+		if paths[folder.ID] != "archive/projects" || paths[search.ID] != "" {
+			t.Errorf("Unexpected paths: %v", paths)
+		}
+	})
+
+	t.Run("remove and persist", func(t *testing.T) {
+		if err := ss.RemoveBookmark(heading.ID); err != nil {
+			t.Fatalf("RemoveBookmark failed: %v", err)
+		}
+		if err := ss.RemoveBookmark(heading.ID); err == nil {
+			t.Error("Should fail for removed bookmarks")
+		}
+
+		reloaded := NewStateService(cs, NewFileService(cs))
+		if err := reloaded.Load(); err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		groups := reloaded.GetBookmarks()
+		if len(groups) != 2 || len(groups[0].Bookmarks) != 3 || len(groups[1].Bookmarks) != 0 {
+			t.Errorf("Unexpected reloaded bookmarks: %+v", groups)
+		}
+	})
+}
+
+func TestStateService_PathMoved(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs := NewFileService(cs)
+	ss := NewStateService(cs, fs)
+
+	os.MkdirAll(filepath.Join(tmpDir, "notes"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "notes", "a.md"), nil, 0644)
+	ss.SetLastOpenedFile("notes/a.md", models.FileTypeMarkdown)
+	ss.PinFile("notes/a.md")
+	ss.SetManualOrder("notes", []string{"b.md", "a.md"})
+
+	if err := fs.MoveFile("notes/a.md", "notes/c.md"); err != nil {
+		t.Fatalf("MoveFile failed: %v", err)
+	}
+	if err := fs.MoveFile("notes", "journal"); err != nil {
+		t.Fatalf("MoveFile failed: %v", err)
+	}
+
+	if last := ss.GetLastOpenedFile(); last == nil || filepath.ToSlash(last.Path) != "journal/c.md" {
+		t.Errorf("Unexpected last opened file: %+v", last)
+	}
+	if pinned := ss.GetPinnedFiles(); len(pinned) != 1 || pinned[0] != "journal/c.md" {
+		t.Errorf("Unexpected pinned files: %v", pinned)
+	}
+	if sort, _ := ss.GetTreeSort("journal"); sort.Mode != models.TreeSortManual {
+		t.Errorf("The folder sort should move along, got %+v", sort)
+	}
+	if order := ss.folderOrder("journal"); len(order.manual) != 2 || order.manual[1] != "c.md" {
+		t.Errorf("Unexpected manual order: %v", order.manual)
+	}
+}
+
+func TestStateService_BookmarksPathDeleted(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs := NewFileService(cs)
+	ss := NewStateService(cs, fs)
+
+	os.MkdirAll(filepath.Join(tmpDir, "projects"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "projects", "plan.md"), nil, 0644)
+	os.WriteFile(filepath.Join(tmpDir, "projects.md"), nil, 0644)
+	ss.AddBookmark("", models.Bookmark{Type: models.BookmarkTypeHeading, Path: "projects/plan.md", Heading: "Goals"})
+	ss.AddBookmark("", models.Bookmark{Type: models.BookmarkTypeNote, Path: "projects.md"})
+	ss.AddBookmark("Work", models.Bookmark{Type: models.BookmarkTypeFolder, Path: "projects"})
+	ss.AddBookmark("Work", models.Bookmark{Type: models.BookmarkTypeSearch, Query: "projects"})
+
+	if err := fs.DeletePath("projects"); err != nil {
+		t.Fatalf("DeletePath failed: %v", err)
+	}
+	groups := ss.GetBookmarks()
+	if len(groups) != 2 || len(groups[0].Bookmarks) != 1 || groups[0].Bookmarks[0].Path != "projects.md" {
+		t.Errorf("Expected only the bookmarks in the deleted folder to be removed: %+v", groups)
+	}
+	if len(groups[1].Bookmarks) != 1 || groups[1].Bookmarks[0].Type != models.BookmarkTypeSearch {
+		t.Errorf("Expected the search bookmark to stay: %+v", groups[1])
+	}
+}
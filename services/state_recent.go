@@ -0,0 +1,190 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/kazuph/obails/models"
+)
+
+// Size limits of the recent files and the navigation history
+const (
+	maxRecentFiles       = 50
+	maxNavigationEntries = 100
+)
+
+// RecordFileOpened adds an opened file to the recent files and the navigation
+// history. Entries forward of the current one are dropped, as in a browser.
+func (s *StateService) RecordFileOpened(relativePath string, fileType string) error {
+	key, err := stateKey(relativePath)
+	if err != nil {
+		return err
+	}
+	if key == "." {
+		return &VaultPathError{Path: relativePath, Err: ErrVaultRoot}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recent := slices.DeleteFunc(s.state.RecentFiles, func(file models.RecentFile) bool {
+		return file.Path == key
+	})
+	recent = slices.Insert(recent, 0, models.RecentFile{Path: key, FileType: fileType, OpenedAt: time.Now()})
+	s.state.RecentFiles = recent[:min(len(recent), maxRecentFiles)]
+
+	nav := &s.state.Navigation
+	if current := s.currentNavigationEntry(); current == nil || current.Path != key {
+		kept := nav.Entries[:max(min(nav.Index+1, len(nav.Entries)), 0)]
+		entries := append(kept, models.NavigationEntry{Path: key, FileType: fileType})
+		if len(entries) > maxNavigationEntries {
+			entries = entries[len(entries)-maxNavigationEntries:]
+		}
+		nav.Entries = entries
+		nav.Index = len(entries) - 1
+	}
+	return s.save()
+}
+
+// GetRecentFiles returns the recently opened files, most recent first. Files that no
+// longer exist are dropped.
+func (s *StateService) GetRecentFiles() []models.RecentFile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := len(s.state.RecentFiles)
+	s.state.RecentFiles = slices.DeleteFunc(s.state.RecentFiles, func(file models.RecentFile) bool {
+		return !s.fileExists(file.Path)
+	})
+	if len(s.state.RecentFiles) != before {
+		s.save() // Best effort, the list is pruned again next time
+	}
+	return append([]models.RecentFile{}, s.state.RecentFiles...)
+}
+
+// ClearRecentFiles empties the recent files
+func (s *StateService) ClearRecentFiles() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.RecentFiles = nil
+	return s.save()
+}
+
+// GetNavigation returns the back/forward navigation history
+func (s *StateService) GetNavigation() models.NavigationHistory {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return models.NavigationHistory{
+		Entries: append([]models.NavigationEntry{}, s.state.Navigation.Entries...),
+		Index:   s.state.Navigation.Index,
+	}
+}
+
+// NavigateBack moves to the previous file in the navigation history and returns it,
+// or nil at the start of the history. Files that no longer exist are skipped.
+func (s *StateService) NavigateBack() (*models.NavigationEntry, error) {
+	return s.navigate(-1)
+}
+
+// NavigateForward moves to the next file in the navigation history and returns it,
+// or nil at the end of the history. Files that no longer exist are skipped.
+func (s *StateService) NavigateForward() (*models.NavigationEntry, error) {
+	return s.navigate(1)
+}
+
+func (s *StateService) navigate(step int) (*models.NavigationEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := s.currentNavigationEntry()
+	currentRemoved := current != nil && !s.fileExists(current.Path)
+	s.removeNavigationEntries(func(entry models.NavigationEntry) bool {
+		return !s.fileExists(entry.Path)
+	})
+
+	// Without the current entry, the closest earlier one is already a step back
+	nav := &s.state.Navigation
+	target := nav.Index + step
+	if currentRemoved && step < 0 {
+		target = nav.Index
+	}
+	if target < 0 || target >= len(nav.Entries) {
+		return nil, s.save()
+	}
+	nav.Index = target
+	entry := nav.Entries[target]
+	return &entry, s.save()
+}
+
+// recentPathDeleted drops the recent files and navigation entries of a deleted file
+// or folder. The caller must hold mu.
+func (s *StateService) recentPathDeleted(deleted string) {
+	removed := func(p string) bool {
+		return isSubPath(filepath.FromSlash(p), filepath.FromSlash(deleted))
+	}
+	s.state.RecentFiles = slices.DeleteFunc(s.state.RecentFiles, func(file models.RecentFile) bool {
+		return removed(file.Path)
+	})
+	s.removeNavigationEntries(func(entry models.NavigationEntry) bool {
+		return removed(entry.Path)
+	})
+}
+
+// recentPathMoved updates the recent files and navigation entries after a file or
+// folder was moved. The caller must hold mu.
+func (s *StateService) recentPathMoved(from string, to string) {
+	for i := range s.state.RecentFiles {
+		s.state.RecentFiles[i].Path, _ = movedPath(s.state.RecentFiles[i].Path, from, to)
+	}
+	for i := range s.state.Navigation.Entries {
+		s.state.Navigation.Entries[i].Path, _ = movedPath(s.state.Navigation.Entries[i].Path, from, to)
+	}
+}
+
+// removeNavigationEntries drops matching entries from the navigation history. The
+// current entry becomes the closest earlier one kept, and entries that end up next
+// to an entry for the same file are merged. The caller must hold mu.
+func (s *StateService) removeNavigationEntries(remove func(entry models.NavigationEntry) bool) {
+	nav := &s.state.Navigation
+	kept := []models.NavigationEntry{}
+	index := 0
+	for i, entry := range nav.Entries {
+		if !remove(entry) && (len(kept) == 0 || kept[len(kept)-1].Path != entry.Path) {
+			kept = append(kept, entry)
+		}
+		if i == nav.Index {
+			index = max(len(kept)-1, 0)
+		}
+	}
+	nav.Entries = kept
+	nav.Index = index
+}
+
+// currentNavigationEntry returns the current entry of the navigation history, or nil
+// if it is empty. The caller must hold mu.
+func (s *StateService) currentNavigationEntry() *models.NavigationEntry {
+	nav := &s.state.Navigation
+	if nav.Index < 0 || nav.Index >= len(nav.Entries) {
+		return nil
+	}
+	return &nav.Entries[nav.Index]
+}
+
+// fileExists reports whether a file in the state still exists. Without a vault
+// nothing can be checked, so everything counts as existing.
+func (s *StateService) fileExists(key string) bool {
+	vaultPath := s.configService.GetVaultPath()
+	if vaultPath == "" {
+		return true
+	}
+	fullPath, err := resolveInVault(vaultPath, key)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(fullPath)
+	return err == nil
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kazuph/obails/models"
+)
+
+func TestStateService_RecentFiles(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs := NewFileService(cs)
+	ss := NewStateService(cs, fs)
+
+	for _, name := range []string{"a.md", "b.md", "c.md"} {
+		os.WriteFile(filepath.Join(tmpDir, name), nil, 0644)
+	}
+
+	recentPaths := func() string {
+		var paths []string
+		for _, file := range ss.GetRecentFiles() {
+			paths = append(paths, file.Path)
+		}
+		return fmt.Sprint(paths)
+	}
+
+	for _, name := range []string{"a.md", "b.md", "a.md", "c.md"} {
+		if err := ss.RecordFileOpened(name, models.FileTypeMarkdown); err != nil {
+			t.Fatalf("RecordFileOpened failed: %v", err)
+		}
+	}
+	if got := recentPaths(); got != "[c.md a.md b.md]" {
+		t.Errorf("Unexpected recent files %s", got)
+	}
+
+	t.Run("limit", func(t *testing.T) {
+		for i := range maxNavigationEntries + 5 {
+			ss.RecordFileOpened(fmt.Sprintf("gone%d.md", i), models.FileTypeMarkdown)
+		}
+		if n := len(ss.state.RecentFiles); n != maxRecentFiles {
+			t.Errorf("Expected %d recent files, got %d", maxRecentFiles, n)
+		}
+		if n := len(ss.state.Navigation.Entries); n != maxNavigationEntries {
+			t.Errorf("Expected %d navigation entries, got %d", maxNavigationEntries, n)
+		}
+		// Files that don't exist are dropped when listed
+		if got := recentPaths(); got != "[]" {
+			t.Errorf("Expected missing files to be dropped, got %s", got)
+		}
+	})
+
+	t.Run("moved and deleted files", func(t *testing.T) {
+		ss.ClearRecentFiles()
+		ss.RecordFileOpened("a.md", models.FileTypeMarkdown)
+		ss.RecordFileOpened("b.md", models.FileTypeMarkdown)
+
+		if err := fs.MoveFile("a.md", "notes/a.md"); err != nil {
+			t.Fatalf("MoveFile failed: %v", err)
+		}
+		if got := recentPaths(); got != "[b.md notes/a.md]" {
+			t.Errorf("Unexpected recent files after move %s", got)
+		}
+		if err := fs.DeletePath("notes"); err != nil {
+			t.Fatalf("DeletePath failed: %v", err)
+		}
+		if got := fmt.Sprint(ss.state.RecentFiles); len(ss.state.RecentFiles) != 1 {
+			t.Errorf("Deleted files should be dropped: %s", got)
+		}
+	})
+}
+
+func TestStateService_Navigation(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs := NewFileService(cs)
+	ss := NewStateService(cs, fs)
+
+	for _, name := range []string{"a.md", "b.md", "c.md", "d.md"} {
+		os.WriteFile(filepath.Join(tmpDir, name), nil, 0644)
+	}
+	open := func(names ...string) {
+		for _, name := range names {
+			if err := ss.RecordFileOpened(name, models.FileTypeMarkdown); err != nil {
+				t.Fatalf("RecordFileOpened failed: %v", err)
+			}
+		}
+	}
+	step := func(back bool) string {
+		t.Helper()
+		var entry *models.NavigationEntry
+		var err error
+		if back {
+			entry, err = ss.NavigateBack()
+		} else {
+			entry, err = ss.NavigateForward()
+		}
+		if err != nil {
+			t.Fatalf("Navigate failed: %v", err)
+		}
+		if entry == nil {
+			return ""
+		}
+		return entry.Path
+	}
+
+	open("a.md", "b.md", "b.md", "c.md")
+	if nav := ss.GetNavigation(); len(nav.Entries) != 3 || nav.Index != 2 {
+		t.Fatalf("Reopening the current file should not add an entry: %+v", nav)
+	}
+
+	if got := step(true); got != "b.md" {
+		t.Errorf("Expected b.md, got %q", got)
+	}
+	if got := step(true); got != "a.md" {
+		t.Errorf("Expected a.md, got %q", got)
+	}
+	if got := step(true); got != "" {
+		t.Errorf("Expected the start of the history, got %q", got)
+	}
+	if got := step(false); got != "b.md" {
+		t.Errorf("Expected b.md, got %q", got)
+	}
+
+	// Opening a file drops the forward entries
+	open("d.md")
+	if got := step(false); got != "" {
+		t.Errorf("Expected the end of the history, got %q", got)
+	}
+	if nav := ss.GetNavigation(); fmt.Sprint(len(nav.Entries), nav.Index) != "3 2" {
+		t.Errorf("Unexpected history: %+v", nav)
+	}
+
+	t.Run("missing files are skipped", func(t *testing.T) {
+		// a b d, at d: d vanishes outside the app and b is deleted
+		os.Remove(filepath.Join(tmpDir, "d.md"))
+		fs.DeletePath("b.md")
+		if got := step(true); got != "a.md" {
+			t.Errorf("Expected a.md, got %q", got)
+		}
+		if nav := ss.GetNavigation(); len(nav.Entries) != 1 || nav.Index != 0 {
+			t.Errorf("Unexpected history: %+v", nav)
+		}
+	})
+
+	t.Run("persisted", func(t *testing.T) {
+		reloaded := NewStateService(cs, NewFileService(cs))
+		if err := reloaded.Load(); err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if nav := reloaded.GetNavigation(); len(nav.Entries) != 1 || nav.Entries[0].Path != "a.md" {
+			t.Errorf("Unexpected reloaded history: %+v", nav)
+		}
+	})
+}
@@ -2,8 +2,12 @@ package services
 
 import (
 	"encoding/json"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/kazuph/obails/models"
 )
@@ -11,15 +15,22 @@ import (
 // StateService handles application session state stored in vault
 type StateService struct {
 	configService *ConfigService
+	mu            sync.Mutex
 	state         *models.State
 }
 
-// NewStateService creates a new StateService
-func NewStateService(configService *ConfigService) *StateService {
-	return &StateService{
+// NewStateService creates a new StateService. The file tree is sorted in the order
+// kept in the state.
+func NewStateService(configService *ConfigService, fileService *FileService) *StateService {
+	s := &StateService{
 		configService: configService,
 		state:         models.DefaultState(),
 	}
+	fileService.orderTreeBy(s.folderOrder)
+	fileService.onMove(s.pathMoved)
+	fileService.onDelete(s.pathDeleted)
+	fileService.onRestore(s.pathRestored)
+	return s
 }
 
 // getStatePath returns the path to the state file
@@ -33,6 +44,12 @@ func (s *StateService) getStatePath() string {
 
 // Load reads state from file
 func (s *StateService) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// State of a previously open vault must not carry over
+	s.state = models.DefaultState()
+
 	statePath := s.getStatePath()
 	if statePath == "" {
 		return nil
@@ -49,11 +66,26 @@ func (s *StateService) Load() error {
 		return err
 	}
 
-	return json.Unmarshal(data, s.state)
+	state := &models.State{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return err
+	}
+	s.state = state
+	if migrateState(state) {
+		return s.save()
+	}
+	return nil
 }
 
 // Save writes state to file
 func (s *StateService) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.save()
+}
+
+// save writes state to file. The caller must hold mu.
+func (s *StateService) save() error {
 	statePath := s.getStatePath()
 	if statePath == "" {
 		return nil
@@ -71,25 +103,124 @@ func (s *StateService) Save() error {
 		return err
 	}
 
-	return os.WriteFile(statePath, data, 0644)
+	return atomicWriteFile(statePath, data, 0644)
 }
 
 // SetLastOpenedFile sets the last opened file and saves
 func (s *StateService) SetLastOpenedFile(path string, fileType string) error {
+	cleaned, err := cleanRelativePath(path)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	s.state.LastOpenedFile = &models.LastOpenedFile{
-		Path:     path,
+		Path:     cleaned,
 		FileType: fileType,
 	}
-	return s.Save()
+	return s.save()
 }
 
 // GetLastOpenedFile returns the last opened file information
 func (s *StateService) GetLastOpenedFile() *models.LastOpenedFile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.state.LastOpenedFile
 }
 
 // ClearLastOpenedFile clears the last opened file and saves
 func (s *StateService) ClearLastOpenedFile() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	s.state.LastOpenedFile = nil
-	return s.Save()
+	return s.save()
+}
+
+// pathMoved updates the paths in the state after a file or folder was moved
+func (s *StateService) pathMoved(from string, to string) {
+	from, to = filepath.ToSlash(from), filepath.ToSlash(to)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if last := s.state.LastOpenedFile; last != nil {
+		if moved, ok := movedPath(filepath.ToSlash(last.Path), from, to); ok {
+			last.Path = filepath.FromSlash(moved)
+		}
+	}
+
+	tree := &s.state.FileTree
+	for i, pinned := range tree.Pinned {
+		tree.Pinned[i], _ = movedPath(pinned, from, to)
+	}
+	for folder, sort := range maps.Clone(tree.Sort) {
+		if moved, ok := movedPath(folder, from, to); ok {
+			delete(tree.Sort, folder)
+			tree.Sort[moved] = sort
+		}
+	}
+	for folder, names := range maps.Clone(tree.ManualOrder) {
+		if moved, ok := movedPath(folder, from, to); ok {
+			delete(tree.ManualOrder, folder)
+			tree.ManualOrder[moved] = names
+		}
+	}
+	// A renamed entry keeps its place in the manual order of its folder
+	if dir := path.Dir(from); dir == path.Dir(to) {
+		for i, name := range tree.ManualOrder[dir] {
+			if name == path.Base(from) {
+				tree.ManualOrder[dir][i] = path.Base(to)
+			}
+		}
+	}
+
+	s.workspacePathMoved(from, to)
+	s.recentPathMoved(from, to)
+
+	for _, group := range s.state.Bookmarks {
+		for i := range group.Bookmarks {
+			if group.Bookmarks[i].Path != "" {
+				group.Bookmarks[i].Path, _ = movedPath(group.Bookmarks[i].Path, from, to)
+			}
+		}
+	}
+
+	s.save() // Best effort, the move itself succeeded
+}
+
+// pathDeleted drops the tree order, bookmarks, recently opened entries, tabs and
+// note positions of a deleted file or folder
+func (s *StateService) pathDeleted(relativePath string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := filepath.ToSlash(relativePath)
+	s.treePathDeleted(deleted)
+	s.bookmarksPathDeleted(deleted)
+	s.recentPathDeleted(deleted)
+	s.workspacePathDeleted(deleted)
+	s.save() // Best effort, the deletion itself succeeded
+}
+
+// pathRestored updates the state after a file or folder came back from the trash.
+// Restored under another name, its entries follow it as if it was moved.
+func (s *StateService) pathRestored(original string, restored string) {
+	if original != restored {
+		s.pathMoved(original, restored)
+	}
+}
+
+// movedPath returns where a path is after from was moved to to. Paths inside a
+// moved folder move along.
+func movedPath(p string, from string, to string) (string, bool) {
+	if p == from {
+		return to, true
+	}
+	if rest, ok := strings.CutPrefix(p, from+"/"); ok {
+		return to + "/" + rest, true
+	}
+	return p, false
 }
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"path/filepath"
+	"slices"
+
+	"github.com/kazuph/obails/models"
+)
+
+var treeSortModes = map[string]bool{
+	models.TreeSortName:     true,
+	models.TreeSortNatural:  true,
+	models.TreeSortCreated:  true,
+	models.TreeSortModified: true,
+	models.TreeSortSize:     true,
+	models.TreeSortManual:   true,
+}
+
+// GetTreeSort returns the sort order of a folder. Folders without one use the order
+// of the closest parent folder that has one.
+func (s *StateService) GetTreeSort(folder string) (models.TreeSort, error) {
+	key, err := stateKey(folder)
+	if err != nil {
+		return models.TreeSort{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.treeSort(key), nil
+}
+
+// SetTreeSort sets the sort order of a folder and the subfolders without their own.
+// An empty folder is the vault root.
+func (s *StateService) SetTreeSort(folder string, sort models.TreeSort) error {
+	if !treeSortModes[sort.Mode] {
+		return fmt.Errorf("unknown sort mode %q", sort.Mode)
+	}
+	key, err := stateKey(folder)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state.FileTree.Sort == nil {
+		s.state.FileTree.Sort = make(map[string]models.TreeSort)
+	}
+	s.state.FileTree.Sort[key] = sort
+	return s.save()
+}
+
+// ClearTreeSort removes the sort order of a folder, so it uses its parent's again
+func (s *StateService) ClearTreeSort(folder string) error {
+	key, err := stateKey(folder)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.state.FileTree.Sort, key)
+	return s.save()
+}
+
+// SetManualOrder sets the order of the entries of a folder by name and sorts the
+// folder manually. Entries missing from names follow in natural order.
+func (s *StateService) SetManualOrder(folder string, names []string) error {
+	key, err := stateKey(folder)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state.FileTree.ManualOrder == nil {
+		s.state.FileTree.ManualOrder = make(map[string][]string)
+	}
+	if s.state.FileTree.Sort == nil {
+		s.state.FileTree.Sort = make(map[string]models.TreeSort)
+	}
+	s.state.FileTree.ManualOrder[key] = slices.Clone(names)
+	s.state.FileTree.Sort[key] = models.TreeSort{Mode: models.TreeSortManual}
+	return s.save()
+}
+
+// GetPinnedFiles returns the pinned files and folders in pin order
+func (s *StateService) GetPinnedFiles() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.state.FileTree.Pinned...)
+}
+
+// PinFile lists a file or folder first in its folder
+func (s *StateService) PinFile(relativePath string) error {
+	key, err := stateKey(relativePath)
+	if err != nil {
+		return err
+	}
+	if key == "." {
+		return &VaultPathError{Path: relativePath, Err: ErrVaultRoot}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slices.Contains(s.state.FileTree.Pinned, key) {
+		return nil
+	}
+	s.state.FileTree.Pinned = append(s.state.FileTree.Pinned, key)
+	return s.save()
+}
+
+// UnpinFile removes a file or folder from the pinned entries
+func (s *StateService) UnpinFile(relativePath string) error {
+	key, err := stateKey(relativePath)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.FileTree.Pinned = slices.DeleteFunc(s.state.FileTree.Pinned, func(pinned string) bool {
+		return pinned == key
+	})
+	return s.save()
+}
+
+// folderOrder returns the order of the entries of a folder in the file tree
+func (s *StateService) folderOrder(folder string) treeOrder {
+	key := filepath.ToSlash(filepath.Clean(folder))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order := treeOrder{
+		sort:   s.treeSort(key),
+		manual: slices.Clone(s.state.FileTree.ManualOrder[key]),
+	}
+	for _, pinned := range s.state.FileTree.Pinned {
+		if path.Dir(pinned) == key {
+			order.pinned = append(order.pinned, pinned)
+		}
+	}
+	return order
+}
+
+// treePathDeleted forgets the pins, sort orders and manual order places of a
+// deleted file or folder and of everything in it. The caller must hold mu.
+func (s *StateService) treePathDeleted(deleted string) {
+	removed := func(p string) bool {
+		return isSubPath(filepath.FromSlash(p), filepath.FromSlash(deleted))
+	}
+
+	tree := &s.state.FileTree
+	tree.Pinned = slices.DeleteFunc(tree.Pinned, removed)
+	maps.DeleteFunc(tree.Sort, func(folder string, _ models.TreeSort) bool {
+		return removed(folder)
+	})
+	maps.DeleteFunc(tree.ManualOrder, func(folder string, _ []string) bool {
+		return removed(folder)
+	})
+
+	dir := path.Dir(deleted)
+	if names, ok := tree.ManualOrder[dir]; ok {
+		names = slices.DeleteFunc(names, func(name string) bool {
+			return name == path.Base(deleted)
+		})
+		if len(names) == 0 {
+			delete(tree.ManualOrder, dir)
+		} else {
+			tree.ManualOrder[dir] = names
+		}
+	}
+}
+
+// treeSort returns the sort order of a folder or its closest parent with one.
+// The caller must hold mu.
+func (s *StateService) treeSort(key string) models.TreeSort {
+	for {
+		if sort, ok := s.state.FileTree.Sort[key]; ok {
+			return sort
+		}
+		if key == "." {
+			return models.TreeSort{}
+		}
+		key = path.Dir(key)
+	}
+}
+
+// stateKey cleans a vault-relative path for use in the state, with forward slashes
+func stateKey(relativePath string) (string, error) {
+	cleaned, err := cleanRelativePath(relativePath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(cleaned), nil
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kazuph/obails/models"
+)
+
+func TestStateService_TreeOrder(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+
+	// Sizes and modification times increase with the listed order
+	names := []string{"Note 10.md", "note 2.md", "B.md", "a.md"}
+	for i, name := range names {
+		path := filepath.Join(tmpDir, name)
+		os.WriteFile(path, make([]byte, i+1), 0644)
+		mtime := time.Now().Add(time.Duration(i-10) * time.Minute)
+		os.Chtimes(path, mtime, mtime)
+	}
+	os.MkdirAll(filepath.Join(tmpDir, "sub", "deeper"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "sub", "x10.md"), nil, 0644)
+	os.WriteFile(filepath.Join(tmpDir, "sub", "x9.md"), nil, 0644)
+
+	fs := NewFileService(cs)
+	ss := NewStateService(cs, fs)
+
+	list := func(folder string) string {
+		t.Helper()
+		entries, err := fs.ListDirectory(folder)
+		if err != nil {
+			t.Fatalf("ListDirectory failed: %v", err)
+		}
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name)
+		}
+		return fmt.Sprint(names)
+	}
+
+	t.Run("default order", func(t *testing.T) {
+		if got := list(""); got != "[sub a.md B.md note 2.md Note 10.md]" {
+			t.Errorf("Unexpected order %s", got)
+		}
+	})
+
+	t.Run("sort modes", func(t *testing.T) {
+		tests := []struct {
+			sort models.TreeSort
+			want string
+		}{
+			{models.TreeSort{Mode: models.TreeSortName}, "[sub a.md B.md Note 10.md note 2.md]"},
+			{models.TreeSort{Mode: models.TreeSortNatural}, "[sub a.md B.md note 2.md Note 10.md]"},
+			{models.TreeSort{Mode: models.TreeSortSize, Descending: true}, "[sub a.md B.md note 2.md Note 10.md]"},
+			{models.TreeSort{Mode: models.TreeSortModified}, "[sub Note 10.md note 2.md B.md a.md]"},
+		}
+		for _, tt := range tests {
+			if err := ss.SetTreeSort("", tt.sort); err != nil {
+				t.Fatalf("SetTreeSort failed: %v", err)
+			}
+			if got := list(""); got != tt.want {
+				t.Errorf("%+v: got %s, want %s", tt.sort, got, tt.want)
+			}
+		}
+
+		if err := ss.SetTreeSort("", models.TreeSort{Mode: "color"}); err == nil {
+			t.Error("Should reject unknown modes")
+		}
+	})
+
+	t.Run("inherited by subfolders", func(t *testing.T) {
+		ss.SetTreeSort("", models.TreeSort{Mode: models.TreeSortNatural, Descending: true})
+		if got := list("sub"); got != "[deeper x10.md x9.md]" {
+			t.Errorf("Unexpected order %s", got)
+		}
+		ss.SetTreeSort("sub", models.TreeSort{Mode: models.TreeSortNatural})
+		if got := list("sub"); got != "[deeper x9.md x10.md]" {
+			t.Errorf("Unexpected order %s", got)
+		}
+		ss.ClearTreeSort("sub")
+		if sort, _ := ss.GetTreeSort("sub/deeper"); !sort.Descending {
+			t.Errorf("Expected the root order, got %+v", sort)
+		}
+	})
+
+	t.Run("pinned and manual order", func(t *testing.T) {
+		if err := ss.SetManualOrder("", []string{"B.md", "Note 10.md"}); err != nil {
+			t.Fatalf("SetManualOrder failed: %v", err)
+		}
+		if got := list(""); got != "[sub B.md Note 10.md a.md note 2.md]" {
+			t.Errorf("Unexpected manual order %s", got)
+		}
+
+		ss.PinFile("note 2.md")
+		ss.PinFile("a.md")
+		ss.PinFile("a.md")
+		if got := list(""); got != "[note 2.md a.md sub B.md Note 10.md]" {
+			t.Errorf("Unexpected pinned order %s", got)
+		}
+		if pinned := ss.GetPinnedFiles(); len(pinned) != 2 {
+			t.Errorf("Expected 2 pinned files, got %v", pinned)
+		}
+
+		ss.UnpinFile("note 2.md")
+		if got := list(""); got != "[a.md sub B.md Note 10.md note 2.md]" {
+			t.Errorf("Unexpected order after unpinning %s", got)
+		}
+		if err := ss.PinFile(""); err == nil {
+			t.Error("Should not pin the vault root")
+		}
+	})
+
+	t.Run("persisted", func(t *testing.T) {
+		reloaded := NewStateService(cs, NewFileService(cs))
+		if err := reloaded.Load(); err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if sort, _ := reloaded.GetTreeSort(""); sort.Mode != models.TreeSortManual {
+			t.Errorf("Expected manual sort, got %+v", sort)
+		}
+		if pinned := reloaded.GetPinnedFiles(); len(pinned) != 1 || pinned[0] != "a.md" {
+			t.Errorf("Unexpected pinned files %v", pinned)
+		}
+	})
+}
+
+func TestStateService_TreePathDeleted(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+
+	os.MkdirAll(filepath.Join(tmpDir, "sub", "deeper"), 0755)
+	for _, name := range []string{"a.md", "b.md", filepath.Join("sub", "c.md")} {
+		os.WriteFile(filepath.Join(tmpDir, name), nil, 0644)
+	}
+
+	fs := NewFileService(cs)
+	ss := NewStateService(cs, fs)
+	ss.PinFile("a.md")
+	ss.PinFile("sub/c.md")
+	ss.SetManualOrder("", []string{"b.md", "sub", "a.md"})
+	ss.SetManualOrder("sub", []string{"c.md"})
+	ss.SetTreeSort("sub/deeper", models.TreeSort{Mode: models.TreeSortName})
+
+	if err := fs.DeletePath("a.md"); err != nil {
+		t.Fatalf("DeletePath failed: %v", err)
+	}
+	if pinned := ss.GetPinnedFiles(); len(pinned) != 1 || pinned[0] != "sub/c.md" {
+		t.Errorf("Expected the deleted file to be unpinned, got %v", pinned)
+	}
+	if got := fmt.Sprint(ss.state.FileTree.ManualOrder["."]); got != "[b.md sub]" {
+		t.Errorf("Expected the deleted file out of the manual order, got %s", got)
+	}
+
+	if err := fs.DeletePath("sub"); err != nil {
+		t.Fatalf("DeletePath failed: %v", err)
+	}
+	tree := ss.state.FileTree
+	if _, ok := tree.Sort["sub/deeper"]; ok || len(tree.Pinned) != 0 || fmt.Sprint(tree.ManualOrder) != "map[.:[b.md]]" {
+		t.Errorf("Expected the state of the deleted folder to be gone, got %+v", tree)
+	}
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"maps"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/kazuph/obails/models"
+)
+
+// maxNoteViews caps the remembered note positions; the least recently updated go
+// first, down to noteViewsKept so evictions are rare
+const (
+	maxNoteViews  = 500
+	noteViewsKept = maxNoteViews * 9 / 10
+)
+
+// stateMigrations upgrade state.json from the version at their index to the next one.
+// There must be one per version up to models.CurrentStateVersion.
+var stateMigrations = []func(state *models.State){
+	// 0 → 1: the last opened file becomes the only tab of the workspace
+	func(state *models.State) {
+		if last := state.LastOpenedFile; last != nil && len(state.Workspace.Panes) == 0 {
+			state.Workspace.Panes = []models.WorkspacePane{{
+				Tabs: []models.WorkspaceTab{{Path: filepath.ToSlash(last.Path), FileType: last.FileType}},
+			}}
+		}
+	},
+}
+
+// migrateState upgrades state written by an older version of the app and reports
+// whether it changed. State from a newer version is used as is.
+func migrateState(state *models.State) bool {
+	version := max(state.Version, 0)
+	if version >= len(stateMigrations) {
+		return false
+	}
+	for ; version < len(stateMigrations); version++ {
+		stateMigrations[version](state)
+	}
+	state.Version = version
+	return true
+}
+
+// GetWorkspace returns the saved layout of the app
+func (s *StateService) GetWorkspace() models.Workspace {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	workspace := s.state.Workspace
+	workspace.Panes = make([]models.WorkspacePane, len(s.state.Workspace.Panes))
+	for i, pane := range s.state.Workspace.Panes {
+		pane.Tabs = slices.Clone(pane.Tabs)
+		workspace.Panes[i] = pane
+	}
+	workspace.ExpandedFolders = append([]string{}, workspace.ExpandedFolders...)
+	return workspace
+}
+
+// SaveWorkspace replaces the saved layout of the app. Active pane and tab indexes
+// are clamped to the open panes and tabs.
+func (s *StateService) SaveWorkspace(workspace models.Workspace) error {
+	panes := make([]models.WorkspacePane, 0, len(workspace.Panes))
+	for _, pane := range workspace.Panes {
+		tabs := make([]models.WorkspaceTab, 0, len(pane.Tabs))
+		for _, tab := range pane.Tabs {
+			key, err := stateKey(tab.Path)
+			if err != nil {
+				return err
+			}
+			tabs = append(tabs, models.WorkspaceTab{Path: key, FileType: tab.FileType})
+		}
+		pane.Tabs = tabs
+		pane.ActiveTab = clampIndex(pane.ActiveTab, len(tabs))
+		panes = append(panes, pane)
+	}
+	workspace.Panes = panes
+	workspace.ActivePane = clampIndex(workspace.ActivePane, len(panes))
+
+	folders := make([]string, 0, len(workspace.ExpandedFolders))
+	for _, folder := range workspace.ExpandedFolders {
+		key, err := stateKey(folder)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(folders, key) {
+			folders = append(folders, key)
+		}
+	}
+	workspace.ExpandedFolders = folders
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.Workspace = workspace
+	return s.save()
+}
+
+// GetNoteViewState returns the scroll and cursor position of a note, or nil if none
+// was saved
+func (s *StateService) GetNoteViewState(relativePath string) (*models.NoteViewState, error) {
+	key, err := stateKey(relativePath)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	view, ok := s.state.NoteViews[key]
+	if !ok {
+		return nil, nil
+	}
+	return &view, nil
+}
+
+// SetNoteViewState saves the scroll and cursor position of a note
+func (s *StateService) SetNoteViewState(relativePath string, view models.NoteViewState) error {
+	key, err := stateKey(relativePath)
+	if err != nil {
+		return err
+	}
+	view.UpdatedAt = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state.NoteViews == nil {
+		s.state.NoteViews = make(map[string]models.NoteViewState)
+	}
+	s.state.NoteViews[key] = view
+
+	if len(s.state.NoteViews) > maxNoteViews {
+		s.evictNoteViews()
+	}
+	return s.save()
+}
+
+// evictNoteViews drops the least recently updated note positions in one pass, down
+// to noteViewsKept. The caller must hold mu.
+func (s *StateService) evictNoteViews() {
+	paths := slices.Collect(maps.Keys(s.state.NoteViews))
+	slices.SortFunc(paths, func(a, b string) int {
+		return s.state.NoteViews[b].UpdatedAt.Compare(s.state.NoteViews[a].UpdatedAt)
+	})
+	for _, path := range paths[noteViewsKept:] {
+		delete(s.state.NoteViews, path)
+	}
+}
+
+// workspacePathMoved updates the workspace paths after a file or folder was moved.
+// The caller must hold mu.
+func (s *StateService) workspacePathMoved(from string, to string) {
+	for _, pane := range s.state.Workspace.Panes {
+		for i := range pane.Tabs {
+			pane.Tabs[i].Path, _ = movedPath(pane.Tabs[i].Path, from, to)
+		}
+	}
+	for i, folder := range s.state.Workspace.ExpandedFolders {
+		s.state.Workspace.ExpandedFolders[i], _ = movedPath(folder, from, to)
+	}
+	for path, view := range maps.Clone(s.state.NoteViews) {
+		if moved, ok := movedPath(path, from, to); ok {
+			delete(s.state.NoteViews, path)
+			s.state.NoteViews[moved] = view
+		}
+	}
+}
+
+// workspacePathDeleted closes the tabs of a deleted file or of the files in a deleted
+// folder and forgets its expanded folders and note positions. The caller must hold mu.
+func (s *StateService) workspacePathDeleted(deleted string) {
+	isDeleted := func(path string) bool {
+		return isSubPath(filepath.FromSlash(path), filepath.FromSlash(deleted))
+	}
+
+	for i := range s.state.Workspace.Panes {
+		pane := &s.state.Workspace.Panes[i]
+		tabs := make([]models.WorkspaceTab, 0, len(pane.Tabs))
+		active := pane.ActiveTab
+		for j, tab := range pane.Tabs {
+			if !isDeleted(tab.Path) {
+				tabs = append(tabs, tab)
+			} else if j < pane.ActiveTab {
+				active-- // The active tab keeps its place among the remaining ones
+			}
+		}
+		pane.Tabs = tabs
+		pane.ActiveTab = clampIndex(active, len(tabs))
+	}
+	s.state.Workspace.ExpandedFolders = slices.DeleteFunc(s.state.Workspace.ExpandedFolders, isDeleted)
+	maps.DeleteFunc(s.state.NoteViews, func(path string, _ models.NoteViewState) bool {
+		return isDeleted(path)
+	})
+}
+
+func clampIndex(index int, length int) int {
+	return max(min(index, length-1), 0)
+}
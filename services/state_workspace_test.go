@@ -0,0 +1,174 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kazuph/obails/models"
+)
+
+func TestStateMigrations(t *testing.T) {
+	if len(stateMigrations) != models.CurrentStateVersion {
+		t.Fatalf("Expected %d migrations, got %d", models.CurrentStateVersion, len(stateMigrations))
+	}
+
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+
+	statePath := filepath.Join(tmpDir, ".obails", "state.json")
+	os.MkdirAll(filepath.Dir(statePath), 0755)
+
+	t.Run("unversioned state", func(t *testing.T) {
+		os.WriteFile(statePath, []byte(`{"lastOpenedFile": {"path": "notes/a.md", "fileType": "markdown"}}`), 0644)
+
+		ss := NewStateService(cs, NewFileService(cs))
+		if err := ss.Load(); err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		workspace := ss.GetWorkspace()
+		if len(workspace.Panes) != 1 || len(workspace.Panes[0].Tabs) != 1 || workspace.Panes[0].Tabs[0].Path != "notes/a.md" {
+			t.Errorf("The last opened file should become a tab: %+v", workspace)
+		}
+		if last := ss.GetLastOpenedFile(); last == nil || last.Path != "notes/a.md" {
+			t.Errorf("The last opened file should be kept: %+v", last)
+		}
+
+		var saved models.State
+		data, _ := os.ReadFile(statePath)
+		if err := json.Unmarshal(data, &saved); err != nil || saved.Version != models.CurrentStateVersion {
+			t.Errorf("Migrated state should be saved with the current version: %s", data)
+		}
+	})
+
+	t.Run("newer state", func(t *testing.T) {
+		os.WriteFile(statePath, []byte(`{"version": 99, "workspace": {"panes": []}}`), 0644)
+
+		ss := NewStateService(cs, NewFileService(cs))
+		if err := ss.Load(); err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if ss.state.Version != 99 {
+			t.Errorf("State from a newer version should be kept, got version %d", ss.state.Version)
+		}
+	})
+}
+
+func TestStateService_Workspace(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs := NewFileService(cs)
+	ss := NewStateService(cs, fs)
+
+	t.Run("save and restore", func(t *testing.T) {
+		workspace := models.Workspace{
+			Panes: []models.WorkspacePane{
+				{Tabs: []models.WorkspaceTab{{Path: "a.md", FileType: "markdown"}, {Path: "img/b.png", FileType: "image"}}, ActiveTab: 5, Size: 0.6},
+				{Tabs: []models.WorkspaceTab{}},
+			},
+			ActivePane:      1,
+			ExpandedFolders: []string{"img", "img/", "projects/2024"},
+			Sidebars:        models.SidebarLayout{LeftWidth: 280, RightCollapsed: true},
+			Graph:           models.GraphViewState{Open: true, Zoom: 1.5, Filter: "tag:#idea"},
+		}
+		if err := ss.SaveWorkspace(workspace); err != nil {
+			t.Fatalf("SaveWorkspace failed: %v", err)
+		}
+
+		reloaded := NewStateService(cs, NewFileService(cs))
+		if err := reloaded.Load(); err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		restored := reloaded.GetWorkspace()
+		if len(restored.Panes) != 2 || restored.Panes[0].ActiveTab != 1 || restored.Panes[1].ActiveTab != 0 || restored.ActivePane != 1 {
+			t.Errorf("Unexpected panes: %+v", restored)
+		}
+		if len(restored.ExpandedFolders) != 2 || restored.ExpandedFolders[1] != "projects/2024" {
+			t.Errorf("Unexpected expanded folders: %v", restored.ExpandedFolders)
+		}
+		if restored.Sidebars != workspace.Sidebars || restored.Graph != workspace.Graph {
+			t.Errorf("Unexpected sidebars or graph: %+v", restored)
+		}
+	})
+
+	t.Run("invalid paths", func(t *testing.T) {
+		workspace := models.Workspace{Panes: []models.WorkspacePane{{Tabs: []models.WorkspaceTab{{Path: "../outside.md"}}}}}
+		if err := ss.SaveWorkspace(workspace); err == nil {
+			t.Error("Should reject paths outside the vault")
+		}
+	})
+
+	t.Run("note views", func(t *testing.T) {
+		if view, err := ss.GetNoteViewState("a.md"); err != nil || view != nil {
+			t.Errorf("Expected no view state, got %+v, %v", view, err)
+		}
+		if err := ss.SetNoteViewState("a.md", models.NoteViewState{ScrollTop: 420, CursorLine: 12, CursorColumn: 3}); err != nil {
+			t.Fatalf("SetNoteViewState failed: %v", err)
+		}
+		view, err := ss.GetNoteViewState("a.md")
+		if err != nil || view == nil || view.ScrollTop != 420 || view.CursorLine != 12 || view.UpdatedAt.IsZero() {
+			t.Errorf("Unexpected view state: %+v, %v", view, err)
+		}
+	})
+
+	t.Run("note views are capped", func(t *testing.T) {
+		ss.mu.Lock()
+		saved := maps.Clone(ss.state.NoteViews)
+		for i := range maxNoteViews - 1 {
+			ss.state.NoteViews[fmt.Sprintf("old%d.md", i)] = models.NoteViewState{UpdatedAt: time.Now().Add(-time.Hour)}
+		}
+		ss.mu.Unlock()
+
+		ss.SetNoteViewState("new.md", models.NoteViewState{})
+		if len(ss.state.NoteViews) != noteViewsKept {
+			t.Errorf("Expected %d note views after evicting, got %d", noteViewsKept, len(ss.state.NoteViews))
+		}
+		for _, path := range []string{"a.md", "new.md"} {
+			if view, _ := ss.GetNoteViewState(path); view == nil {
+				t.Errorf("Expected the recent view of %s to be kept", path)
+			}
+		}
+		ss.state.NoteViews = saved
+	})
+
+	t.Run("paths follow moves", func(t *testing.T) {
+		os.MkdirAll(filepath.Join(tmpDir, "img"), 0755)
+		os.WriteFile(filepath.Join(tmpDir, "a.md"), nil, 0644)
+		os.WriteFile(filepath.Join(tmpDir, "img", "b.png"), testPNG, 0644)
+
+		fs.MoveFile("a.md", "notes/a.md")
+		fs.MoveFile("img", "assets")
+
+		workspace := ss.GetWorkspace()
+		if tabs := workspace.Panes[0].Tabs; tabs[0].Path != "notes/a.md" || tabs[1].Path != "assets/b.png" {
+			t.Errorf("Tabs should follow moves: %+v", tabs)
+		}
+		if workspace.ExpandedFolders[0] != "assets" {
+			t.Errorf("Expanded folders should follow moves: %v", workspace.ExpandedFolders)
+		}
+		if view, _ := ss.GetNoteViewState("notes/a.md"); view == nil || view.CursorLine != 12 {
+			t.Errorf("Note views should follow moves: %+v", view)
+		}
+	})
+
+	t.Run("deleted paths are dropped", func(t *testing.T) {
+		fs.DeletePath("notes/a.md")
+		fs.DeletePath("assets")
+
+		workspace := ss.GetWorkspace()
+		if tabs := workspace.Panes[0].Tabs; len(tabs) != 0 || workspace.Panes[0].ActiveTab != 0 {
+			t.Errorf("Tabs of deleted files should be closed: %+v", workspace.Panes[0])
+		}
+		if len(workspace.ExpandedFolders) != 1 || workspace.ExpandedFolders[0] != "projects/2024" {
+			t.Errorf("Deleted folders should not stay expanded: %v", workspace.ExpandedFolders)
+		}
+		if view, _ := ss.GetNoteViewState("notes/a.md"); view != nil {
+			t.Errorf("Note views of deleted notes should be dropped: %+v", view)
+		}
+	})
+}
@@ -0,0 +1,432 @@
+package services
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kazuph/obails/models"
+)
+
+var (
+	// Match "- [ ] text", "* [x] text" or "1. [ ] text" with any indentation
+	taskRegex = regexp.MustCompile(`^(\s*(?:[-*+]|\d+[.)])\s+\[)(.)(\]\s+)(.*)$`)
+	// Match "📅 2026-10-20", "due:2026-10-20" or "due:: 2026-10-20"
+	taskDueRegex = regexp.MustCompile(`(?:📅\x{FE0F}?|\bdue::?)\s*(\d{4}-\d{2}-\d{2})`)
+	// Match "#tag" and "#nested/tag"
+	taskTagRegex = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_/-]+)`)
+)
+
+// taskPriorities maps Obsidian Tasks priority emoji to priority names
+var taskPriorities = []struct {
+	emoji    string
+	priority string
+}{
+	{"🔺", models.TaskPriorityHighest},
+	{"⏫", models.TaskPriorityHigh},
+	{"🔼", models.TaskPriorityMedium},
+	{"🔽", models.TaskPriorityLow},
+	{"⏬", models.TaskPriorityLowest},
+}
+
+// taskPriorityRank orders priorities from most to least important
+var taskPriorityRank = map[string]int{
+	models.TaskPriorityHighest: 0,
+	models.TaskPriorityHigh:    1,
+	models.TaskPriorityMedium:  2,
+	models.TaskPriorityNone:    3,
+	models.TaskPriorityLow:     4,
+	models.TaskPriorityLowest:  5,
+}
+
+// TaskService indexes checkbox tasks across the vault
+type TaskService struct {
+	noteService   *NoteService
+	fileService   *FileService
+	configService *ConfigService
+
+	// Task index: file path -> tasks found in that file
+	index map[string][]models.Task
+
+	mu sync.RWMutex
+}
+
+// NewTaskService creates a new TaskService that keeps the index up to date as notes
+// are saved, moved and deleted
+func NewTaskService(noteService *NoteService, fileService *FileService, configService *ConfigService) *TaskService {
+	s := &TaskService{
+		noteService:   noteService,
+		fileService:   fileService,
+		configService: configService,
+		index:         make(map[string][]models.Task),
+	}
+	noteService.onSave(s.noteSaved)
+	fileService.onMove(s.pathMoved)
+	fileService.onDelete(s.pathDeleted)
+	fileService.onRestore(s.pathRestored)
+	return s
+}
+
+// ParseTasks extracts all checkbox tasks from content
+func (s *TaskService) ParseTasks(content string) []models.Task {
+	var tasks []models.Task
+	heading := ""
+	inFence := false
+
+	for i, line := range strings.Split(content, "\n") {
+		if isCodeFence(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		if headingLevel(line) > 0 {
+			heading = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#"))
+			continue
+		}
+
+		matches := taskRegex.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+
+		text := strings.TrimSpace(matches[4])
+		task := models.Task{
+			Line:    i + 1,
+			Text:    text,
+			Done:    isDoneMark(matches[2]),
+			Heading: heading,
+		}
+
+		if due := taskDueRegex.FindStringSubmatch(text); due != nil {
+			task.Due = due[1]
+		}
+		for _, p := range taskPriorities {
+			if strings.Contains(text, p.emoji) {
+				task.Priority = p.priority
+				break
+			}
+		}
+		for _, tag := range taskTagRegex.FindAllStringSubmatch(text, -1) {
+			if strings.Trim(tag[1], "0123456789") != "" {
+				task.Tags = append(task.Tags, tag[1])
+			}
+		}
+
+		tasks = append(tasks, task)
+	}
+
+	return tasks
+}
+
+// RebuildIndex rebuilds the entire task index
+func (s *TaskService) RebuildIndex() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.index = make(map[string][]models.Task)
+
+	vaultPath := s.configService.GetVaultPath()
+	if vaultPath == "" {
+		return nil
+	}
+
+	templatesPath := ""
+	if folder := s.configService.GetTemplatesFolder(); folder != "" {
+		templatesPath = filepath.Join(vaultPath, folder)
+	}
+
+	ignore := s.fileService.ignoreRules()
+
+	return filepath.Walk(vaultPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // Skip errors
+		}
+
+		// Skip hidden and ignored directories and templates
+		relativePath, _ := filepath.Rel(vaultPath, path)
+		if info.IsDir() {
+			if path != vaultPath && (strings.HasPrefix(info.Name(), ".") || ignore.ignored(relativePath, true)) {
+				return filepath.SkipDir
+			}
+			if path == templatesPath {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if strings.HasPrefix(info.Name(), ".") || !s.fileService.types.isNote(info.Name()) || ignore.ignored(relativePath, false) {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+
+		s.setTasksWithoutLock(relativePath, string(content))
+		return nil
+	})
+}
+
+// UpdateFile re-indexes the tasks of a single note (e.g. after it was saved)
+func (s *TaskService) UpdateFile(relativePath string) error {
+	if !s.indexable(relativePath, s.fileService.ignoreRules()) {
+		s.RemoveFile(relativePath)
+		return nil
+	}
+
+	content, err := s.fileService.ReadFile(relativePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.RemoveFile(relativePath)
+			return nil
+		}
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setTasksWithoutLock(relativePath, content)
+	return nil
+}
+
+// noteSaved re-indexes a saved note
+func (s *TaskService) noteSaved(relativePath string) {
+	rel, err := cleanRelativePath(relativePath)
+	if err == nil && s.fileService.types.isNote(rel) {
+		s.UpdateFile(rel) // Best effort, the next rebuild catches up
+	}
+}
+
+// pathMoved moves the tasks of a moved note or of the notes in a moved folder.
+// Notes moved into the templates folder or an ignored folder are dropped.
+func (s *TaskService) pathMoved(from string, to string) {
+	ignore := s.fileService.ignoreRules()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for path, tasks := range maps.Clone(s.index) {
+		moved, ok := movedPath(filepath.ToSlash(path), filepath.ToSlash(from), filepath.ToSlash(to))
+		if !ok {
+			continue
+		}
+		delete(s.index, path)
+		moved = filepath.FromSlash(moved)
+		if !s.indexable(moved, ignore) {
+			continue
+		}
+		for i := range tasks {
+			tasks[i].Path = moved
+		}
+		s.index[moved] = tasks
+	}
+}
+
+// indexable reports whether RebuildIndex indexes the note at a vault-relative
+// path, which it doesn't for hidden and ignored paths and templates
+func (s *TaskService) indexable(relativePath string, ignore *ignoreRules) bool {
+	rel := filepath.Clean(relativePath)
+	for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+		if strings.HasPrefix(part, ".") {
+			return false
+		}
+	}
+	if folder := s.configService.GetTemplatesFolder(); folder != "" && isSubPath(rel, folder) {
+		return false
+	}
+	return !ignore.ignored(rel, false)
+}
+
+// pathDeleted drops the tasks of a deleted note or of the notes in a deleted folder
+func (s *TaskService) pathDeleted(relativePath string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for path := range s.index {
+		if isSubPath(path, relativePath) {
+			delete(s.index, path)
+		}
+	}
+}
+
+// pathRestored indexes a note restored from the trash. A restored folder can hold
+// many notes, so the whole index is rebuilt.
+func (s *TaskService) pathRestored(original string, restored string) {
+	if s.fileService.types.isNote(restored) {
+		s.UpdateFile(restored) // Best effort, the next rebuild catches up
+		return
+	}
+	s.RebuildIndex()
+}
+
+// RemoveFile drops a note from the task index
+func (s *TaskService) RemoveFile(relativePath string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.index, relativePath)
+}
+
+// QueryTasks returns indexed tasks matching the query, ordered by due date,
+// priority and location. Tasks without a due date come last.
+func (s *TaskService) QueryTasks(query models.TaskQuery) []models.Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	text := strings.ToLower(query.Text)
+	tag := strings.TrimPrefix(query.Tag, "#")
+	result := []models.Task{}
+
+	for path, tasks := range s.index {
+		if query.Path != "" && !isSubPath(path, query.Path) {
+			continue
+		}
+
+		for _, task := range tasks {
+			switch {
+			case query.Status == "open" && task.Done,
+				query.Status == "done" && !task.Done,
+				query.HasDue && task.Due == "",
+				query.DueAfter != "" && (task.Due == "" || task.Due < query.DueAfter),
+				query.DueBefore != "" && (task.Due == "" || task.Due > query.DueBefore),
+				query.Priority != "" && task.Priority != query.Priority,
+				tag != "" && !hasTag(task.Tags, tag),
+				text != "" && !strings.Contains(strings.ToLower(task.Text), text):
+				continue
+			}
+			result = append(result, task)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Due != b.Due {
+			if a.Due == "" || b.Due == "" {
+				return b.Due == ""
+			}
+			return a.Due < b.Due
+		}
+		if taskPriorityRank[a.Priority] != taskPriorityRank[b.Priority] {
+			return taskPriorityRank[a.Priority] < taskPriorityRank[b.Priority]
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Line < b.Line
+	})
+
+	return result
+}
+
+// ToggleTask flips the checkbox of the task at the given line and returns the updated
+// task. text is the task text the caller saw, which must still be on that line.
+func (s *TaskService) ToggleTask(relativePath string, line int, text string) (*models.Task, error) {
+	return s.updateTaskLine(relativePath, line, text, func(matches []string) string {
+		mark := "x"
+		if isDoneMark(matches[2]) {
+			mark = " "
+		}
+		return matches[1] + mark + matches[3] + matches[4]
+	})
+}
+
+// RescheduleTask sets the due date ("YYYY-MM-DD") of the task at the given line.
+// An existing due date is replaced in place; an empty due removes it. text is the task
+// text the caller saw, which must still be on that line.
+func (s *TaskService) RescheduleTask(relativePath string, line int, text string, due string) (*models.Task, error) {
+	if due != "" {
+		if _, err := time.Parse("2006-01-02", due); err != nil {
+			return nil, fmt.Errorf("invalid due date %q: %w", due, err)
+		}
+	}
+
+	return s.updateTaskLine(relativePath, line, text, func(matches []string) string {
+		text := matches[4]
+		switch loc := taskDueRegex.FindStringSubmatchIndex(text); {
+		case loc == nil && due != "":
+			text = strings.TrimRight(text, " ") + " 📅 " + due
+		case loc != nil && due != "":
+			text = text[:loc[2]] + due + text[loc[3]:]
+		case loc != nil:
+			text = strings.TrimRight(text[:loc[0]], " ") + text[loc[1]:]
+		}
+		return matches[1] + matches[2] + matches[3] + text
+	})
+}
+
+// updateTaskLine rewrites the task at the given 1-based line and re-indexes the note.
+// It fails if the line no longer holds the expected task, e.g. after the note was
+// edited since it was indexed.
+func (s *TaskService) updateTaskLine(relativePath string, line int, text string, update func(matches []string) string) (*models.Task, error) {
+	content, err := s.fileService.ReadFile(relativePath)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(content, "\n")
+	if line < 1 || line > len(lines) {
+		return nil, fmt.Errorf("line %d out of range in %s", line, relativePath)
+	}
+
+	matches := taskRegex.FindStringSubmatch(lines[line-1])
+	if matches == nil {
+		return nil, fmt.Errorf("line %d in %s is not a task", line, relativePath)
+	}
+	if strings.TrimSpace(matches[4]) != strings.TrimSpace(text) {
+		return nil, fmt.Errorf("task at line %d in %s has changed", line, relativePath)
+	}
+	lines[line-1] = update(matches)
+
+	content = strings.Join(lines, "\n")
+	if err := s.noteService.SaveNote(relativePath, content); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	tasks := s.setTasksWithoutLock(relativePath, content)
+	s.mu.Unlock()
+
+	for i := range tasks {
+		if tasks[i].Line == line {
+			return &tasks[i], nil
+		}
+	}
+	return nil, fmt.Errorf("line %d in %s is not a task", line, relativePath)
+}
+
+func (s *TaskService) setTasksWithoutLock(relativePath string, content string) []models.Task {
+	tasks := s.ParseTasks(content)
+	for i := range tasks {
+		tasks[i].Path = relativePath
+	}
+
+	if len(tasks) == 0 {
+		delete(s.index, relativePath)
+	} else {
+		s.index[relativePath] = tasks
+	}
+	return tasks
+}
+
+// isDoneMark reports whether a checkbox mark closes a task: [x] done or [-] cancelled
+func isDoneMark(mark string) bool {
+	return mark == "x" || mark == "X" || mark == "-"
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) || strings.HasPrefix(strings.ToLower(t), strings.ToLower(tag)+"/") {
+			return true
+		}
+	}
+	return false
+}
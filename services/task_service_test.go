@@ -0,0 +1,266 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kazuph/obails/models"
+)
+
+func newTestTaskService(t *testing.T) (*TaskService, *FileService, string) {
+	t.Helper()
+	tmpDir, err := os.MkdirTemp("", "obails-task-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+
+	cs := &ConfigService{
+		configPath: filepath.Join(tmpDir, "config.toml"),
+		config: &models.Config{
+			Vault: models.VaultConfig{
+				Path: tmpDir,
+			},
+			Templates: models.TemplatesConfig{
+				Folder: "templates",
+			},
+		},
+	}
+
+	fs := NewFileService(cs)
+	ts := NewTaskService(NewNoteService(fs, cs), fs, cs)
+	return ts, fs, tmpDir
+}
+
+func TestTaskService_ParseTasks(t *testing.T) {
+	ts, _, tmpDir := newTestTaskService(t)
+	defer os.RemoveAll(tmpDir)
+
+	content := "# Project\n\n## Todo\n" +
+		"- [ ] Write report 📅 2026-10-20 ⏫ #work\n" +
+		"- [x] Done thing\n" +
+		"  - [ ] Nested due:2026-11-01 #home/garden\n" +
+		"- Plain item\n" +
+		"```\n- [ ] In code block\n```\n" +
+		"1. [ ] Numbered 🔽 #123\n" +
+		"- [-] Cancelled\n"
+
+	tasks := ts.ParseTasks(content)
+	if len(tasks) != 5 {
+		t.Fatalf("Expected 5 tasks, got %d: %+v", len(tasks), tasks)
+	}
+
+	first := tasks[0]
+	if first.Line != 4 || first.Heading != "Todo" || first.Done {
+		t.Errorf("Unexpected first task: %+v", first)
+	}
+	if first.Due != "2026-10-20" || first.Priority != models.TaskPriorityHigh {
+		t.Errorf("Due/priority mismatch: %+v", first)
+	}
+	if len(first.Tags) != 1 || first.Tags[0] != "work" {
+		t.Errorf("Tags mismatch: %v", first.Tags)
+	}
+
+	if !tasks[1].Done {
+		t.Error("Second task should be done")
+	}
+	if tasks[2].Due != "2026-11-01" || tasks[2].Tags[0] != "home/garden" {
+		t.Errorf("Nested task mismatch: %+v", tasks[2])
+	}
+	if tasks[3].Priority != models.TaskPriorityLow || len(tasks[3].Tags) != 0 {
+		t.Errorf("Numbered task mismatch: %+v", tasks[3])
+	}
+	if !tasks[4].Done {
+		t.Error("Cancelled task should count as done, as in rollover")
+	}
+}
+
+func TestTaskService_QueryTasks(t *testing.T) {
+	ts, fs, tmpDir := newTestTaskService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs.CreateFile("daily/2026-10-18.md", "## Todo\n- [ ] Call mom 📅 2026-10-19\n- [x] Shopping #home\n")
+	fs.CreateFile("projects/app.md", "- [ ] Ship it 📅 2026-10-18 🔺 #work\n- [ ] Someday\n")
+	fs.CreateFile("templates/daily.md", "- [ ] 09:00 Plan the day\n")
+	fs.CreateFile(".hidden/secret.md", "- [ ] Hidden\n")
+
+	if err := ts.RebuildIndex(); err != nil {
+		t.Fatalf("RebuildIndex failed: %v", err)
+	}
+
+	t.Run("all tasks sorted by due date", func(t *testing.T) {
+		tasks := ts.QueryTasks(models.TaskQuery{})
+		if len(tasks) != 4 {
+			t.Fatalf("Expected 4 tasks, got %d: %+v", len(tasks), tasks)
+		}
+		if tasks[0].Text != "Ship it 📅 2026-10-18 🔺 #work" || tasks[1].Text != "Call mom 📅 2026-10-19" {
+			t.Errorf("Unexpected order: %+v", tasks)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		query    models.TaskQuery
+		expected int
+	}{
+		{"open only", models.TaskQuery{Status: "open"}, 3},
+		{"done only", models.TaskQuery{Status: "done"}, 1},
+		{"by folder", models.TaskQuery{Path: "projects"}, 2},
+		{"by tag", models.TaskQuery{Tag: "#work"}, 1},
+		{"by text", models.TaskQuery{Text: "MOM"}, 1},
+		{"due range", models.TaskQuery{DueAfter: "2026-10-19", DueBefore: "2026-10-31"}, 1},
+		{"has due", models.TaskQuery{HasDue: true}, 2},
+		{"priority", models.TaskQuery{Priority: models.TaskPriorityHighest}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(ts.QueryTasks(tt.query)); got != tt.expected {
+				t.Errorf("Expected %d tasks, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTaskService_ToggleAndReschedule(t *testing.T) {
+	ts, fs, tmpDir := newTestTaskService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs.CreateFile("todo.md", "# Todo\n- [ ] First\n- [ ] Second 📅 2026-10-20 #work\nNot a task\n")
+	ts.RebuildIndex()
+
+	t.Run("toggle", func(t *testing.T) {
+		task, err := ts.ToggleTask("todo.md", 2, "First")
+		if err != nil {
+			t.Fatalf("ToggleTask failed: %v", err)
+		}
+		if !task.Done {
+			t.Error("Task should be done")
+		}
+
+		content, _ := fs.ReadFile("todo.md")
+		if !strings.Contains(content, "- [x] First") {
+			t.Errorf("File not updated:\n%s", content)
+		}
+		if len(ts.QueryTasks(models.TaskQuery{Status: "done"})) != 1 {
+			t.Error("Index not updated")
+		}
+
+		task, _ = ts.ToggleTask("todo.md", 2, "First")
+		if task.Done {
+			t.Error("Task should be open again")
+		}
+	})
+
+	t.Run("reschedule replaces due date", func(t *testing.T) {
+		task, err := ts.RescheduleTask("todo.md", 3, "Second 📅 2026-10-20 #work", "2026-10-25")
+		if err != nil {
+			t.Fatalf("RescheduleTask failed: %v", err)
+		}
+		if task.Due != "2026-10-25" || task.Text != "Second 📅 2026-10-25 #work" {
+			t.Errorf("Unexpected task: %+v", task)
+		}
+	})
+
+	t.Run("reschedule adds due date", func(t *testing.T) {
+		task, _ := ts.RescheduleTask("todo.md", 2, "First", "2026-10-21")
+		if task.Text != "First 📅 2026-10-21" {
+			t.Errorf("Unexpected text: %q", task.Text)
+		}
+	})
+
+	t.Run("reschedule removes due date", func(t *testing.T) {
+		task, _ := ts.RescheduleTask("todo.md", 3, "Second 📅 2026-10-25 #work", "")
+		if task.Due != "" || task.Text != "Second #work" {
+			t.Errorf("Unexpected task: %+v", task)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if _, err := ts.ToggleTask("todo.md", 4, "Not a task"); err == nil {
+			t.Error("Should fail for non-task line")
+		}
+		if _, err := ts.ToggleTask("todo.md", 100, "First"); err == nil {
+			t.Error("Should fail for out of range line")
+		}
+		if _, err := ts.RescheduleTask("todo.md", 2, "First 📅 2026-10-21", "tomorrow"); err == nil {
+			t.Error("Should fail for invalid date")
+		}
+	})
+
+	t.Run("stale line", func(t *testing.T) {
+		fs.WriteFile("todo.md", "# Todo\n- [ ] Inserted\n- [ ] First 📅 2026-10-21\n")
+		if _, err := ts.ToggleTask("todo.md", 2, "First 📅 2026-10-21"); err == nil {
+			t.Error("Should fail when the line holds another task")
+		}
+		content, _ := fs.ReadFile("todo.md")
+		if strings.Contains(content, "[x]") {
+			t.Errorf("File should be unchanged:\n%s", content)
+		}
+	})
+}
+
+func TestTaskService_KeepsIndexUpToDate(t *testing.T) {
+	ts, fs, tmpDir := newTestTaskService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs.CreateFile("projects/todo.md", "- [ ] First\n")
+	ts.RebuildIndex()
+
+	t.Run("save", func(t *testing.T) {
+		if err := ts.noteService.SaveNote("projects/todo.md", "- [ ] First\n- [ ] Second\n"); err != nil {
+			t.Fatalf("SaveNote failed: %v", err)
+		}
+		if got := len(ts.QueryTasks(models.TaskQuery{})); got != 2 {
+			t.Errorf("Expected 2 tasks after a save, got %d", got)
+		}
+	})
+
+	t.Run("move", func(t *testing.T) {
+		if err := fs.MoveFile("projects", "archive"); err != nil {
+			t.Fatalf("MoveFile failed: %v", err)
+		}
+		tasks := ts.QueryTasks(models.TaskQuery{})
+		if len(tasks) != 2 || tasks[0].Path != filepath.Join("archive", "todo.md") {
+			t.Errorf("Expected the tasks at the new path, got %+v", tasks)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := fs.DeletePath("archive"); err != nil {
+			t.Fatalf("DeletePath failed: %v", err)
+		}
+		if got := len(ts.QueryTasks(models.TaskQuery{})); got != 0 {
+			t.Errorf("Expected no tasks after a delete, got %d", got)
+		}
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		items, _ := fs.ListTrash()
+		if len(items) != 1 {
+			t.Fatalf("Expected one trash item, got %+v", items)
+		}
+		if _, err := fs.RestoreFromTrash(items[0].ID); err != nil {
+			t.Fatalf("RestoreFromTrash failed: %v", err)
+		}
+		if got := len(ts.QueryTasks(models.TaskQuery{})); got != 2 {
+			t.Errorf("Expected the restored tasks, got %d", got)
+		}
+	})
+
+	t.Run("templates and ignored notes", func(t *testing.T) {
+		os.WriteFile(filepath.Join(tmpDir, ignoreFileName), []byte("drafts/\n"), 0644)
+		for _, path := range []string{"templates/task.md", "drafts/idea.md"} {
+			if err := ts.noteService.SaveNote(path, "- [ ] Skipped\n"); err != nil {
+				t.Fatalf("SaveNote failed: %v", err)
+			}
+		}
+		if err := fs.MoveFile("archive", "drafts/archive"); err != nil {
+			t.Fatalf("MoveFile failed: %v", err)
+		}
+		if tasks := ts.QueryTasks(models.TaskQuery{}); len(tasks) != 0 {
+			t.Errorf("Expected no tasks of templates and ignored notes, got %+v", tasks)
+		}
+	})
+}
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	_ "image/gif" // Register decoders for image.Decode
+
+	"github.com/kazuph/obails/models"
+	_ "golang.org/x/image/bmp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// thumbnailFolder caches generated thumbnails. Files are named
+// <hash of source path>-<source mtime>-<size>.<png|jpg>, so a changed source
+// misses the cache and its stale thumbnails can be found by prefix.
+const thumbnailFolder = ".obails/cache/thumbs"
+
+// Thumbnail size limits in pixels (longest edge)
+const (
+	minThumbnailSize = 16
+	maxThumbnailSize = 1024
+)
+
+// maxThumbnailSourcePixels guards against decoding huge images into memory
+const maxThumbnailSourcePixels = 100_000_000
+
+// ThumbnailService generates and caches scaled-down previews of vault images
+type ThumbnailService struct {
+	fileService *FileService
+}
+
+// NewThumbnailService creates a new ThumbnailService
+func NewThumbnailService(fileService *FileService) *ThumbnailService {
+	s := &ThumbnailService{fileService: fileService}
+	fileService.onMove(s.pathMoved)
+	fileService.onDelete(s.pathDeleted)
+	return s
+}
+
+// GetThumbnail returns a preview of an image whose longest edge is at most size pixels.
+// Images smaller than that are not scaled up. Results are cached until the image changes.
+func (s *ThumbnailService) GetThumbnail(relativePath string, size int) (*models.Thumbnail, error) {
+	size = min(max(size, minThumbnailSize), maxThumbnailSize)
+
+	fullPath, err := s.fileService.getEntryPath(relativePath)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", relativePath)
+	}
+
+	prefix, err := s.cachePrefix(relativePath)
+	if err != nil {
+		return nil, err
+	}
+	version := fmt.Sprintf("%s-%x-", prefix, info.ModTime().UnixNano())
+	stem := version + strconv.Itoa(size)
+
+	if thumb, err := s.readCached(relativePath, stem); err == nil {
+		return thumb, nil
+	}
+
+	data, mimeType, bounds, err := renderThumbnail(fullPath, size)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replace thumbnails of older versions of the image
+	s.removeCached(prefix, func(name string) bool {
+		return !strings.HasPrefix(name, filepath.Base(version))
+	})
+	cachePath := stem + thumbnailExtension(mimeType)
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err == nil {
+		atomicWriteFile(cachePath, data, 0644) // The cache is best effort
+	}
+
+	return &models.Thumbnail{
+		Path:     relativePath,
+		Width:    bounds.Dx(),
+		Height:   bounds.Dy(),
+		MimeType: mimeType,
+		Data:     base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
+
+// InvalidateThumbnails deletes the cached thumbnails of an image, e.g. after it was
+// deleted or moved
+func (s *ThumbnailService) InvalidateThumbnails(relativePath string) error {
+	prefix, err := s.cachePrefix(relativePath)
+	if err != nil {
+		return err
+	}
+	return s.removeCached(prefix, func(string) bool { return true })
+}
+
+// pathMoved drops the thumbnails of a moved image, or of the images in a moved folder
+func (s *ThumbnailService) pathMoved(from string, to string) {
+	fullPath, err := s.fileService.getEntryPath(to)
+	if err != nil {
+		return
+	}
+	s.invalidateFolder(fullPath, from)
+}
+
+// invalidateFolder drops the thumbnails of the files under fullPath as they were
+// named when the folder was at relativePath
+func (s *ThumbnailService) invalidateFolder(fullPath string, relativePath string) {
+	filepath.WalkDir(fullPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(fullPath, p)
+		if err == nil {
+			s.InvalidateThumbnails(filepath.Join(relativePath, rel)) // Best effort, stale thumbnails are never served
+		}
+		return nil
+	})
+}
+
+// pathDeleted drops the thumbnails of a deleted image, or of the images in a deleted
+// folder. Those are walked where the folder went in the trash; a folder deleted from
+// the trash had its thumbnails dropped when it was trashed.
+func (s *ThumbnailService) pathDeleted(relativePath string) {
+	s.InvalidateThumbnails(relativePath) // Best effort, stale thumbnails are never served
+	if isSubPath(relativePath, trashFolder) {
+		return
+	}
+
+	items, err := s.fileService.ListTrash()
+	if err != nil {
+		return
+	}
+	for _, item := range items { // Newest first
+		if item.OriginalPath != filepath.ToSlash(relativePath) {
+			continue
+		}
+		if item.IsDir {
+			trashed, err := s.fileService.getFullPath(filepath.Join(trashFolder, item.ID, item.Name))
+			if err == nil {
+				s.invalidateFolder(trashed, relativePath)
+			}
+		}
+		return
+	}
+}
+
+// ClearThumbnailCache deletes all cached thumbnails
+func (s *ThumbnailService) ClearThumbnailCache() error {
+	cacheDir, err := s.fileService.getFullPath(thumbnailFolder)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(cacheDir)
+}
+
+// cachePrefix returns the absolute cache path prefix for thumbnails of an image
+func (s *ThumbnailService) cachePrefix(relativePath string) (string, error) {
+	rel, err := cleanRelativePath(relativePath)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(filepath.ToSlash(rel)))
+	return s.fileService.getFullPath(filepath.Join(thumbnailFolder, hex.EncodeToString(sum[:12])))
+}
+
+// readCached loads a cached thumbnail for the given cache stem
+func (s *ThumbnailService) readCached(relativePath string, stem string) (*models.Thumbnail, error) {
+	for _, mimeType := range []string{"image/png", "image/jpeg"} {
+		data, err := os.ReadFile(stem + thumbnailExtension(mimeType))
+		if err != nil {
+			continue
+		}
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return &models.Thumbnail{
+			Path:     relativePath,
+			Width:    config.Width,
+			Height:   config.Height,
+			MimeType: mimeType,
+			Data:     base64.StdEncoding.EncodeToString(data),
+		}, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+// removeCached deletes the cached thumbnails with the given prefix whose names match
+func (s *ThumbnailService) removeCached(prefix string, match func(name string) bool) error {
+	entries, err := os.ReadDir(filepath.Dir(prefix))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), filepath.Base(prefix)+"-") && match(entry.Name()) {
+			if err := os.Remove(filepath.Join(filepath.Dir(prefix), entry.Name())); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// renderThumbnail decodes an image and encodes a copy scaled to fit in size×size:
+// JPEG for opaque images, PNG when transparency must be kept
+func renderThumbnail(fullPath string, size int) ([]byte, string, image.Rectangle, error) {
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return nil, "", image.Rectangle{}, err
+	}
+	defer f.Close()
+
+	config, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return nil, "", image.Rectangle{}, fmt.Errorf("unsupported image %s: %w", filepath.Base(fullPath), err)
+	}
+	if config.Width*config.Height > maxThumbnailSourcePixels {
+		return nil, "", image.Rectangle{}, fmt.Errorf("image %s is too large (%dx%d)", filepath.Base(fullPath), config.Width, config.Height)
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		return nil, "", image.Rectangle{}, err
+	}
+
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return nil, "", image.Rectangle{}, err
+	}
+
+	bounds := thumbnailBounds(src.Bounds(), size)
+	dst := image.NewRGBA(bounds)
+	draw.CatmullRom.Scale(dst, bounds, src, src.Bounds(), draw.Src, nil)
+
+	var buf bytes.Buffer
+	mimeType := "image/png"
+	if opaque, ok := src.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		mimeType = "image/jpeg"
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, "", image.Rectangle{}, err
+	}
+	return buf.Bytes(), mimeType, bounds, nil
+}
+
+// thumbnailBounds scales a size down to fit in size×size, keeping the aspect ratio
+func thumbnailBounds(src image.Rectangle, size int) image.Rectangle {
+	w, h := src.Dx(), src.Dy()
+	if w <= size && h <= size {
+		return image.Rect(0, 0, max(w, 1), max(h, 1))
+	}
+	if w >= h {
+		return image.Rect(0, 0, size, max(h*size/w, 1))
+	}
+	return image.Rect(0, 0, max(w*size/h, 1), size)
+}
+
+func thumbnailExtension(mimeType string) string {
+	if mimeType == "image/jpeg" {
+		return ".jpg"
+	}
+	return ".png"
+}
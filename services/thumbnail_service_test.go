@@ -0,0 +1,164 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/image/bmp"
+)
+
+// 1x1 lossless WebP
+const testWebP = "UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA=="
+
+func newTestImage(w, h int, alpha uint8) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: alpha})
+		}
+	}
+	return img
+}
+
+func TestThumbnailService_Formats(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs := NewFileService(cs)
+	ts := NewThumbnailService(fs)
+
+	var pngBuf, jpegBuf, gifBuf, bmpBuf bytes.Buffer
+	png.Encode(&pngBuf, newTestImage(400, 200, 128))
+	jpeg.Encode(&jpegBuf, newTestImage(100, 300, 255), nil)
+	gif.Encode(&gifBuf, newTestImage(64, 64, 255), nil)
+	bmp.Encode(&bmpBuf, newTestImage(50, 20, 255))
+	webp, _ := base64.StdEncoding.DecodeString(testWebP)
+
+	fs.WriteBinaryFile("a.png", base64.StdEncoding.EncodeToString(pngBuf.Bytes()))
+	fs.WriteBinaryFile("b.jpg", base64.StdEncoding.EncodeToString(jpegBuf.Bytes()))
+	fs.WriteBinaryFile("c.gif", base64.StdEncoding.EncodeToString(gifBuf.Bytes()))
+	fs.WriteBinaryFile("d.bmp", base64.StdEncoding.EncodeToString(bmpBuf.Bytes()))
+	fs.WriteBinaryFile("e.webp", base64.StdEncoding.EncodeToString(webp))
+	fs.CreateFile("f.png", "not an image")
+
+	tests := []struct {
+		path          string
+		width, height int
+		mimeType      string
+	}{
+		{"a.png", 100, 50, "image/png"}, // Transparent, keeps PNG
+		{"b.jpg", 33, 100, "image/jpeg"},
+		{"c.gif", 64, 64, "image/jpeg"},
+		{"d.bmp", 50, 20, "image/jpeg"},
+		{"e.webp", 1, 1, "image/png"},
+	}
+	for _, tt := range tests {
+		thumb, err := ts.GetThumbnail(tt.path, 100)
+		if err != nil {
+			t.Errorf("GetThumbnail(%s) failed: %v", tt.path, err)
+			continue
+		}
+		if thumb.Width != tt.width || thumb.Height != tt.height || thumb.MimeType != tt.mimeType {
+			t.Errorf("GetThumbnail(%s) = %dx%d %s, want %dx%d %s", tt.path,
+				thumb.Width, thumb.Height, thumb.MimeType, tt.width, tt.height, tt.mimeType)
+		}
+		data, _ := base64.StdEncoding.DecodeString(thumb.Data)
+		if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil || config.Width != tt.width {
+			t.Errorf("GetThumbnail(%s) returned invalid data: %v", tt.path, err)
+		}
+	}
+
+	if _, err := ts.GetThumbnail("f.png", 100); err == nil {
+		t.Error("Should fail for invalid image data")
+	}
+	if _, err := ts.GetThumbnail("../outside.png", 100); err == nil {
+		t.Error("Should reject paths outside the vault")
+	}
+}
+
+func TestThumbnailService_Cache(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+
+	fs := NewFileService(cs)
+	ts := NewThumbnailService(fs)
+	cacheDir := filepath.Join(tmpDir, thumbnailFolder)
+
+	var buf bytes.Buffer
+	png.Encode(&buf, newTestImage(300, 300, 255))
+	fs.WriteBinaryFile("img.png", base64.StdEncoding.EncodeToString(buf.Bytes()))
+
+	first, _ := ts.GetThumbnail("img.png", 64)
+	ts.GetThumbnail("img.png", 128)
+	if entries, _ := os.ReadDir(cacheDir); len(entries) != 2 {
+		t.Fatalf("Expected 2 cached sizes, got %d", len(entries))
+	}
+
+	cached, err := ts.GetThumbnail("img.png", 64)
+	if err != nil || cached.Data != first.Data || cached.Width != 64 {
+		t.Errorf("Cached thumbnail mismatch: %v", err)
+	}
+
+	t.Run("changed image invalidates the cache", func(t *testing.T) {
+		buf.Reset()
+		png.Encode(&buf, newTestImage(300, 150, 255))
+		fullPath := filepath.Join(tmpDir, "img.png")
+		os.WriteFile(fullPath, buf.Bytes(), 0644)
+		later := time.Now().Add(time.Minute)
+		os.Chtimes(fullPath, later, later)
+
+		thumb, _ := ts.GetThumbnail("img.png", 64)
+		if thumb.Height != 32 {
+			t.Errorf("Expected a thumbnail of the new image, got %dx%d", thumb.Width, thumb.Height)
+		}
+		if entries, _ := os.ReadDir(cacheDir); len(entries) != 1 {
+			t.Errorf("Expected stale thumbnails to be removed, got %d entries", len(entries))
+		}
+	})
+
+	t.Run("invalidate", func(t *testing.T) {
+		if err := ts.InvalidateThumbnails("img.png"); err != nil {
+			t.Fatalf("InvalidateThumbnails failed: %v", err)
+		}
+		if entries, _ := os.ReadDir(cacheDir); len(entries) != 0 {
+			t.Errorf("Expected empty cache, got %d entries", len(entries))
+		}
+	})
+
+	t.Run("moved and deleted images", func(t *testing.T) {
+		fs.CreateDirectory("images")
+		fs.WriteBinaryFile("images/a.png", base64.StdEncoding.EncodeToString(buf.Bytes()))
+		ts.GetThumbnail("img.png", 64)
+		ts.GetThumbnail("images/a.png", 64)
+
+		if err := fs.MoveFile("images", "pictures"); err != nil {
+			t.Fatalf("MoveFile failed: %v", err)
+		}
+		if entries, _ := os.ReadDir(cacheDir); len(entries) != 1 {
+			t.Errorf("Expected the moved image's thumbnail to be removed, got %d entries", len(entries))
+		}
+		if err := fs.DeletePath("img.png"); err != nil {
+			t.Fatalf("DeletePath failed: %v", err)
+		}
+		if entries, _ := os.ReadDir(cacheDir); len(entries) != 0 {
+			t.Errorf("Expected the deleted image's thumbnail to be removed, got %d entries", len(entries))
+		}
+
+		ts.GetThumbnail("pictures/a.png", 64)
+		if err := fs.DeletePath("pictures"); err != nil {
+			t.Fatalf("DeletePath failed: %v", err)
+		}
+		if entries, _ := os.ReadDir(cacheDir); len(entries) != 0 {
+			t.Errorf("Expected the deleted folder's thumbnails to be removed, got %d entries", len(entries))
+		}
+	})
+}
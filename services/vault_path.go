@@ -0,0 +1,105 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Errors returned for rejected vault paths (wrapped in a VaultPathError)
+var (
+	ErrVaultNotSet      = errors.New("vault path is not set")
+	ErrPathOutsideVault = errors.New("path is outside the vault")
+	ErrAbsolutePath     = errors.New("path must be relative to the vault")
+	ErrVaultRoot        = errors.New("operation not allowed on the vault root")
+)
+
+// VaultPathError records a vault-relative path that was rejected
+type VaultPathError struct {
+	Path string
+	Err  error
+}
+
+func (e *VaultPathError) Error() string {
+	return fmt.Sprintf("invalid vault path %q: %v", e.Path, e.Err)
+}
+
+func (e *VaultPathError) Unwrap() error {
+	return e.Err
+}
+
+// cleanRelativePath normalizes a vault-relative path coming from the frontend.
+// It returns "." for the vault root and rejects absolute paths and paths that
+// climb out of the vault with "..".
+func cleanRelativePath(relativePath string) (string, error) {
+	if strings.ContainsRune(relativePath, 0) {
+		return "", &VaultPathError{Path: relativePath, Err: ErrPathOutsideVault}
+	}
+
+	native := filepath.FromSlash(relativePath)
+	if filepath.IsAbs(native) || filepath.VolumeName(native) != "" || strings.HasPrefix(native, string(filepath.Separator)) {
+		return "", &VaultPathError{Path: relativePath, Err: ErrAbsolutePath}
+	}
+
+	cleaned := filepath.Clean(native)
+	if cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", &VaultPathError{Path: relativePath, Err: ErrPathOutsideVault}
+	}
+
+	return cleaned, nil
+}
+
+// resolveInVault turns a vault-relative path into an absolute path inside the vault.
+// Besides the lexical checks of cleanRelativePath, the deepest existing part of the
+// path is resolved through symlinks and must still lie inside the vault.
+func resolveInVault(vaultPath string, relativePath string) (string, error) {
+	if vaultPath == "" {
+		return "", &VaultPathError{Path: relativePath, Err: ErrVaultNotSet}
+	}
+
+	rel, err := cleanRelativePath(relativePath)
+	if err != nil {
+		return "", err
+	}
+
+	root, err := filepath.Abs(vaultPath)
+	if err != nil {
+		return "", err
+	}
+	fullPath := filepath.Join(root, rel)
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		// The vault doesn't exist (yet), so there are no symlinks to follow
+		return fullPath, nil
+	}
+
+	for existing := fullPath; ; existing = filepath.Dir(existing) {
+		real, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			if !isWithin(realRoot, real) {
+				return "", &VaultPathError{Path: relativePath, Err: ErrPathOutsideVault}
+			}
+			break
+		}
+		if _, lerr := os.Lstat(existing); lerr == nil {
+			// Exists but can't be resolved, e.g. a dangling symlink
+			return "", &VaultPathError{Path: relativePath, Err: ErrPathOutsideVault}
+		}
+		if existing == root {
+			break
+		}
+	}
+
+	return fullPath, nil
+}
+
+// isWithin reports whether path is root or lies inside it (both absolute and clean)
+func isWithin(root string, path string) bool {
+	if path == root {
+		return true
+	}
+	return strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator))
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCleanRelativePath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+		err      error
+	}{
+		{"", ".", nil},
+		{"note.md", "note.md", nil},
+		{"folder/../note.md", "note.md", nil},
+		{"./folder//nested.md", filepath.Join("folder", "nested.md"), nil},
+		{"..", "", ErrPathOutsideVault},
+		{"../../.ssh/id_rsa", "", ErrPathOutsideVault},
+		{"folder/../../outside.md", "", ErrPathOutsideVault},
+		{"/etc/passwd", "", ErrAbsolutePath},
+		{"note\x00.md", "", ErrPathOutsideVault},
+	}
+
+	for _, tt := range tests {
+		got, err := cleanRelativePath(tt.path)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("cleanRelativePath(%q) error = %v, want %v", tt.path, err, tt.err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("cleanRelativePath(%q) = %q, want %q", tt.path, got, tt.expected)
+		}
+	}
+}
+
+func TestFileService_PathSandbox(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+
+	outsideDir, err := os.MkdirTemp("", "obails-outside-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(outsideDir)
+	os.WriteFile(filepath.Join(outsideDir, "secret.md"), []byte("secret"), 0644)
+
+	fs := NewFileService(cs)
+	fs.CreateFile("inside.md", "inside")
+
+	t.Run("traversal is rejected", func(t *testing.T) {
+		escape := filepath.Join("..", filepath.Base(outsideDir), "secret.md")
+
+		if _, err := fs.ReadFile(escape); !errors.Is(err, ErrPathOutsideVault) {
+			t.Errorf("ReadFile error = %v, want ErrPathOutsideVault", err)
+		}
+		if err := fs.WriteFile(escape, "pwned"); !errors.Is(err, ErrPathOutsideVault) {
+			t.Errorf("WriteFile error = %v, want ErrPathOutsideVault", err)
+		}
+		if err := fs.MoveFile("inside.md", escape); !errors.Is(err, ErrPathOutsideVault) {
+			t.Errorf("MoveFile error = %v, want ErrPathOutsideVault", err)
+		}
+		if fs.FileExists(escape) {
+			t.Error("FileExists should be false outside the vault")
+		}
+
+		var pathErr *VaultPathError
+		if _, err := fs.ReadBinaryFile(escape); !errors.As(err, &pathErr) || pathErr.Path != escape {
+			t.Errorf("Expected VaultPathError for %q, got %v", escape, err)
+		}
+
+		content, _ := os.ReadFile(filepath.Join(outsideDir, "secret.md"))
+		if string(content) != "secret" {
+			t.Error("File outside the vault was modified")
+		}
+	})
+
+	t.Run("absolute path is rejected", func(t *testing.T) {
+		if _, err := fs.ReadFile(filepath.Join(outsideDir, "secret.md")); !errors.Is(err, ErrAbsolutePath) {
+			t.Errorf("ReadFile error = %v, want ErrAbsolutePath", err)
+		}
+	})
+
+	t.Run("symlink escape is rejected", func(t *testing.T) {
+		if err := os.Symlink(outsideDir, filepath.Join(tmpDir, "link")); err != nil {
+			t.Skipf("Symlinks not supported: %v", err)
+		}
+
+		if _, err := fs.ReadFile("link/secret.md"); !errors.Is(err, ErrPathOutsideVault) {
+			t.Errorf("ReadFile error = %v, want ErrPathOutsideVault", err)
+		}
+		if err := fs.WriteFile("link/new.md", "pwned"); !errors.Is(err, ErrPathOutsideVault) {
+			t.Errorf("WriteFile error = %v, want ErrPathOutsideVault", err)
+		}
+		if _, err := os.Stat(filepath.Join(outsideDir, "new.md")); err == nil {
+			t.Error("File was created outside the vault")
+		}
+	})
+
+	t.Run("symlink inside the vault is allowed", func(t *testing.T) {
+		os.MkdirAll(filepath.Join(tmpDir, "real"), 0755)
+		if err := os.Symlink(filepath.Join(tmpDir, "real"), filepath.Join(tmpDir, "alias")); err != nil {
+			t.Skipf("Symlinks not supported: %v", err)
+		}
+
+		if err := fs.WriteFile("alias/note.md", "ok"); err != nil {
+			t.Errorf("WriteFile through internal symlink failed: %v", err)
+		}
+	})
+
+	t.Run("dangling symlink is rejected", func(t *testing.T) {
+		if err := os.Symlink(filepath.Join(outsideDir, "missing.md"), filepath.Join(tmpDir, "dangling.md")); err != nil {
+			t.Skipf("Symlinks not supported: %v", err)
+		}
+
+		if err := fs.WriteFile("dangling.md", "pwned"); !errors.Is(err, ErrPathOutsideVault) {
+			t.Errorf("WriteFile error = %v, want ErrPathOutsideVault", err)
+		}
+	})
+
+	t.Run("vault root cannot be deleted", func(t *testing.T) {
+		for _, path := range []string{"", ".", "folder/.."} {
+			if err := fs.DeletePath(path); !errors.Is(err, ErrVaultRoot) {
+				t.Errorf("DeletePath(%q) error = %v, want ErrVaultRoot", path, err)
+			}
+		}
+		if _, err := os.Stat(tmpDir); err != nil {
+			t.Error("Vault should still exist")
+		}
+	})
+
+	t.Run("empty vault path is rejected", func(t *testing.T) {
+		cs.config.Vault.Path = ""
+		defer func() { cs.config.Vault.Path = tmpDir }()
+
+		if _, err := fs.ReadFile("inside.md"); !errors.Is(err, ErrVaultNotSet) {
+			t.Errorf("ReadFile error = %v, want ErrVaultNotSet", err)
+		}
+		if _, err := fs.ListDirectoryTree(); !errors.Is(err, ErrVaultNotSet) {
+			t.Errorf("ListDirectoryTree error = %v, want ErrVaultNotSet", err)
+		}
+	})
+}
+
+func TestLinkService_ResolveLinkOutsideVault(t *testing.T) {
+	ls, _, tmpDir := newTestLinkService(t)
+	defer os.RemoveAll(tmpDir)
+
+	os.WriteFile(filepath.Join(filepath.Dir(tmpDir), "obails-outside-link.md"), []byte("x"), 0644)
+	defer os.Remove(filepath.Join(filepath.Dir(tmpDir), "obails-outside-link.md"))
+
+	if path, ok := ls.ResolveLink("../obails-outside-link"); ok {
+		t.Errorf("Link outside the vault should not resolve, got %q", path)
+	}
+}
+
+func TestStateService_SetLastOpenedFileRejectsEscape(t *testing.T) {
+	cs, tmpDir := newTestConfigService(t)
+	defer os.RemoveAll(tmpDir)
+
+	ss := NewStateService(cs, NewFileService(cs))
+	if err := ss.SetLastOpenedFile("../outside.md", "markdown"); !errors.Is(err, ErrPathOutsideVault) {
+		t.Errorf("SetLastOpenedFile error = %v, want ErrPathOutsideVault", err)
+	}
+	if ss.GetLastOpenedFile() != nil {
+		t.Error("Rejected path should not be stored")
+	}
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/kazuph/obails/models"
+)
+
+// VaultService keeps the list of known vaults and switches between them
+type VaultService struct {
+	configService *ConfigService
+	fileService   *FileService
+	stateService  *StateService
+	noteService   *NoteService
+	linkService   *LinkService
+	taskService   *TaskService
+	gitService    *GitService
+
+	mu sync.Mutex // Serializes vault switches
+}
+
+// NewVaultService creates a new VaultService. Vaults opened through the config
+// service are switched by it too.
+func NewVaultService(configService *ConfigService, fileService *FileService, stateService *StateService, noteService *NoteService, linkService *LinkService, taskService *TaskService, gitService *GitService) *VaultService {
+	s := &VaultService{
+		configService: configService,
+		fileService:   fileService,
+		stateService:  stateService,
+		noteService:   noteService,
+		linkService:   linkService,
+		taskService:   taskService,
+		gitService:    gitService,
+	}
+	configService.switchVault = s.SwitchVault
+	return s
+}
+
+// GetVaults returns the known vaults, most recently opened first
+func (s *VaultService) GetVaults() []models.KnownVault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	vaults := s.configService.knownVaults()
+	slices.SortStableFunc(vaults, func(a, b models.KnownVault) int {
+		return b.LastOpened.Compare(a.LastOpened)
+	})
+	return vaults
+}
+
+// GetCurrentVault returns the open vault, or nil if none is open
+func (s *VaultService) GetCurrentVault() *models.KnownVault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	vaults := s.configService.knownVaults()
+	i := vaultIndex(vaults, s.configService.GetVaultPath())
+	if i < 0 {
+		return nil
+	}
+	return &vaults[i]
+}
+
+// AddVault adds a folder to the known vaults without opening it. An empty name
+// stands for the folder name.
+func (s *VaultService) AddVault(path string, name string) (*models.KnownVault, error) {
+	path, err := vaultFolder(path)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = filepath.Base(path)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if vaultIndex(s.configService.knownVaults(), path) >= 0 {
+		return nil, fmt.Errorf("vault %s already exists", path)
+	}
+	vault := models.KnownVault{Name: name, Path: path}
+	err = s.configService.updateVaults(func(vaults []models.KnownVault) []models.KnownVault {
+		return append(vaults, vault)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &vault, nil
+}
+
+// RenameVault changes the display name of a known vault
+func (s *VaultService) RenameVault(path string, name string) error {
+	if name == "" {
+		return fmt.Errorf("vault has no name")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if vaultIndex(s.configService.knownVaults(), path) < 0 {
+		return fmt.Errorf("vault %s not found", path)
+	}
+	return s.configService.updateVaults(func(vaults []models.KnownVault) []models.KnownVault {
+		if i := vaultIndex(vaults, path); i >= 0 {
+			vaults[i].Name = name
+		}
+		return vaults
+	})
+}
+
+// RemoveVault removes a vault from the known vaults. Its files are left alone.
+// The open vault can't be removed.
+func (s *VaultService) RemoveVault(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	vaults := s.configService.knownVaults()
+	i := vaultIndex(vaults, path)
+	if i < 0 {
+		return fmt.Errorf("vault %s not found", path)
+	}
+	if i == vaultIndex(vaults, s.configService.GetVaultPath()) {
+		return fmt.Errorf("vault %s is open", path)
+	}
+	return s.configService.updateVaults(func(vaults []models.KnownVault) []models.KnownVault {
+		if i := vaultIndex(vaults, path); i >= 0 {
+			vaults = slices.Delete(vaults, i, i+1)
+		}
+		return vaults
+	})
+}
+
+// SwitchVault opens another vault. The configuration with the new vault's overrides
+// is loaded, pending auto-commits of the old vault are made, then the state and the
+// indices are loaded from the new vault. Unknown folders are added to the known
+// vaults. If the new vault's configuration can't be loaded, the old vault stays
+// open; once it is open, every step runs even if an earlier one fails.
+func (s *VaultService) SwitchVault(path string) error {
+	path, err := vaultFolder(path)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	oldVault := s.configService.GetVaultPath()
+	if err := s.configService.openVault(path); err != nil {
+		return err
+	}
+	s.gitService.closeVault(oldVault)
+	s.noteService.bases.clear()
+
+	var errs []error
+	if err := s.stateService.Load(); err != nil {
+		errs = append(errs, fmt.Errorf("load state: %w", err))
+	}
+	if err := s.PrepareVault(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// PrepareVault cleans up old trash and history, builds the link and task indices
+// and starts auto-committing the open vault. Every step runs even if an earlier
+// one fails.
+func (s *VaultService) PrepareVault() error {
+	var errs []error
+	if _, err := s.fileService.PurgeTrash(); err != nil {
+		errs = append(errs, fmt.Errorf("purge trash: %w", err))
+	}
+	if _, err := s.noteService.PruneHistory(); err != nil {
+		errs = append(errs, fmt.Errorf("prune note history: %w", err))
+	}
+	if err := s.linkService.RebuildIndex(); err != nil {
+		errs = append(errs, fmt.Errorf("build link index: %w", err))
+	}
+	if err := s.taskService.RebuildIndex(); err != nil {
+		errs = append(errs, fmt.Errorf("build task index: %w", err))
+	}
+	s.gitService.StartAutoCommit()
+	return errors.Join(errs...)
+}
+
+// vaultIndex returns the index of the vault at path in vaults, or -1
+func vaultIndex(vaults []models.KnownVault, path string) int {
+	if path == "" {
+		return -1
+	}
+	path = filepath.Clean(path)
+	return slices.IndexFunc(vaults, func(vault models.KnownVault) bool {
+		return vault.Path == path
+	})
+}
+
+// vaultFolder cleans the path of a vault folder, which must exist
+func vaultFolder(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("vault path %q is not absolute", path)
+	}
+	path = filepath.Clean(path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("vault path %s is not a folder", path)
+	}
+	return path, nil
+}
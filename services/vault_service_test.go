@@ -0,0 +1,255 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/kazuph/obails/models"
+)
+
+func newTestVaultService(t *testing.T) (*VaultService, string, string, string) {
+	t.Helper()
+	cs, tmpDir := newTestConfigService(t)
+	vaultA := filepath.Join(tmpDir, "a")
+	vaultB := filepath.Join(tmpDir, "b")
+	for _, dir := range []string{vaultA, vaultB} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create vault: %v", err)
+		}
+	}
+	cs.config.Vault.Path = vaultA
+
+	fs := NewFileService(cs)
+	ss := NewStateService(cs, fs)
+	ns := NewNoteService(fs, cs)
+	ls := NewLinkService(fs, cs)
+	ts := NewTaskService(ns, fs, cs)
+	gs := NewGitService(ns, fs, cs)
+	return NewVaultService(cs, fs, ss, ns, ls, ts, gs), tmpDir, vaultA, vaultB
+}
+
+func TestVaultService_KnownVaults(t *testing.T) {
+	vs, tmpDir, vaultA, vaultB := newTestVaultService(t)
+	defer os.RemoveAll(tmpDir)
+
+	if _, err := vs.AddVault(vaultB, "Work"); err != nil {
+		t.Fatalf("AddVault failed: %v", err)
+	}
+	if _, err := vs.AddVault(vaultB, ""); err == nil {
+		t.Error("Expected an error adding a known vault twice")
+	}
+	if _, err := vs.AddVault("relative", ""); err == nil {
+		t.Error("Expected an error for a relative vault path")
+	}
+	if err := vs.SwitchVault(vaultA); err != nil {
+		t.Fatalf("SwitchVault failed: %v", err)
+	}
+
+	vaults := vs.GetVaults()
+	if len(vaults) != 2 || vaults[0].Path != vaultA || vaults[0].Name != "a" || vaults[1].Name != "Work" {
+		t.Fatalf("Unexpected vaults %+v", vaults)
+	}
+	if vaults[0].LastOpened.IsZero() || !vaults[1].LastOpened.IsZero() {
+		t.Errorf("Unexpected last opened times %+v", vaults)
+	}
+	if current := vs.GetCurrentVault(); current == nil || current.Path != vaultA {
+		t.Errorf("Unexpected current vault %+v", current)
+	}
+
+	if err := vs.RenameVault(vaultA, "Personal"); err != nil {
+		t.Fatalf("RenameVault failed: %v", err)
+	}
+	if err := vs.RemoveVault(vaultA); err == nil {
+		t.Error("Expected an error removing the open vault")
+	}
+	if err := vs.RemoveVault(vaultB); err != nil {
+		t.Fatalf("RemoveVault failed: %v", err)
+	}
+
+	// The list survives a reload of the config file
+	loaded := &ConfigService{configPath: vs.configService.configPath, config: models.DefaultConfig()}
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(loaded.config.Vaults) != 1 || loaded.config.Vaults[0].Name != "Personal" {
+		t.Errorf("Unexpected saved vaults %+v", loaded.config.Vaults)
+	}
+}
+
+func TestVaultService_SwitchVault(t *testing.T) {
+	vs, tmpDir, vaultA, vaultB := newTestVaultService(t)
+	defer os.RemoveAll(tmpDir)
+
+	os.WriteFile(filepath.Join(vaultA, "a.md"), []byte("[[b]]\n- [ ] Task in A\n"), 0644)
+	os.WriteFile(filepath.Join(vaultA, "b.md"), nil, 0644)
+	os.WriteFile(filepath.Join(vaultB, "c.md"), []byte("[[d]]\n"), 0644)
+	os.WriteFile(filepath.Join(vaultB, "d.md"), nil, 0644)
+
+	if err := vs.PrepareVault(); err != nil {
+		t.Fatalf("PrepareVault failed: %v", err)
+	}
+	if err := vs.stateService.PinFile("a.md"); err != nil {
+		t.Fatalf("PinFile failed: %v", err)
+	}
+	if len(vs.linkService.GetBacklinks("b.md")) != 1 {
+		t.Fatal("Expected a backlink in vault A")
+	}
+
+	if err := vs.configService.SetVaultPath(vaultB); err != nil {
+		t.Fatalf("SetVaultPath failed: %v", err)
+	}
+	if got := vs.configService.GetVaultPath(); got != vaultB {
+		t.Errorf("Expected vault %s, got %s", vaultB, got)
+	}
+	if len(vs.linkService.GetBacklinks("b.md")) != 0 || len(vs.linkService.GetBacklinks("d.md")) != 1 {
+		t.Error("Expected the link index of vault B only")
+	}
+	if len(vs.taskService.index) != 0 {
+		t.Errorf("Expected no tasks of vault A, got %v", vs.taskService.index)
+	}
+	if pinned := vs.stateService.GetPinnedFiles(); len(pinned) != 0 {
+		t.Errorf("Expected no pinned files in vault B, got %v", pinned)
+	}
+
+	if err := vs.SwitchVault(vaultA); err != nil {
+		t.Fatalf("SwitchVault failed: %v", err)
+	}
+	if pinned := vs.stateService.GetPinnedFiles(); len(pinned) != 1 || pinned[0] != "a.md" {
+		t.Errorf("Expected the state of vault A back, got %v", pinned)
+	}
+
+	if err := vs.SwitchVault(filepath.Join(vaultA, "a.md")); err == nil {
+		t.Error("Expected an error switching to a file")
+	}
+
+	t.Run("broken vault config", func(t *testing.T) {
+		os.MkdirAll(filepath.Join(vaultB, ".obails"), 0755)
+		os.WriteFile(filepath.Join(vaultB, vaultConfigFile), []byte("[timeline\n"), 0644)
+
+		if err := vs.SwitchVault(vaultB); err == nil {
+			t.Fatal("Expected an error switching to a vault with a broken config")
+		}
+		if got := vs.configService.GetVaultPath(); got != vaultA {
+			t.Errorf("Expected vault A to stay open, got %s", got)
+		}
+		if pinned := vs.stateService.GetPinnedFiles(); len(pinned) != 1 || pinned[0] != "a.md" {
+			t.Errorf("Expected the state of vault A, got %v", pinned)
+		}
+		if len(vs.linkService.GetBacklinks("b.md")) != 1 {
+			t.Error("Expected the link index of vault A")
+		}
+	})
+}
+
+func TestVaultService_SwitchVaultSteps(t *testing.T) {
+	vs, tmpDir, vaultA, vaultB := newTestVaultService(t)
+	defer os.RemoveAll(tmpDir)
+
+	cs := vs.configService
+	cs.config.Git = models.GitConfig{AutoCommit: models.GitAutoCommitSave, AuthorName: "Tester", AuthorEmail: "tester@example.com"}
+	vs.gitService.saveDelay = time.Hour
+	repo, err := git.PlainInit(vaultA, false)
+	if err != nil {
+		t.Fatalf("Failed to init repository: %v", err)
+	}
+	os.WriteFile(filepath.Join(vaultB, "c.md"), []byte("[[d]]\n"), 0644)
+	os.MkdirAll(filepath.Join(vaultB, ".obails"), 0755)
+
+	if err := vs.noteService.SaveNote("a.md", "a\n"); err != nil {
+		t.Fatalf("SaveNote failed: %v", err)
+	}
+
+	t.Run("broken vault config keeps the pending commit", func(t *testing.T) {
+		os.WriteFile(filepath.Join(vaultB, vaultConfigFile), []byte("[timeline\n"), 0644)
+		defer os.Remove(filepath.Join(vaultB, vaultConfigFile))
+
+		if err := vs.SwitchVault(vaultB); err == nil {
+			t.Fatal("Expected an error switching to a vault with a broken config")
+		}
+		if _, err := repo.Head(); err == nil {
+			t.Error("Expected no commit while vault A stays open")
+		}
+	})
+
+	t.Run("broken state", func(t *testing.T) {
+		os.WriteFile(filepath.Join(vaultB, ".obails", "state.json"), []byte("{"), 0644)
+
+		if err := vs.SwitchVault(vaultB); err == nil {
+			t.Error("Expected an error loading a broken state")
+		}
+		if got := cs.GetVaultPath(); got != vaultB {
+			t.Errorf("Expected vault B to be open, got %s", got)
+		}
+		if len(vs.linkService.GetBacklinks("d.md")) != 1 {
+			t.Error("Expected the link index of vault B despite the broken state")
+		}
+	})
+
+	t.Run("pending commit goes to the old vault", func(t *testing.T) {
+		head, err := repo.Head()
+		if err != nil {
+			t.Fatalf("Expected the pending commit in vault A: %v", err)
+		}
+		commit, _ := repo.CommitObject(head.Hash())
+		if _, err := commit.File("a.md"); err != nil {
+			t.Errorf("Expected a.md in the commit: %v", err)
+		}
+	})
+}
+
+func TestVaultService_ConfigOverrides(t *testing.T) {
+	vs, tmpDir, vaultA, vaultB := newTestVaultService(t)
+	defer os.RemoveAll(tmpDir)
+	cs := vs.configService
+	cs.config.Timeline.Section = "## Memos"
+	cs.config.Files.Ignore = []string{"*.log"}
+
+	os.MkdirAll(filepath.Join(vaultB, ".obails"), 0755)
+	override := "[timeline]\nsection = \"## Log\"\n\n[vault]\npath = \"/elsewhere\"\n"
+	os.WriteFile(filepath.Join(vaultB, vaultConfigFile), []byte(override), 0644)
+
+	if err := vs.SwitchVault(vaultB); err != nil {
+		t.Fatalf("SwitchVault failed: %v", err)
+	}
+	if got := cs.GetTimelineSection(); got != "## Log" {
+		t.Errorf("Expected the vault's timeline section, got %q", got)
+	}
+	if got := cs.GetIgnorePatterns(); len(got) != 1 || got[0] != "*.log" {
+		t.Errorf("Expected the global ignore patterns, got %v", got)
+	}
+	if got := cs.GetVaultPath(); got != vaultB {
+		t.Errorf("Expected the vault path not to be overridden, got %s", got)
+	}
+
+	t.Run("not saved globally", func(t *testing.T) {
+		data, err := os.ReadFile(cs.configPath)
+		if err != nil {
+			t.Fatalf("Failed to read config: %v", err)
+		}
+		if strings.Contains(string(data), "## Log") || !strings.Contains(string(data), "## Memos") {
+			t.Errorf("Expected the global config without overrides:\n%s", data)
+		}
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		if err := cs.ReloadConfig(); err != nil {
+			t.Fatalf("ReloadConfig failed: %v", err)
+		}
+		if got := cs.GetTimelineSection(); got != "## Log" {
+			t.Errorf("Expected the override after a reload, got %q", got)
+		}
+	})
+
+	t.Run("other vault", func(t *testing.T) {
+		if err := vs.SwitchVault(vaultA); err != nil {
+			t.Fatalf("SwitchVault failed: %v", err)
+		}
+		if got := cs.GetTimelineSection(); got != "## Memos" {
+			t.Errorf("Expected the global timeline section, got %q", got)
+		}
+	})
+}